@@ -0,0 +1,318 @@
+// Package api serves keys to local applications over HTTP, usually on a
+// Unix socket. Every request but /v1/health needs a token created with
+// 'keys api-token create', and is limited to the profiles and key names
+// the token allows.
+//
+//	GET /v1/health                        {"ok": true}
+//	GET /v1/keys?profile=P                {"profile": P, "keys": [names]}
+//	GET /v1/keys/{name}?profile=P         {"profile": P, "name": name, "value": v}
+//	GET /v1/bundle?profile=P&name=A&name=B {"profile": P, "keys": {name: value}}
+//	GET /v1/changes?profile=P&name=A&known=A:DIGEST
+//	                                      {"profile": P, "keys": {name: value}, "missing": [names]}
+//
+// /v1/changes is for polling: it returns only the keys whose value no
+// longer has the Digest the caller already knows, and only those reads are
+// audited.
+//
+// The profile defaults to the token's only profile, or the active one.
+// Errors are {"error": message, "code": code}:
+//
+//	401 unauthorized    missing, unknown or revoked token
+//	401 token_expired   the token's expiry time has passed
+//	403 forbidden       profile or key outside the token's scope
+//	404 not_found       no such key
+//	500 internal
+package api
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/stym06/keys/db"
+)
+
+// KeyList is the response of GET /v1/keys.
+type KeyList struct {
+	Profile string   `json:"profile"`
+	Keys    []string `json:"keys"`
+}
+
+// KeyValue is the response of GET /v1/keys/{name}.
+type KeyValue struct {
+	Profile string `json:"profile"`
+	Name    string `json:"name"`
+	Value   string `json:"value"`
+}
+
+// Bundle is the response of GET /v1/bundle.
+type Bundle struct {
+	Profile string            `json:"profile"`
+	Keys    map[string]string `json:"keys"`
+}
+
+// Changes is the response of GET /v1/changes.
+type Changes struct {
+	Profile string            `json:"profile"`
+	Keys    map[string]string `json:"keys"`
+	Missing []string          `json:"missing"`
+}
+
+// Digest identifies a value in /v1/changes requests without sending it.
+func Digest(value string) string {
+	sum := sha256.Sum256([]byte(value))
+	return hex.EncodeToString(sum[:])
+}
+
+// Error is the body of every error response.
+type Error struct {
+	Error string `json:"error"`
+	Code  string `json:"code"`
+}
+
+// Error codes.
+const (
+	CodeUnauthorized = "unauthorized"
+	CodeTokenExpired = "token_expired"
+	CodeForbidden    = "forbidden"
+	CodeNotFound     = "not_found"
+	CodeInternal     = "internal"
+)
+
+// SocketEnv names the environment variable that overrides DefaultSocket.
+const SocketEnv = "KEYS_API_SOCKET"
+
+// DefaultSocket returns the socket the API is served on by default:
+// $KEYS_API_SOCKET, or ~/.keys/api.sock.
+func DefaultSocket() (string, error) {
+	if s := os.Getenv(SocketEnv); s != "" {
+		return s, nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".keys", "api.sock"), nil
+}
+
+// AuditSource returns the audit log source for requests made by client.
+func AuditSource(client string) string {
+	return "api:" + client
+}
+
+// NewHandler returns the API handler. Each request is logged to log.
+func NewHandler(log io.Writer) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /v1/health", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]bool{"ok": true})
+	})
+	mux.Handle("GET /v1/keys", authed(listKeys))
+	mux.Handle("GET /v1/keys/{name}", authed(getKey))
+	mux.Handle("GET /v1/bundle", authed(getBundle))
+	mux.Handle("GET /v1/changes", authed(getChanges))
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK, client: "-"}
+		mux.ServeHTTP(rec, r)
+		fmt.Fprintf(log, "%s %s %s client=%s %d\n", time.Now().Format(time.RFC3339), r.Method, r.URL.Path, rec.client, rec.status)
+	})
+}
+
+// statusRecorder remembers the status and client of a response for the
+// request log.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+	client string
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+type handlerFunc func(w http.ResponseWriter, r *http.Request, tok *db.APIToken, profile string) error
+
+// httpError is an error with the status and code it is reported with.
+type httpError struct {
+	status int
+	code   string
+	msg    string
+}
+
+func (e *httpError) Error() string { return e.msg }
+
+func errorf(status int, code, format string, args ...any) error {
+	return &httpError{status: status, code: code, msg: fmt.Sprintf(format, args...)}
+}
+
+// authed checks the bearer token and the requested profile before calling h.
+func authed(h handlerFunc) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok {
+			writeError(w, errorf(http.StatusUnauthorized, CodeUnauthorized, "missing bearer token"))
+			return
+		}
+		tok, err := db.VerifyAPIToken(strings.TrimSpace(token))
+		if err != nil {
+			switch {
+			case errors.Is(err, db.ErrInvalidToken):
+				err = errorf(http.StatusUnauthorized, CodeUnauthorized, "%v", err)
+			case errors.Is(err, db.ErrTokenExpired):
+				err = errorf(http.StatusUnauthorized, CodeTokenExpired, "%v", err)
+			}
+			writeError(w, err)
+			return
+		}
+		if rec, ok := w.(*statusRecorder); ok {
+			rec.client = tok.Client
+		}
+
+		profile := r.URL.Query().Get("profile")
+		if profile == "" {
+			profile = db.GetActiveProfile()
+			if len(tok.Profiles) == 1 {
+				profile = tok.Profiles[0]
+			}
+		}
+		if !tok.AllowsProfile(profile) {
+			writeError(w, errorf(http.StatusForbidden, CodeForbidden, "token for %q may not read profile %q", tok.Client, profile))
+			return
+		}
+		if err := h(w, r, tok, profile); err != nil {
+			writeError(w, err)
+		}
+	})
+}
+
+func listKeys(w http.ResponseWriter, r *http.Request, tok *db.APIToken, profile string) error {
+	keys, err := db.GetAllKeysForProfile(profile)
+	if err != nil {
+		return err
+	}
+	list := KeyList{Profile: profile, Keys: []string{}}
+	for _, k := range keys {
+		if tok.AllowsKey(profile, k.Name) {
+			list.Keys = append(list.Keys, k.Name)
+		}
+	}
+	_ = db.LogAccessForProfile(profile, "*", "list", AuditSource(tok.Client))
+	writeJSON(w, http.StatusOK, list)
+	return nil
+}
+
+func getKey(w http.ResponseWriter, r *http.Request, tok *db.APIToken, profile string) error {
+	name := r.PathValue("name")
+	if !tok.AllowsKey(profile, name) {
+		return errorf(http.StatusForbidden, CodeForbidden, "token for %q may not read %s", tok.Client, name)
+	}
+	k, err := db.GetKeyForProfile(profile, name)
+	if err != nil {
+		return err
+	}
+	_ = db.LogAccessForProfile(profile, k.Name, "get", AuditSource(tok.Client))
+	writeJSON(w, http.StatusOK, KeyValue{Profile: profile, Name: k.Name, Value: k.Value})
+	return nil
+}
+
+func getBundle(w http.ResponseWriter, r *http.Request, tok *db.APIToken, profile string) error {
+	names := r.URL.Query()["name"]
+	for _, name := range names {
+		if !tok.AllowsKey(profile, name) {
+			return errorf(http.StatusForbidden, CodeForbidden, "token for %q may not read %s", tok.Client, name)
+		}
+	}
+
+	var keys []db.Key
+	var err error
+	if len(names) == 0 {
+		keys, err = db.GetAllKeysForProfile(profile)
+	} else {
+		keys, err = db.GetKeysByNamesForProfile(names, profile)
+	}
+	if err != nil {
+		return err
+	}
+
+	bundle := Bundle{Profile: profile, Keys: make(map[string]string)}
+	for _, k := range keys {
+		if tok.AllowsKey(profile, k.Name) {
+			bundle.Keys[k.Name] = k.Value
+		}
+	}
+	for _, name := range names {
+		if _, ok := bundle.Keys[name]; !ok {
+			return fmt.Errorf("key %q %w", name, db.ErrNotFound)
+		}
+	}
+	for name := range bundle.Keys {
+		_ = db.LogAccessForProfile(profile, name, "get", AuditSource(tok.Client))
+	}
+	writeJSON(w, http.StatusOK, bundle)
+	return nil
+}
+
+func getChanges(w http.ResponseWriter, r *http.Request, tok *db.APIToken, profile string) error {
+	names := r.URL.Query()["name"]
+	for _, name := range names {
+		if !tok.AllowsKey(profile, name) {
+			return errorf(http.StatusForbidden, CodeForbidden, "token for %q may not read %s", tok.Client, name)
+		}
+	}
+	known := make(map[string]string)
+	for _, kv := range r.URL.Query()["known"] {
+		if name, digest, ok := strings.Cut(kv, ":"); ok {
+			known[name] = digest
+		}
+	}
+
+	keys, err := db.GetKeysByNamesForProfile(names, profile)
+	if err != nil {
+		return err
+	}
+	values := make(map[string]string, len(keys))
+	for _, k := range keys {
+		values[k.Name] = k.Value
+	}
+
+	changes := Changes{Profile: profile, Keys: make(map[string]string), Missing: []string{}}
+	for _, name := range names {
+		value, ok := values[name]
+		switch {
+		case !ok:
+			changes.Missing = append(changes.Missing, name)
+		case Digest(value) != known[name]:
+			changes.Keys[name] = value
+			_ = db.LogAccessForProfile(profile, name, "get", AuditSource(tok.Client))
+		}
+	}
+	writeJSON(w, http.StatusOK, changes)
+	return nil
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	status, code := http.StatusInternalServerError, CodeInternal
+	var he *httpError
+	switch {
+	case errors.As(err, &he):
+		status, code = he.status, he.code
+	case errors.Is(err, db.ErrNotFound):
+		status, code = http.StatusNotFound, CodeNotFound
+	}
+	writeJSON(w, status, Error{Error: err.Error(), Code: code})
+}
@@ -0,0 +1,145 @@
+package api
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stym06/keys/db"
+)
+
+func setupServer(t *testing.T) (*httptest.Server, string) {
+	t.Helper()
+	t.Setenv("HOME", t.TempDir())
+	for name, value := range map[string]string{"STRIPE_KEY": "sk_live_1", "STRIPE_HOOK": "whsec_2", "OPENAI_KEY": "sk-3"} {
+		if err := db.AddKeyForProfile("prod", name, value); err != nil {
+			t.Fatal(err)
+		}
+	}
+	token, err := db.CreateAPIToken("billing", []string{"prod"}, []string{"STRIPE_*"}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(NewHandler(io.Discard))
+	t.Cleanup(srv.Close)
+	return srv, token
+}
+
+func request(t *testing.T, srv *httptest.Server, token, path string, v any) int {
+	t.Helper()
+	req, _ := http.NewRequest("GET", srv.URL+path, nil)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if v != nil {
+		if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+			t.Fatalf("decoding %s: %v", path, err)
+		}
+	}
+	return resp.StatusCode
+}
+
+func TestAPIAuthAndScope(t *testing.T) {
+	srv, token := setupServer(t)
+
+	tests := []struct {
+		token, path string
+		want        int
+	}{
+		{"", "/v1/keys", http.StatusUnauthorized},
+		{"keys_00000000_nope", "/v1/keys", http.StatusUnauthorized},
+		{token, "/v1/keys/STRIPE_KEY", http.StatusOK},
+		{token, "/v1/keys/OPENAI_KEY", http.StatusForbidden},
+		{token, "/v1/keys/STRIPE_KEY?profile=dev", http.StatusForbidden},
+		{token, "/v1/keys/STRIPE_MISSING", http.StatusNotFound},
+		{"", "/v1/health", http.StatusOK},
+	}
+	for _, tt := range tests {
+		if got := request(t, srv, tt.token, tt.path, nil); got != tt.want {
+			t.Errorf("GET %s: status %d, want %d", tt.path, got, tt.want)
+		}
+	}
+
+	if err := db.RevokeAPIToken("billing"); err != nil {
+		t.Fatal(err)
+	}
+	if got := request(t, srv, token, "/v1/keys/STRIPE_KEY", nil); got != http.StatusUnauthorized {
+		t.Errorf("revoked token: status %d, want 401", got)
+	}
+}
+
+func TestAPIListAndBundle(t *testing.T) {
+	srv, token := setupServer(t)
+
+	var list KeyList
+	request(t, srv, token, "/v1/keys", &list)
+	if list.Profile != "prod" || strings.Join(list.Keys, ",") != "STRIPE_HOOK,STRIPE_KEY" {
+		t.Errorf("unexpected list: %+v", list)
+	}
+
+	var bundle Bundle
+	if got := request(t, srv, token, "/v1/bundle?name=STRIPE_KEY&name=STRIPE_HOOK", &bundle); got != http.StatusOK {
+		t.Fatalf("bundle: status %d", got)
+	}
+	if bundle.Keys["STRIPE_KEY"] != "sk_live_1" || bundle.Keys["STRIPE_HOOK"] != "whsec_2" {
+		t.Errorf("unexpected bundle: %+v", bundle)
+	}
+
+	var all Bundle
+	request(t, srv, token, "/v1/bundle", &all)
+	if _, ok := all.Keys["OPENAI_KEY"]; ok || len(all.Keys) != 2 {
+		t.Errorf("bundle without names should hold only allowed keys: %+v", all)
+	}
+
+	if err := db.SetActiveProfile("prod"); err != nil {
+		t.Fatal(err)
+	}
+	entries, err := db.GetAuditLog(20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	found := false
+	for _, e := range entries {
+		if e.KeyName == "STRIPE_KEY" && e.Source == AuditSource("billing") {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("expected an audit entry with source %q: %+v", AuditSource("billing"), entries)
+	}
+}
+
+func TestAPIChanges(t *testing.T) {
+	srv, token := setupServer(t)
+
+	path := "/v1/changes?name=STRIPE_KEY&name=STRIPE_HOOK&name=STRIPE_GONE&known=STRIPE_KEY:" + Digest("sk_live_1")
+	var changes Changes
+	if got := request(t, srv, token, path, &changes); got != http.StatusOK {
+		t.Fatalf("changes: status %d", got)
+	}
+	if len(changes.Keys) != 1 || changes.Keys["STRIPE_HOOK"] != "whsec_2" {
+		t.Errorf("expected only the unknown key's value, got %+v", changes.Keys)
+	}
+	if strings.Join(changes.Missing, ",") != "STRIPE_GONE" {
+		t.Errorf("unexpected missing keys: %v", changes.Missing)
+	}
+	if got := request(t, srv, token, "/v1/changes?name=OPENAI_KEY", nil); got != http.StatusForbidden {
+		t.Errorf("key outside the scope: status %d, want 403", got)
+	}
+
+	entries, err := db.QueryAuditLog(db.AuditQuery{Profile: "prod", Action: "get"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].KeyName != "STRIPE_HOOK" {
+		t.Errorf("expected only the returned value audited, got %+v", entries)
+	}
+}
@@ -0,0 +1,295 @@
+// Package client reads keys from Go programs without shelling out to the
+// keys CLI.
+//
+// A Client asks a running 'keys serve-api' when a token is configured and
+// the API is reachable, and otherwise opens the vault directly, as the CLI
+// does. The package-level functions use a Client configured from the
+// environment:
+//
+//	KEYS_API_TOKEN    token from 'keys api-token create'
+//	KEYS_API_SOCKET   API socket (default ~/.keys/api.sock)
+//
+// Failures can be told apart with errors.Is:
+//
+//	value, err := client.Get(ctx, "OPENAI_KEY")
+//	if errors.Is(err, client.ErrNotFound) {
+//		...
+//	}
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+
+	"github.com/stym06/keys/api"
+	"github.com/stym06/keys/db"
+)
+
+var (
+	// ErrNotFound is returned when a key does not exist in the profile.
+	ErrNotFound = errors.New("key not found")
+	// ErrLocked is returned when the vault cannot be unlocked: the user
+	// failed to authenticate, or the API rejected the token.
+	ErrLocked = errors.New("vault is locked")
+	// ErrExpired is returned when the API token has expired.
+	ErrExpired = errors.New("API token expired")
+	// ErrDenied is returned when the token does not allow the profile or key.
+	ErrDenied = errors.New("access denied")
+)
+
+// TokenEnv names the environment variable holding the API token.
+const TokenEnv = "KEYS_API_TOKEN"
+
+// AuditSource is the audit log source of reads that open the vault
+// directly. Reads through the API are logged as "api:<client>".
+const AuditSource = "client"
+
+// DefaultWatchInterval is how often Watch checks for changes by default.
+const DefaultWatchInterval = 30 * time.Second
+
+// Client reads keys. The zero value is ready to use.
+type Client struct {
+	// Profile to read from. If empty, the API uses the token's profile and
+	// direct reads use the active profile.
+	Profile string
+	// Token for the API. Defaults to $KEYS_API_TOKEN; without a token the
+	// vault is always opened directly.
+	Token string
+	// Socket the API is served on. Defaults to $KEYS_API_SOCKET or
+	// ~/.keys/api.sock.
+	Socket string
+	// BaseURL reaches the API over TCP instead of the socket, e.g.
+	// "http://127.0.0.1:7878".
+	BaseURL string
+	// WatchInterval is how often Watch checks for changes. Defaults to
+	// DefaultWatchInterval.
+	WatchInterval time.Duration
+}
+
+// Default is the client used by the package-level functions.
+var Default = &Client{}
+
+// Get returns the value of a key using the Default client.
+func Get(ctx context.Context, name string) (string, error) {
+	return Default.Get(ctx, name)
+}
+
+// GetMany returns the values of keys by name using the Default client.
+func GetMany(ctx context.Context, names ...string) (map[string]string, error) {
+	return Default.GetMany(ctx, names...)
+}
+
+// LoadIntoEnv sets keys as environment variables using the Default client.
+func LoadIntoEnv(profile string, names ...string) error {
+	return Default.LoadIntoEnv(profile, names...)
+}
+
+// Watch reports changes to keys using the Default client.
+func Watch(ctx context.Context, names ...string) (<-chan Change, error) {
+	return Default.Watch(ctx, names...)
+}
+
+// Get returns the value of a key.
+func (c *Client) Get(ctx context.Context, name string) (string, error) {
+	values, err := c.GetMany(ctx, name)
+	if err != nil {
+		return "", err
+	}
+	return values[name], nil
+}
+
+// GetMany returns the values of keys by name. It fails with ErrNotFound if
+// any of them is missing.
+func (c *Client) GetMany(ctx context.Context, names ...string) (map[string]string, error) {
+	values, _, err := c.fetch(ctx, names)
+	return values, err
+}
+
+// fetch is GetMany, also reporting whether the vault was opened directly.
+func (c *Client) fetch(ctx context.Context, names []string) (map[string]string, bool, error) {
+	if len(names) == 0 {
+		return map[string]string{}, false, nil
+	}
+	if httpClient, base, ok := c.api(); ok {
+		values, err := c.fetchAPI(ctx, httpClient, base, names)
+		if !isUnreachable(ctx, err) {
+			return values, false, err
+		}
+		// The API is not running; fall back to the vault
+	}
+	values, err := c.fetchVault(names)
+	return values, true, err
+}
+
+// isUnreachable reports whether err means the API is not running.
+func isUnreachable(ctx context.Context, err error) bool {
+	var netErr net.Error
+	return errors.As(err, &netErr) && ctx.Err() == nil
+}
+
+// LoadIntoEnv sets keys from profile (or the client's profile, if empty)
+// as environment variables of the current process.
+func (c *Client) LoadIntoEnv(profile string, names ...string) error {
+	cc := *c
+	if profile != "" {
+		cc.Profile = profile
+	}
+	values, err := cc.GetMany(context.Background(), names...)
+	if err != nil {
+		return err
+	}
+	for name, value := range values {
+		if err := os.Setenv(name, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// api returns an HTTP client and base URL for the API, if a token is
+// configured and the API may be running.
+func (c *Client) api() (*http.Client, string, bool) {
+	if c.token() == "" {
+		return nil, "", false
+	}
+	if c.BaseURL != "" {
+		return http.DefaultClient, c.BaseURL, true
+	}
+	socket := c.Socket
+	if socket == "" {
+		var err error
+		if socket, err = api.DefaultSocket(); err != nil {
+			return nil, "", false
+		}
+	}
+	if _, err := os.Stat(socket); err != nil {
+		return nil, "", false
+	}
+	transport := &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, "unix", socket)
+		},
+	}
+	return &http.Client{Transport: transport}, "http://keys", true
+}
+
+func (c *Client) token() string {
+	if c.Token != "" {
+		return c.Token
+	}
+	return os.Getenv(TokenEnv)
+}
+
+func (c *Client) fetchAPI(ctx context.Context, httpClient *http.Client, base string, names []string) (map[string]string, error) {
+	var bundle api.Bundle
+	if err := c.call(ctx, httpClient, base, "/v1/bundle", url.Values{"name": names}, &bundle); err != nil {
+		return nil, err
+	}
+	return bundle.Keys, nil
+}
+
+// call makes a GET request to the API and decodes the response into v.
+func (c *Client) call(ctx context.Context, httpClient *http.Client, base, path string, query url.Values, v any) error {
+	if c.Profile != "" {
+		query.Set("profile", c.Profile)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, base+path+"?"+query.Encode(), nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+c.token())
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		var body api.Error
+		if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+			return fmt.Errorf("keys API: %s", resp.Status)
+		}
+		kind, ok := apiErrors[body.Code]
+		if !ok {
+			return fmt.Errorf("keys API: %s", body.Error)
+		}
+		return &keyError{kind: kind, msg: body.Error}
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("keys API: %w", err)
+	}
+	return nil
+}
+
+var apiErrors = map[string]error{
+	api.CodeUnauthorized: ErrLocked,
+	api.CodeTokenExpired: ErrExpired,
+	api.CodeForbidden:    ErrDenied,
+	api.CodeNotFound:     ErrNotFound,
+}
+
+func (c *Client) fetchVault(names []string) (map[string]string, error) {
+	if err := unlock(); err != nil {
+		return nil, err
+	}
+	profile, values, err := c.readVault(names)
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range names {
+		if _, ok := values[name]; !ok {
+			return nil, &keyError{kind: ErrNotFound, msg: fmt.Sprintf("key %q not found", name)}
+		}
+	}
+	for name := range values {
+		_ = db.LogAccessForProfile(profile, name, "get", AuditSource)
+	}
+	return values, nil
+}
+
+// unlock authenticates the user before the vault is read.
+func unlock() error {
+	if err := db.Authenticate(); err != nil {
+		return &keyError{kind: ErrLocked, msg: err.Error()}
+	}
+	return nil
+}
+
+// readVault returns the profile read from and the values of the named keys
+// that exist, without authenticating or auditing.
+func (c *Client) readVault(names []string) (string, map[string]string, error) {
+	profile := c.Profile
+	if profile == "" {
+		profile = db.GetActiveProfile()
+	}
+	keys, err := db.GetKeysByNamesForProfile(names, profile)
+	if err != nil {
+		return "", nil, err
+	}
+	values := make(map[string]string, len(keys))
+	for _, k := range keys {
+		values[k.Name] = k.Value
+	}
+	return profile, values, nil
+}
+
+// keyError carries a descriptive message while matching one of the
+// package's errors with errors.Is.
+type keyError struct {
+	kind error
+	msg  string
+}
+
+func (e *keyError) Error() string { return e.msg }
+
+func (e *keyError) Unwrap() error { return e.kind }
@@ -0,0 +1,192 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stym06/keys/api"
+	"github.com/stym06/keys/db"
+)
+
+func setupVault(t *testing.T) {
+	t.Helper()
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv(TokenEnv, "")
+	t.Setenv(api.SocketEnv, "")
+	for name, value := range map[string]string{"OPENAI_KEY": "sk-1", "STRIPE_KEY": "sk_live_2"} {
+		if err := db.AddKeyForProfile("prod", name, value); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// apiClient returns a client for an API server with a token scoped to
+// STRIPE_* in prod.
+func apiClient(t *testing.T, ttl time.Duration) *Client {
+	t.Helper()
+	token, err := db.CreateAPIToken("billing", []string{"prod"}, []string{"STRIPE_*"}, ttl)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(api.NewHandler(io.Discard))
+	t.Cleanup(srv.Close)
+	return &Client{Token: token, BaseURL: srv.URL}
+}
+
+func TestGetFromVault(t *testing.T) {
+	setupVault(t)
+	c := &Client{Profile: "prod"}
+	ctx := context.Background()
+
+	value, err := c.Get(ctx, "OPENAI_KEY")
+	if err != nil || value != "sk-1" {
+		t.Errorf("Get = %q, %v", value, err)
+	}
+	values, err := c.GetMany(ctx, "OPENAI_KEY", "STRIPE_KEY")
+	if err != nil || len(values) != 2 || values["STRIPE_KEY"] != "sk_live_2" {
+		t.Errorf("GetMany = %v, %v", values, err)
+	}
+	if _, err := c.Get(ctx, "MISSING"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+
+	db.SetActiveProfile("prod")
+	entries, _ := db.GetAuditLog(10)
+	if len(entries) == 0 || entries[0].Source != AuditSource {
+		t.Errorf("expected reads audited with source %q: %+v", AuditSource, entries)
+	}
+}
+
+func TestGetFromAPI(t *testing.T) {
+	setupVault(t)
+	c := apiClient(t, 0)
+	ctx := context.Background()
+
+	value, err := c.Get(ctx, "STRIPE_KEY")
+	if err != nil || value != "sk_live_2" {
+		t.Errorf("Get = %q, %v", value, err)
+	}
+	if _, err := c.Get(ctx, "OPENAI_KEY"); !errors.Is(err, ErrDenied) {
+		t.Errorf("expected ErrDenied outside the token's scope, got %v", err)
+	}
+	if _, err := c.Get(ctx, "STRIPE_MISSING"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+
+	db.RevokeAPIToken("billing")
+	if _, err := c.Get(ctx, "STRIPE_KEY"); !errors.Is(err, ErrLocked) {
+		t.Errorf("expected ErrLocked for a revoked token, got %v", err)
+	}
+}
+
+func TestGetExpiredToken(t *testing.T) {
+	setupVault(t)
+	c := apiClient(t, time.Second)
+	time.Sleep(1100 * time.Millisecond)
+
+	if _, err := c.Get(context.Background(), "STRIPE_KEY"); !errors.Is(err, ErrExpired) {
+		t.Errorf("expected ErrExpired, got %v", err)
+	}
+}
+
+func TestFallsBackToVaultWithoutServer(t *testing.T) {
+	setupVault(t)
+	socket := filepath.Join(t.TempDir(), "api.sock")
+	os.WriteFile(socket, nil, 0600) // left behind by a stopped server
+	c := &Client{Profile: "prod", Token: "keys_00000000_x", Socket: socket}
+
+	value, err := c.Get(context.Background(), "OPENAI_KEY")
+	if err != nil || value != "sk-1" {
+		t.Errorf("Get = %q, %v", value, err)
+	}
+}
+
+func TestLoadIntoEnv(t *testing.T) {
+	setupVault(t)
+	t.Setenv("OPENAI_KEY", "")
+
+	if err := (&Client{}).LoadIntoEnv("prod", "OPENAI_KEY"); err != nil {
+		t.Fatalf("LoadIntoEnv: %v", err)
+	}
+	if got := os.Getenv("OPENAI_KEY"); got != "sk-1" {
+		t.Errorf("OPENAI_KEY = %q", got)
+	}
+}
+
+func TestWatch(t *testing.T) {
+	setupVault(t)
+	c := &Client{Profile: "prod", WatchInterval: 10 * time.Millisecond}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	changes, err := c.Watch(ctx, "OPENAI_KEY", "STRIPE_KEY")
+	if err != nil {
+		t.Fatalf("Watch: %v", err)
+	}
+
+	db.AddKeyForProfile("prod", "OPENAI_KEY", "sk-rotated")
+	if ch := <-changes; ch.Name != "OPENAI_KEY" || ch.Value != "sk-rotated" {
+		t.Errorf("unexpected change: %+v", ch)
+	}
+
+	db.DeleteKeyForProfile("prod", "STRIPE_KEY")
+	if ch := <-changes; ch.Name != "STRIPE_KEY" || !ch.Deleted {
+		t.Errorf("unexpected change: %+v", ch)
+	}
+
+	if _, err := c.Watch(ctx, "MISSING"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected Watch to fail for a missing key, got %v", err)
+	}
+}
+
+func TestWatchAuditsOnlyChanges(t *testing.T) {
+	reads := func(source string) int {
+		t.Helper()
+		entries, err := db.QueryAuditLog(db.AuditQuery{Profile: "prod", Action: "get", Source: source})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return len(entries)
+	}
+
+	for _, tc := range []struct {
+		name   string
+		client func(t *testing.T) *Client
+		source string
+	}{
+		{"vault", func(t *testing.T) *Client { return &Client{Profile: "prod"} }, AuditSource},
+		{"api", func(t *testing.T) *Client { return apiClient(t, 0) }, api.AuditSource("billing")},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			setupVault(t)
+			c := tc.client(t)
+			c.WatchInterval = 5 * time.Millisecond
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+
+			changes, err := c.Watch(ctx, "STRIPE_KEY")
+			if err != nil {
+				t.Fatalf("Watch: %v", err)
+			}
+			time.Sleep(50 * time.Millisecond)
+			if n := reads(tc.source); n != 1 {
+				t.Errorf("expected only the first read audited, got %d", n)
+			}
+
+			db.AddKeyForProfile("prod", "STRIPE_KEY", "sk_live_3")
+			if ch := <-changes; ch.Value != "sk_live_3" {
+				t.Errorf("unexpected change: %+v", ch)
+			}
+			time.Sleep(50 * time.Millisecond)
+			if n := reads(tc.source); n != 2 {
+				t.Errorf("expected the changed value audited once, got %d reads", n)
+			}
+		})
+	}
+}
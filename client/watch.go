@@ -0,0 +1,125 @@
+package client
+
+import (
+	"context"
+	"net/url"
+	"time"
+
+	"github.com/stym06/keys/api"
+	"github.com/stym06/keys/db"
+)
+
+// Change is a change to a watched key.
+type Change struct {
+	Name    string
+	Value   string
+	Deleted bool
+	// Err is set, with no Name, when checking for changes failed. Watching
+	// continues.
+	Err error
+}
+
+// Watch reads the named keys and then checks them every WatchInterval,
+// sending a Change whenever a value changes or a key is deleted or comes
+// back. The channel is closed when ctx is done. It fails at once if the
+// keys cannot be read.
+//
+// Checks are not audited; a key is logged as read only when its new value
+// is fetched. The vault is unlocked once, not at every check.
+func (c *Client) Watch(ctx context.Context, names ...string) (<-chan Change, error) {
+	current, unlocked, err := c.fetch(ctx, names)
+	if err != nil {
+		return nil, err
+	}
+	interval := c.WatchInterval
+	if interval <= 0 {
+		interval = DefaultWatchInterval
+	}
+
+	changes := make(chan Change)
+	go func() {
+		defer close(changes)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+
+			var pending []Change
+			changed, missing, err := c.changes(ctx, names, current, &unlocked)
+			if err != nil {
+				pending = append(pending, Change{Err: err})
+			} else {
+				gone := make(map[string]bool, len(missing))
+				for _, name := range missing {
+					gone[name] = true
+				}
+				for _, name := range names {
+					_, had := current[name]
+					if value, ok := changed[name]; ok {
+						pending = append(pending, Change{Name: name, Value: value})
+						current[name] = value
+					} else if had && gone[name] {
+						pending = append(pending, Change{Name: name, Deleted: true})
+						delete(current, name)
+					}
+				}
+			}
+
+			for _, change := range pending {
+				select {
+				case changes <- change:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+	return changes, nil
+}
+
+// changes returns the named keys whose value differs from current, and the
+// names that no longer exist. unlocked records whether the vault has been
+// unlocked, so it is only done once.
+func (c *Client) changes(ctx context.Context, names []string, current map[string]string, unlocked *bool) (map[string]string, []string, error) {
+	if httpClient, base, ok := c.api(); ok {
+		query := url.Values{"name": names}
+		for name, value := range current {
+			query.Add("known", name+":"+api.Digest(value))
+		}
+		var resp api.Changes
+		err := c.call(ctx, httpClient, base, "/v1/changes", query, &resp)
+		if !isUnreachable(ctx, err) {
+			return resp.Keys, resp.Missing, err
+		}
+		// The API is not running; fall back to the vault
+	}
+
+	if !*unlocked {
+		if err := unlock(); err != nil {
+			return nil, nil, err
+		}
+		*unlocked = true
+	}
+	profile, values, err := c.readVault(names)
+	if err != nil {
+		return nil, nil, err
+	}
+	changed := make(map[string]string)
+	var missing []string
+	for _, name := range names {
+		value, ok := values[name]
+		old, had := current[name]
+		switch {
+		case !ok:
+			missing = append(missing, name)
+		case !had || value != old:
+			changed[name] = value
+			_ = db.LogAccessForProfile(profile, name, "get", AuditSource)
+		}
+	}
+	return changed, missing, nil
+}
@@ -0,0 +1,159 @@
+// Package clipboard reads and writes the system clipboard through the
+// platform's command-line tools, falling back to the OSC 52 terminal escape
+// sequence over SSH or when no tool is installed.
+package clipboard
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// ErrUnavailable is returned when the clipboard cannot be read.
+var ErrUnavailable = errors.New("no clipboard tool found (install wl-clipboard, xclip or xsel)")
+
+// Method is the way text was put on the clipboard.
+type Method string
+
+// OSC52 asks the terminal to set the clipboard. It works over SSH, but the
+// clipboard cannot be read back.
+const OSC52 Method = "osc52"
+
+// Readable reports whether the clipboard can be read back after copying
+// with m, which is needed to clear it safely.
+func (m Method) Readable() bool {
+	return m != OSC52
+}
+
+// tool is a clipboard command and the arguments that make it copy from
+// stdin or print the clipboard contents.
+type tool struct {
+	name  string
+	copy  []string
+	paste []string
+}
+
+// tools returns the clipboard commands to try on this platform, best first.
+func tools() []tool {
+	if runtime.GOOS == "darwin" {
+		return []tool{{name: "pbcopy"}}
+	}
+	var ts []tool
+	if os.Getenv("WAYLAND_DISPLAY") != "" {
+		ts = append(ts, tool{name: "wl-copy", paste: []string{"--no-newline"}})
+	}
+	if os.Getenv("DISPLAY") != "" {
+		ts = append(ts,
+			tool{name: "xclip", copy: []string{"-selection", "clipboard"}, paste: []string{"-selection", "clipboard", "-o"}},
+			tool{name: "xsel", copy: []string{"--clipboard", "--input"}, paste: []string{"--clipboard", "--output"}},
+		)
+	}
+	return ts
+}
+
+// pasteCommand returns the command that prints the clipboard for t.
+func (t tool) pasteCommand() string {
+	switch t.name {
+	case "pbcopy":
+		return "pbpaste"
+	case "wl-copy":
+		return "wl-paste"
+	}
+	return t.name
+}
+
+// remote reports whether we are in an SSH session, where local clipboard
+// tools would copy on the wrong machine.
+func remote() bool {
+	return os.Getenv("SSH_TTY") != "" || os.Getenv("SSH_CONNECTION") != ""
+}
+
+// Copy puts text on the clipboard and returns how it did so.
+func Copy(text string) (Method, error) {
+	if !remote() {
+		for _, t := range tools() {
+			path, err := exec.LookPath(t.name)
+			if err != nil {
+				continue
+			}
+			c := exec.Command(path, t.copy...)
+			c.Stdin = strings.NewReader(text)
+			if err := c.Run(); err != nil {
+				return "", fmt.Errorf("%s: %w", t.name, err)
+			}
+			return Method(t.name), nil
+		}
+	}
+	if err := copyOSC52(text); err != nil {
+		return "", err
+	}
+	return OSC52, nil
+}
+
+// copyOSC52 writes the OSC 52 sequence to the controlling terminal,
+// wrapped for tmux when running inside it.
+func copyOSC52(text string) error {
+	var w io.Writer = os.Stderr
+	if tty, err := os.OpenFile("/dev/tty", os.O_WRONLY, 0); err == nil {
+		defer tty.Close()
+		w = tty
+	}
+	_, err := io.WriteString(w, osc52(text, os.Getenv("TMUX") != ""))
+	return err
+}
+
+// osc52 returns the escape sequence that sets the clipboard to text.
+func osc52(text string, tmux bool) string {
+	seq := "\x1b]52;c;" + base64.StdEncoding.EncodeToString([]byte(text)) + "\a"
+	if tmux {
+		// tmux passes the sequence through to the outer terminal
+		seq = "\x1bPtmux;\x1b" + seq + "\x1b\\"
+	}
+	return seq
+}
+
+// Read returns the text on the clipboard.
+func Read() (string, error) {
+	for _, t := range tools() {
+		path, err := exec.LookPath(t.pasteCommand())
+		if err != nil {
+			continue
+		}
+		out, err := exec.Command(path, t.paste...).Output()
+		if err != nil {
+			return "", err
+		}
+		return string(out), nil
+	}
+	return "", ErrUnavailable
+}
+
+// Hash returns the hex SHA-256 of text, used to recognise a copied secret
+// without keeping it around.
+func Hash(text string) string {
+	sum := sha256.Sum256([]byte(text))
+	return hex.EncodeToString(sum[:])
+}
+
+// ClearIfUnchanged empties the clipboard if it still holds the text with
+// the given hash, and reports whether it did.
+func ClearIfUnchanged(hash string) (bool, error) {
+	current, err := Read()
+	if err != nil {
+		return false, err
+	}
+	if Hash(current) != hash {
+		return false, nil
+	}
+	if _, err := Copy(""); err != nil {
+		return false, err
+	}
+	return true, nil
+}
@@ -0,0 +1,31 @@
+package clipboard
+
+import "testing"
+
+func TestOSC52(t *testing.T) {
+	if got := osc52("hi", false); got != "\x1b]52;c;aGk=\a" {
+		t.Errorf("unexpected sequence %q", got)
+	}
+	if got := osc52("hi", true); got != "\x1bPtmux;\x1b\x1b]52;c;aGk=\a\x1b\\" {
+		t.Errorf("unexpected tmux sequence %q", got)
+	}
+}
+
+func TestRemoteUsesOSC52(t *testing.T) {
+	t.Setenv("SSH_TTY", "/dev/pts/1")
+	if !remote() {
+		t.Error("SSH_TTY should mark the session as remote")
+	}
+	if OSC52.Readable() {
+		t.Error("OSC 52 copies cannot be read back")
+	}
+	if !Method("xclip").Readable() {
+		t.Error("xclip copies can be read back")
+	}
+}
+
+func TestHash(t *testing.T) {
+	if Hash("a") == Hash("b") || Hash("a") != Hash("a") {
+		t.Error("Hash should identify text")
+	}
+}
@@ -3,29 +3,77 @@ package cmd
 import (
 	"bufio"
 	"fmt"
-	"os"
+	"io"
 	"strings"
 
+	"github.com/stym06/keys/clipboard"
 	"github.com/stym06/keys/db"
+	"github.com/stym06/keys/provider"
 
 	"github.com/spf13/cobra"
 )
 
 var addCmd = &cobra.Command{
-	Use:   "add <name> <value>",
+	Use:   "add <name> [value]",
 	Short: "Store an API key",
-	Args:  cobra.ExactArgs(2),
+	Long: `Store an API key.
+
+Without a value you are prompted for it twice, with nothing echoed, so the
+secret stays out of shell history and the process list. Use '-' or --stdin
+to read it from a pipe, or --from-clipboard to take it from the clipboard.
+
+If the key already exists you are asked whether to overwrite it; --force
+overwrites and --no-clobber leaves it unchanged without asking.
+
+Examples:
+  keys add OPENAI_KEY
+  op read op://dev/openai/key | keys add OPENAI_KEY -
+  keys add OPENAI_KEY --from-clipboard --force`,
+	Args: cobra.RangeArgs(1, 2),
 	RunE: func(cmd *cobra.Command, args []string) error {
-		name, value := args[0], args[1]
+		name := args[0]
+		stdin, _ := cmd.Flags().GetBool("stdin")
+		fromClipboard, _ := cmd.Flags().GetBool("from-clipboard")
+		force, _ := cmd.Flags().GetBool("force")
+		noClobber, _ := cmd.Flags().GetBool("no-clobber")
+
+		if len(args) == 2 && args[1] == "-" {
+			stdin = true
+			args = args[:1]
+		}
+		sources := 0
+		for _, given := range []bool{len(args) == 2, stdin, fromClipboard} {
+			if given {
+				sources++
+			}
+		}
+		if sources > 1 {
+			return fmt.Errorf("give the value as an argument, with --stdin or with --from-clipboard, not several")
+		}
+		if force && noClobber {
+			return fmt.Errorf("--force and --no-clobber cannot be used together")
+		}
+		if sources == 0 && !stdinIsTerminal() {
+			return fmt.Errorf("no value given: pass it with '-' or --stdin when piping")
+		}
 
 		exists, err := db.KeyExists(name)
 		if err != nil {
 			return err
 		}
 
-		if exists {
-			fmt.Printf("Key %q already exists. [o]verwrite / [e]dit / [c]ancel: ", name)
-			reader := bufio.NewReader(os.Stdin)
+		// Settle an existing key before asking for the secret
+		if exists && !force {
+			if noClobber {
+				fmt.Fprintf(cmd.ErrOrStderr(), "Key %q already exists; left unchanged.\n", name)
+				return nil
+			}
+			if stdin {
+				return fmt.Errorf("key %q already exists: pass --force to overwrite or --no-clobber to keep it", name)
+			}
+			out := cmd.OutOrStdout()
+			fmt.Fprintf(out, "Key %q already exists. [o]verwrite / [e]dit / [c]ancel: ", name)
+			reader := bufio.NewReader(cmd.InOrStdin())
 			input, _ := reader.ReadString('\n')
 			input = strings.TrimSpace(strings.ToLower(input))
 
@@ -33,22 +81,67 @@ var addCmd = &cobra.Command{
 			case "o":
 				// proceed with overwrite
 			case "e":
-				fmt.Printf("Run: keys edit %s\n", name)
+				fmt.Fprintf(out, "Run: keys edit %s\n", name)
 				return nil
 			default:
-				fmt.Println("Cancelled.")
+				fmt.Fprintln(out, "Cancelled.")
 				return nil
 			}
 		}
 
+		var value string
+		switch {
+		case len(args) == 2:
+			value = args[1]
+		case stdin:
+			if value, err = readStdinValue(cmd); err != nil {
+				return err
+			}
+		case fromClipboard:
+			if value, err = clipboard.Read(); err != nil {
+				return err
+			}
+			value = strings.TrimSpace(value)
+		default:
+			if value, err = promptSecret(cmd, fmt.Sprintf("Value for %s: ", name), true); err != nil {
+				return err
+			}
+		}
+		if value == "" {
+			return fmt.Errorf("empty value: nothing stored")
+		}
+
 		if err := db.AddKey(name, value); err != nil {
 			return err
 		}
-		fmt.Printf("Stored %s\n", name)
+		if err := tagProvider(cmd.ErrOrStderr(), name, value); err != nil {
+			return err
+		}
+		fmt.Fprintf(cmd.OutOrStdout(), "Stored %s\n", name)
 		return nil
 	},
 }
 
+// metaProvider holds the provider detected from a key's value.
+const metaProvider = "provider"
+
+// tagProvider records the provider detected from value on the key and
+// warns if the value looks wrong for it.
+func tagProvider(w io.Writer, name, value string) error {
+	for _, warning := range provider.Check(name, value) {
+		fmt.Fprintf(w, "warning: %s: %s\n", name, warning)
+	}
+	id := ""
+	if p := provider.Detect(value); p != nil {
+		id = p.ID
+	}
+	return db.SetKeyMeta(name, metaProvider, id)
+}
+
 func init() {
+	addCmd.Flags().Bool("stdin", false, "read the value from stdin (same as '-')")
+	addCmd.Flags().Bool("from-clipboard", false, "read the value from the clipboard")
+	addCmd.Flags().BoolP("force", "f", false, "overwrite an existing key without asking")
+	addCmd.Flags().BoolP("no-clobber", "n", false, "leave an existing key unchanged without asking")
 	rootCmd.AddCommand(addCmd)
 }
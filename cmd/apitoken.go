@@ -0,0 +1,169 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/stym06/keys/db"
+	"github.com/stym06/keys/schema"
+
+	"github.com/spf13/cobra"
+)
+
+var apiTokenCmd = &cobra.Command{
+	Use:   "api-token",
+	Short: "Manage tokens for the local keys API",
+}
+
+var apiTokenCreateCmd = &cobra.Command{
+	Use:   "create <client>",
+	Short: "Create a token for an application",
+	Long: `Create a token that lets an application read keys through 'keys serve-api'.
+The token is printed once; only a hash of it is stored.
+
+--profile and --match limit the token to profiles and key name globs; at
+least one is required. A token that can read every key in every profile
+must be asked for with --all. --expires makes the token stop working after
+a while, e.g. 12h, 30d or 2w.
+
+Examples:
+  keys api-token create billing-worker --profile prod --match 'STRIPE_*'
+  keys api-token create ci --profile ci --expires 12h
+  keys api-token create admin --all`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		profiles, _ := cmd.Flags().GetStringSlice("profile")
+		patterns, _ := cmd.Flags().GetStringSlice("match")
+		expiresFlag, _ := cmd.Flags().GetString("expires")
+		allFlag, _ := cmd.Flags().GetBool("all")
+
+		scoped := len(profiles) > 0 || len(patterns) > 0
+		if allFlag && scoped {
+			return fmt.Errorf("--all cannot be used with --profile or --match")
+		}
+		if !allFlag && !scoped {
+			return fmt.Errorf("limit the token with --profile or --match, or pass --all to let it read every key")
+		}
+
+		var ttl time.Duration
+		if expiresFlag != "" {
+			var err error
+			if ttl, err = schema.ParseDuration(expiresFlag); err != nil {
+				return err
+			}
+		}
+		token, err := db.CreateAPIToken(args[0], profiles, patterns, ttl)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(cmd.OutOrStdout(), token)
+		fmt.Fprintf(cmd.ErrOrStderr(), "Created a token for %s. It is shown only once; revoke it with 'keys api-token revoke %s'.\n", args[0], args[0])
+		return nil
+	},
+}
+
+// apiTokenItem is the machine-readable form of a token.
+type apiTokenItem struct {
+	Client   string     `json:"client" yaml:"client"`
+	ID       string     `json:"id" yaml:"id"`
+	Profiles []string   `json:"profiles" yaml:"profiles"`
+	Patterns []string   `json:"patterns" yaml:"patterns"`
+	Created  *time.Time `json:"created_at" yaml:"created_at"`
+	LastUsed *time.Time `json:"last_used_at" yaml:"last_used_at"`
+	Revoked  *time.Time `json:"revoked_at" yaml:"revoked_at"`
+	Expires  *time.Time `json:"expires_at" yaml:"expires_at"`
+}
+
+var apiTokenListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List API tokens",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		format, err := outputFormat(cmd)
+		if err != nil {
+			return err
+		}
+		tokens, err := db.ListAPITokens()
+		if err != nil {
+			return err
+		}
+
+		if format != outputTable {
+			items := []apiTokenItem{}
+			for _, t := range tokens {
+				items = append(items, apiTokenItem{
+					Client:   t.Client,
+					ID:       t.ID,
+					Profiles: nonNil(t.Profiles),
+					Patterns: nonNil(t.Patterns),
+					Created:  timestamp(t.CreatedAt),
+					LastUsed: timestamp(t.LastUsedAt),
+					Revoked:  timestamp(t.RevokedAt),
+					Expires:  timestamp(t.ExpiresAt),
+				})
+			}
+			return writeData(cmd, format, items)
+		}
+
+		if len(tokens) == 0 {
+			fmt.Fprintln(cmd.OutOrStdout(), "No API tokens. Create one with 'keys api-token create <client>'.")
+			return nil
+		}
+		rows := [][]string{{"CLIENT", "ID", "PROFILES", "KEYS", "LAST USED", "STATUS"}}
+		for _, t := range tokens {
+			status := "active"
+			switch {
+			case t.RevokedAt != 0:
+				status = "revoked " + formatTimeAgo(t.RevokedAt)
+			case t.ExpiresAt != 0 && t.ExpiresAt <= time.Now().Unix():
+				status = "expired " + formatTimeAgo(t.ExpiresAt)
+			case t.ExpiresAt != 0:
+				status = "expires " + time.Unix(t.ExpiresAt, 0).Format("2006-01-02 15:04")
+			}
+			rows = append(rows, []string{t.Client, t.ID, listOrAll(t.Profiles), listOrAll(t.Patterns), formatTimeAgo(t.LastUsedAt), status})
+		}
+		printTable(cmd.OutOrStdout(), rows)
+		return nil
+	},
+}
+
+var apiTokenRevokeCmd = &cobra.Command{
+	Use:   "revoke <client|id>",
+	Short: "Revoke an API token",
+	Long:  `Revoke a client's token. A running 'keys serve-api' rejects it from the next request.`,
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := db.RevokeAPIToken(args[0]); err != nil {
+			return err
+		}
+		fmt.Fprintf(cmd.OutOrStdout(), "Revoked %s\n", args[0])
+		return nil
+	},
+}
+
+func listOrAll(items []string) string {
+	if len(items) == 0 {
+		return "*"
+	}
+	return strings.Join(items, ",")
+}
+
+func nonNil(items []string) []string {
+	if items == nil {
+		return []string{}
+	}
+	return items
+}
+
+func init() {
+	apiTokenCreateCmd.Flags().StringSliceP("profile", "p", nil, "profiles the token may read")
+	apiTokenCreateCmd.Flags().StringSlice("match", nil, "key name globs the token may read")
+	apiTokenCreateCmd.Flags().Bool("all", false, "let the token read every key in every profile")
+	apiTokenCreateCmd.Flags().String("expires", "", "stop accepting the token after this long, e.g. 12h or 30d")
+	addOutputFlag(apiTokenListCmd)
+	apiTokenCmd.AddCommand(apiTokenCreateCmd)
+	apiTokenCmd.AddCommand(apiTokenListCmd)
+	apiTokenCmd.AddCommand(apiTokenRevokeCmd)
+	rootCmd.AddCommand(apiTokenCmd)
+}
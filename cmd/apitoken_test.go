@@ -0,0 +1,38 @@
+package cmd
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stym06/keys/db"
+)
+
+func TestAPITokenCreateNeedsScope(t *testing.T) {
+	setupTestEnv(t)
+	run := func(args ...string) error {
+		t.Helper()
+		rootCmd.SetErr(new(bytes.Buffer))
+		defer rootCmd.SetErr(nil)
+		_, err := runCmd(t, append([]string{"api-token", "create"}, args...)...)
+		return err
+	}
+
+	if err := run("ci"); err == nil || !strings.Contains(err.Error(), "--all") {
+		t.Errorf("an unscoped token should need --all, got %v", err)
+	}
+	if err := run("ci", "--all", "--profile", "prod"); err == nil {
+		t.Error("--all with --profile should fail")
+	}
+	if err := run("billing", "--match", "STRIPE_*"); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := run("admin", "--all"); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	tokens, _ := db.ListAPITokens()
+	if len(tokens) != 2 {
+		t.Errorf("expected 2 tokens, got %+v", tokens)
+	}
+}
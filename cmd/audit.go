@@ -1,10 +1,13 @@
 package cmd
 
 import (
+	"encoding/csv"
+	"encoding/json"
 	"fmt"
 	"time"
 
 	"github.com/stym06/keys/db"
+	"github.com/stym06/keys/schema"
 
 	"github.com/spf13/cobra"
 )
@@ -14,15 +17,36 @@ var auditCmd = &cobra.Command{
 	Short: "Show key access history",
 	Long: `Show a summary of when and how often keys have been accessed.
 
+The access log can be filtered by time, key, action and source, across
+every profile with --all-profiles, and exported as CSV or JSON Lines with
+--output csv or --output jsonl. Filtering or exporting implies --log.
+
+--since and --until take a date (2026-09-01, local time), a time
+(2026-09-01T14:00 or RFC 3339), or a duration ago (12h, 7d, 2w); a date
+given to --until includes that whole day.
+
 Examples:
   keys audit              # summary: access counts per key
   keys audit --log        # full access log (most recent first)
   keys audit --log -n 20  # last 20 access events
-  keys audit --clear      # clear the audit log`,
+  keys audit --output json
+  keys audit --clear      # clear the audit log
+
+  keys audit --since 2026-09-01 --until 2026-09-30 --key 'STRIPE_*'
+  keys audit --action inject --source picker --all-profiles
+  keys audit --since 7d --source 'api:*' --output csv > audit.csv
+  keys audit --all-profiles --output jsonl | siem-ingest`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		clearFlag, _ := cmd.Flags().GetBool("clear")
 		logFlag, _ := cmd.Flags().GetBool("log")
 		limit, _ := cmd.Flags().GetInt("limit")
+		format, _ := cmd.Flags().GetString("output")
+		switch format {
+		case outputTable, outputJSON, outputYAML, outputCSV, outputJSONL:
+		default:
+			return fmt.Errorf("invalid --output %q (use table, json, yaml, csv or jsonl)", format)
+		}
+		export := format == outputCSV || format == outputJSONL
 
 		if clearFlag {
 			if err := db.ClearAuditLog(); err != nil {
@@ -32,20 +56,105 @@ Examples:
 			return nil
 		}
 
-		if logFlag {
-			return showAuditLog(cmd, limit)
+		query, filtered, err := auditQuery(cmd)
+		if err != nil {
+			return err
+		}
+		if export && !cmd.Flags().Changed("limit") {
+			limit = 0
+		}
+		query.Limit = limit
+
+		if logFlag || filtered || export {
+			return showAuditLog(cmd, format, query)
 		}
 
-		return showAuditSummary(cmd)
+		return showAuditSummary(cmd, format)
 	},
 }
 
-func showAuditSummary(cmd *cobra.Command) error {
+// auditSummaryItem and auditLogItem are the machine-readable forms of
+// 'keys audit' and 'keys audit --log'.
+type auditSummaryItem struct {
+	Key          string     `json:"key" yaml:"key"`
+	Count        int        `json:"count" yaml:"count"`
+	LastAccessed *time.Time `json:"last_accessed" yaml:"last_accessed"`
+}
+
+type auditLogItem struct {
+	Key     string     `json:"key" yaml:"key"`
+	Profile string     `json:"profile" yaml:"profile"`
+	Action  string     `json:"action" yaml:"action"`
+	Source  string     `json:"source" yaml:"source"`
+	Time    *time.Time `json:"time" yaml:"time"`
+}
+
+// Export formats accepted by --output for 'keys audit' only.
+const (
+	outputCSV   = "csv"
+	outputJSONL = "jsonl"
+)
+
+// auditQuery builds the log query from the filter flags and reports
+// whether any filter was given.
+func auditQuery(cmd *cobra.Command) (db.AuditQuery, bool, error) {
+	var q db.AuditQuery
+	since, _ := cmd.Flags().GetString("since")
+	until, _ := cmd.Flags().GetString("until")
+	q.Key, _ = cmd.Flags().GetString("key")
+	q.Action, _ = cmd.Flags().GetString("action")
+	q.Source, _ = cmd.Flags().GetString("source")
+	q.AllProfiles, _ = cmd.Flags().GetBool("all-profiles")
+
+	var err error
+	if since != "" {
+		if q.Since, err = parseAuditTime(since, false); err != nil {
+			return q, false, fmt.Errorf("--since: %w", err)
+		}
+	}
+	if until != "" {
+		if q.Until, err = parseAuditTime(until, true); err != nil {
+			return q, false, fmt.Errorf("--until: %w", err)
+		}
+	}
+	filtered := since != "" || until != "" || q.Key != "" || q.Action != "" || q.Source != "" || q.AllProfiles
+	return q, filtered, nil
+}
+
+// parseAuditTime parses a --since or --until value into unix seconds. A
+// bare date given as an end bound means the end of that day.
+func parseAuditTime(s string, end bool) (int64, error) {
+	if t, err := time.ParseInLocation("2006-01-02", s, time.Local); err == nil {
+		if end {
+			t = t.AddDate(0, 0, 1)
+		}
+		return t.Unix(), nil
+	}
+	for _, layout := range []string{time.RFC3339, "2006-01-02T15:04:05", "2006-01-02T15:04", "2006-01-02 15:04"} {
+		if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			return t.Unix(), nil
+		}
+	}
+	if d, err := schema.ParseDuration(s); err == nil {
+		return time.Now().Add(-d).Unix(), nil
+	}
+	return 0, fmt.Errorf("invalid time %q (use a date such as 2026-09-01, a time, or a duration such as 7d)", s)
+}
+
+func showAuditSummary(cmd *cobra.Command, format string) error {
 	entries, err := db.GetAuditSummary()
 	if err != nil {
 		return err
 	}
 
+	if format != outputTable {
+		items := make([]auditSummaryItem, 0, len(entries))
+		for _, e := range entries {
+			items = append(items, auditSummaryItem{Key: e.KeyName, Count: e.Count, LastAccessed: timestamp(e.AccessedAt)})
+		}
+		return writeData(cmd, format, items)
+	}
+
 	if len(entries) == 0 {
 		fmt.Fprintln(cmd.OutOrStdout(), "No access events recorded yet.")
 		return nil
@@ -71,30 +180,77 @@ func showAuditSummary(cmd *cobra.Command) error {
 	return nil
 }
 
-func showAuditLog(cmd *cobra.Command, limit int) error {
-	entries, err := db.GetAuditLog(limit)
+func showAuditLog(cmd *cobra.Command, format string, query db.AuditQuery) error {
+	entries, err := db.QueryAuditLog(query)
 	if err != nil {
 		return err
 	}
 
+	items := make([]auditLogItem, 0, len(entries))
+	for _, e := range entries {
+		items = append(items, auditLogItem{Key: e.KeyName, Profile: e.Profile, Action: e.Action, Source: e.Source, Time: timestamp(e.AccessedAt)})
+	}
+	switch {
+	case format == outputCSV:
+		return writeAuditCSV(cmd, items)
+	case format == outputJSONL:
+		enc := json.NewEncoder(cmd.OutOrStdout())
+		for _, item := range items {
+			if err := enc.Encode(item); err != nil {
+				return err
+			}
+		}
+		return nil
+	case format != outputTable:
+		return writeData(cmd, format, items)
+	}
+
 	if len(entries) == 0 {
 		fmt.Fprintln(cmd.OutOrStdout(), "No access events recorded yet.")
 		return nil
 	}
 
-	maxLen := 0
+	header := []string{"KEY", "ACTION", "SOURCE", "TIME"}
+	if query.AllProfiles {
+		header = append([]string{"PROFILE"}, header...)
+	}
+	rows := [][]string{header}
 	for _, e := range entries {
-		if len(e.KeyName) > maxLen {
-			maxLen = len(e.KeyName)
+		t := time.Unix(e.AccessedAt, 0).Format("2006-01-02 15:04:05") + " (" + formatTimeAgo(e.AccessedAt) + ")"
+		row := []string{e.KeyName, e.Action, e.Source, t}
+		if query.AllProfiles {
+			row = append([]string{e.Profile}, row...)
 		}
+		rows = append(rows, row)
+	}
+	printTable(cmd.OutOrStdout(), rows)
+	return nil
+}
+
+// writeAuditCSV writes log entries as CSV with a header row. Times are
+// RFC 3339 in UTC.
+func writeAuditCSV(cmd *cobra.Command, items []auditLogItem) error {
+	w := csv.NewWriter(cmd.OutOrStdout())
+	w.Write([]string{"time", "profile", "key", "action", "source"})
+	for _, item := range items {
+		w.Write([]string{item.Time.Format(time.RFC3339), item.Profile, item.Key, item.Action, item.Source})
 	}
+	w.Flush()
+	return w.Error()
+}
 
-	fmt.Fprintf(cmd.OutOrStdout(), "%-*s  %-8s  %-8s  %s\n", maxLen, "KEY", "ACTION", "SOURCE", "TIME")
+// accessByKey returns the audit summary of profile by key name. Keys that
+// were never accessed are absent.
+func accessByKey(profile string) (map[string]db.AuditEntry, error) {
+	entries, err := db.GetAuditSummaryForProfile(profile)
+	if err != nil {
+		return nil, err
+	}
+	access := make(map[string]db.AuditEntry, len(entries))
 	for _, e := range entries {
-		t := formatTimeAgo(e.AccessedAt)
-		fmt.Fprintf(cmd.OutOrStdout(), "%-*s  %-8s  %-8s  %s\n", maxLen, e.KeyName, e.Action, e.Source, t)
+		access[e.KeyName] = e
 	}
-	return nil
+	return access, nil
 }
 
 func formatTimeAgo(unix int64) string {
@@ -129,6 +285,13 @@ func formatTimeAgo(unix int64) string {
 func init() {
 	auditCmd.Flags().BoolP("log", "l", false, "show full access log")
 	auditCmd.Flags().BoolP("clear", "c", false, "clear the audit log")
-	auditCmd.Flags().IntP("limit", "n", 50, "number of log entries to show")
+	auditCmd.Flags().IntP("limit", "n", 50, "number of log entries to show (0 for all; default all with csv or jsonl output)")
+	auditCmd.Flags().String("since", "", "only entries at or after this date, time or duration ago")
+	auditCmd.Flags().String("until", "", "only entries before this date, time or duration ago")
+	auditCmd.Flags().String("key", "", "only entries for keys matching this glob")
+	auditCmd.Flags().String("action", "", "only entries with this action (get, inject, list, ...)")
+	auditCmd.Flags().String("source", "", "only entries from sources matching this glob (cli, picker, api:*, ...)")
+	auditCmd.Flags().Bool("all-profiles", false, "include entries from every profile")
+	auditCmd.Flags().String("output", outputTable, "output format: table, json, yaml, or csv or jsonl to export the log")
 	rootCmd.AddCommand(auditCmd)
 }
@@ -0,0 +1,113 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stym06/keys/db"
+)
+
+func runAudit(t *testing.T, args ...string) (string, error) {
+	t.Helper()
+	buf := new(bytes.Buffer)
+	rootCmd.SetOut(buf)
+	rootCmd.SetArgs(append([]string{"audit"}, args...))
+	err := rootCmd.Execute()
+	for _, name := range []string{"log", "limit", "since", "until", "key", "action", "source", "all-profiles", "output"} {
+		f := auditCmd.Flags().Lookup(name)
+		f.Value.Set(f.DefValue)
+		f.Changed = false
+	}
+	return buf.String(), err
+}
+
+func seedAudit(t *testing.T) {
+	t.Helper()
+	setupTestEnv(t)
+	db.LogAccessForProfile("default", "STRIPE_KEY", "get", "cli")
+	db.LogAccessForProfile("default", "STRIPE_HOOK", "inject", "picker")
+	db.LogAccessForProfile("default", "OPENAI_KEY", "inject", "picker")
+	db.LogAccessForProfile("prod", "STRIPE_KEY", "inject", "picker")
+}
+
+func TestAuditExportCSV(t *testing.T) {
+	seedAudit(t)
+
+	out, err := runAudit(t, "--key", "STRIPE_*", "--action", "inject", "--all-profiles", "--output", "csv")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	records, err := csv.NewReader(strings.NewReader(out)).ReadAll()
+	if err != nil {
+		t.Fatalf("invalid CSV: %v\n%s", err, out)
+	}
+	if len(records) != 3 || strings.Join(records[0], ",") != "time,profile,key,action,source" {
+		t.Fatalf("unexpected CSV:\n%s", out)
+	}
+	profiles := map[string]bool{}
+	for _, r := range records[1:] {
+		if _, err := time.Parse(time.RFC3339, r[0]); err != nil {
+			t.Errorf("time %q is not RFC 3339", r[0])
+		}
+		if !strings.HasPrefix(r[2], "STRIPE_") || r[3] != "inject" || r[4] != "picker" {
+			t.Errorf("unexpected record: %v", r)
+		}
+		profiles[r[1]] = true
+	}
+	if !profiles["default"] || !profiles["prod"] {
+		t.Errorf("expected entries from both profiles: %v", records)
+	}
+}
+
+func TestAuditExportJSONL(t *testing.T) {
+	seedAudit(t)
+
+	out, err := runAudit(t, "--since", "1h", "--source", "pick*", "--output", "jsonl")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 lines, got:\n%s", out)
+	}
+	for _, line := range lines {
+		var item auditLogItem
+		if err := json.Unmarshal([]byte(line), &item); err != nil {
+			t.Fatalf("invalid JSON line %q: %v", line, err)
+		}
+		if item.Profile != "default" || item.Source != "picker" || item.Time == nil {
+			t.Errorf("unexpected entry: %+v", item)
+		}
+	}
+
+	out, _ = runAudit(t, "--until", "1h", "--output", "jsonl")
+	if out != "" {
+		t.Errorf("expected no entries before an hour ago, got:\n%s", out)
+	}
+}
+
+func TestAuditFilterFlagsErrors(t *testing.T) {
+	setupTestEnv(t)
+
+	if _, err := runAudit(t, "--since", "last tuesday"); err == nil || !strings.Contains(err.Error(), "--since") {
+		t.Errorf("expected an invalid --since error, got %v", err)
+	}
+	if _, err := runAudit(t, "--output", "xml"); err == nil || !strings.Contains(err.Error(), "csv or jsonl") {
+		t.Errorf("expected an invalid --output error, got %v", err)
+	}
+	if _, err := runWithOutput(t, "csv", "ls"); err == nil {
+		t.Error("csv output should only be accepted by audit")
+	}
+}
+
+func TestParseAuditTimeDateUntil(t *testing.T) {
+	start, _ := parseAuditTime("2026-09-01", false)
+	end, _ := parseAuditTime("2026-09-01", true)
+	if end-start != 24*60*60 {
+		t.Errorf("expected --until with a date to include the whole day, got %d..%d", start, end)
+	}
+}
@@ -0,0 +1,176 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/stym06/keys/db"
+
+	"github.com/spf13/cobra"
+)
+
+// Key names holding AWS credentials, as the AWS CLI reads them from the
+// environment.
+const (
+	awsAccessKeyID     = "AWS_ACCESS_KEY_ID"
+	awsSecretAccessKey = "AWS_SECRET_ACCESS_KEY"
+	awsSessionToken    = "AWS_SESSION_TOKEN"
+	awsExpiration      = "AWS_CREDENTIAL_EXPIRATION"
+)
+
+var awsCmd = &cobra.Command{
+	Use:   "aws",
+	Short: "Hand stored AWS credentials to the AWS CLI and SDKs",
+}
+
+var awsCredentialProcessCmd = &cobra.Command{
+	Use:   "credential-process",
+	Short: "Print AWS credentials in the credential_process JSON format",
+	Long: `Print the AWS credentials stored in a profile as the JSON the AWS CLI and SDKs
+expect from a credential_process:
+
+  AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY                  required
+  AWS_SESSION_TOKEN, AWS_CREDENTIAL_EXPIRATION (RFC 3339)   optional
+
+--prefix reads prefixed names instead, e.g. --prefix BILLING_ for
+BILLING_AWS_ACCESS_KEY_ID. Use 'keys aws export --credential-process' to
+write the ~/.aws/config entry. Reads are audited with source "aws".
+
+Examples:
+  keys aws credential-process --profile prod`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		profile, prefix := awsFlags(cmd)
+		creds, err := readAWSCredentials(profile, prefix)
+		if err != nil {
+			return err
+		}
+		enc := json.NewEncoder(cmd.OutOrStdout())
+		enc.SetIndent("", "  ")
+		return enc.Encode(creds)
+	},
+}
+
+var awsExportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Render an AWS credentials file section for a profile",
+	Long: `Render the AWS credentials stored in a keys profile as a section for
+~/.aws/credentials, named after the profile unless --aws-profile is given.
+
+With --credential-process the section is instead a ~/.aws/config entry that
+runs 'keys aws credential-process', so no secret is written to disk.
+
+Examples:
+  keys aws export --profile prod >> ~/.aws/credentials
+  keys aws export --profile prod --credential-process >> ~/.aws/config`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		profile, prefix := awsFlags(cmd)
+		awsProfile, _ := cmd.Flags().GetString("aws-profile")
+		process, _ := cmd.Flags().GetBool("credential-process")
+		if awsProfile == "" {
+			awsProfile = profile
+		}
+		out := cmd.OutOrStdout()
+
+		if process {
+			// Unlike the shell hooks, credential_process is run by whatever
+			// program uses the AWS SDK, often without the user's PATH, so it
+			// gets this binary's absolute path rather than keysBinary()
+			bin, err := os.Executable()
+			if err != nil {
+				return err
+			}
+			run := shellQuote(bin) + " aws credential-process --profile " + shellQuote(profile)
+			if prefix != "" {
+				run += " --prefix " + shellQuote(prefix)
+			}
+			// The config file names every section but default "profile NAME"
+			section := awsProfile
+			if section != "default" {
+				section = "profile " + section
+			}
+			fmt.Fprintf(out, "[%s]\ncredential_process = %s\n", section, run)
+			return nil
+		}
+
+		creds, err := readAWSCredentials(profile, prefix)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "[%s]\n", awsProfile)
+		fmt.Fprintf(out, "aws_access_key_id = %s\n", creds.AccessKeyID)
+		fmt.Fprintf(out, "aws_secret_access_key = %s\n", creds.SecretAccessKey)
+		if creds.SessionToken != "" {
+			fmt.Fprintf(out, "aws_session_token = %s\n", creds.SessionToken)
+		}
+		return nil
+	},
+}
+
+// awsCredentials is the credential_process output format.
+type awsCredentials struct {
+	Version         int    `json:"Version"`
+	AccessKeyID     string `json:"AccessKeyId"`
+	SecretAccessKey string `json:"SecretAccessKey"`
+	SessionToken    string `json:"SessionToken,omitempty"`
+	Expiration      string `json:"Expiration,omitempty"`
+}
+
+func awsFlags(cmd *cobra.Command) (profile, prefix string) {
+	profile, _ = cmd.Flags().GetString("profile")
+	prefix, _ = cmd.Flags().GetString("prefix")
+	if profile == "" {
+		profile = db.GetActiveProfile()
+	}
+	return profile, prefix
+}
+
+// readAWSCredentials reads the AWS key names, with prefix, from profile.
+func readAWSCredentials(profile, prefix string) (*awsCredentials, error) {
+	names := []string{awsAccessKeyID, awsSecretAccessKey, awsSessionToken, awsExpiration}
+	for i := range names {
+		names[i] = prefix + names[i]
+	}
+	keys, err := db.GetKeysByNamesForProfile(names, profile)
+	if err != nil {
+		return nil, err
+	}
+	values := make(map[string]string, len(keys))
+	for _, k := range keys {
+		values[strings.TrimPrefix(k.Name, prefix)] = k.Value
+		_ = db.LogAccessForProfile(profile, k.Name, "get", "aws")
+	}
+
+	for _, required := range []string{awsAccessKeyID, awsSecretAccessKey} {
+		if values[required] == "" {
+			return nil, fmt.Errorf("%s%s not found in profile %q", prefix, required, profile)
+		}
+	}
+	if exp := values[awsExpiration]; exp != "" {
+		if _, err := time.Parse(time.RFC3339, exp); err != nil {
+			return nil, fmt.Errorf("%s%s: %q is not an RFC 3339 time", prefix, awsExpiration, exp)
+		}
+	}
+	return &awsCredentials{
+		Version:         1,
+		AccessKeyID:     values[awsAccessKeyID],
+		SecretAccessKey: values[awsSecretAccessKey],
+		SessionToken:    values[awsSessionToken],
+		Expiration:      values[awsExpiration],
+	}, nil
+}
+
+func init() {
+	for _, c := range []*cobra.Command{awsCredentialProcessCmd, awsExportCmd} {
+		c.Flags().StringP("profile", "p", "", "keys profile holding the credentials (default: active profile)")
+		c.Flags().String("prefix", "", "prefix of the key names, e.g. BILLING_ for BILLING_AWS_ACCESS_KEY_ID")
+		awsCmd.AddCommand(c)
+	}
+	awsExportCmd.Flags().String("aws-profile", "", "name of the AWS profile section (default: the keys profile)")
+	awsExportCmd.Flags().Bool("credential-process", false, "write a ~/.aws/config entry that runs 'keys aws credential-process' instead of the secrets")
+	rootCmd.AddCommand(awsCmd)
+}
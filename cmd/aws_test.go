@@ -0,0 +1,53 @@
+package cmd
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stym06/keys/db"
+)
+
+func TestAWSCredentialProcess(t *testing.T) {
+	setupTestEnv(t)
+	db.SetActiveProfile("prod")
+	db.AddKey("AWS_ACCESS_KEY_ID", "AKIAPROD")
+	db.AddKey("AWS_SECRET_ACCESS_KEY", "prod-secret")
+	db.AddKey("BILLING_AWS_ACCESS_KEY_ID", "AKIABILL")
+	db.AddKey("BILLING_AWS_SECRET_ACCESS_KEY", "bill-secret")
+	db.AddKey("BILLING_AWS_SESSION_TOKEN", "token")
+	db.SetActiveProfile("default")
+
+	out, err := runCmd(t, "aws", "credential-process", "--profile", "prod")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var creds map[string]any
+	if err := json.Unmarshal([]byte(out), &creds); err != nil {
+		t.Fatalf("invalid JSON %q: %v", out, err)
+	}
+	if creds["Version"] != float64(1) || creds["AccessKeyId"] != "AKIAPROD" || creds["SecretAccessKey"] != "prod-secret" {
+		t.Errorf("unexpected credentials %v", creds)
+	}
+	if _, ok := creds["SessionToken"]; ok {
+		t.Error("SessionToken should be omitted when not stored")
+	}
+
+	out, _ = runCmd(t, "aws", "export", "--profile", "prod", "--prefix", "BILLING_", "--aws-profile", "billing")
+	want := "[billing]\naws_access_key_id = AKIABILL\naws_secret_access_key = bill-secret\naws_session_token = token\n"
+	if out != want {
+		t.Errorf("unexpected credentials section:\n%s", out)
+	}
+
+	out, _ = runCmd(t, "aws", "export", "--profile", "prod", "--credential-process")
+	if !strings.HasPrefix(out, "[profile prod]\ncredential_process = ") || !strings.Contains(out, "aws credential-process --profile 'prod'") {
+		t.Errorf("unexpected config section:\n%s", out)
+	}
+	if strings.Contains(out, "prod-secret") {
+		t.Error("the credential_process entry should not contain secrets")
+	}
+
+	if _, err := runCmd(t, "aws", "credential-process"); err == nil {
+		t.Error("expected an error when the active profile has no AWS keys")
+	}
+}
@@ -1,55 +1,98 @@
 package cmd
 
 import (
-	"bufio"
 	"fmt"
 	"os"
+	"sort"
 	"strings"
+	"time"
 
 	"github.com/stym06/keys/db"
+	"github.com/stym06/keys/envscan"
+	"github.com/stym06/keys/schema"
 
 	"github.com/spf13/cobra"
 )
 
 var checkCmd = &cobra.Command{
 	Use:   "check [file]",
-	Short: "Verify required keys are present",
-	Long: `Check that all keys listed in a requirements file exist in the current profile.
+	Short: "Verify required keys are present and valid",
+	Long: `Check the keys listed in a requirements file against the current profile.
 
 Reads key names (one per line) from .keys.required by default, or a custom file.
-Lines starting with # are ignored.
+Lines starting with # are ignored. Each name may be followed by rules and a
+trailing # description:
+
+  OPENAI_KEY  pattern=^sk-  min=40  max-age=90d  # OpenAI API key
+  SENTRY_DSN  optional                           # error reporting
+
+  optional      the key may be absent
+  pattern=RE    the value must match the regular expression
+  min=N         the value must be at least N characters
+  max-age=DUR   the key must have been updated within DUR (e.g. 90d, 2w, 12h)
+
+Exits with code 1 if any required key is missing or any key breaks a rule.
 
 Examples:
   keys check
-  keys check requirements.txt`,
+  keys check requirements.txt
+  keys check --fix
+  keys check --output json
+
+With --scan, source files are searched for environment variable reads
+(os.Getenv/os.LookupEnv in Go, process.env in JS/TS, os.environ/os.getenv in
+Python, ENV[...] in Ruby, ${VAR} in docker-compose files) and compared with
+the profile and the requirements file. --write appends unlisted variables to
+the requirements file.
+
+  keys check --scan ./...
+  keys check --scan ./src --scan docker-compose.yml --write`,
 	Args: cobra.MaximumNArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
+		fixFlag, _ := cmd.Flags().GetBool("fix")
+		jsonFlag, _ := cmd.Flags().GetBool("json")
+		scanFlag, _ := cmd.Flags().GetStringSlice("scan")
+		writeFlag, _ := cmd.Flags().GetBool("write")
+
+		format, err := outputFormat(cmd)
+		if err != nil {
+			return err
+		}
+		if jsonFlag {
+			format = outputJSON
+		}
+		if fixFlag && format != outputTable {
+			return fmt.Errorf("--fix prompts for values and cannot be used with --output %s", format)
+		}
+		if fixFlag && !stdinIsTerminal() {
+			return fmt.Errorf("--fix prompts for values and needs a terminal")
+		}
+
 		file := ".keys.required"
 		if len(args) == 1 {
 			file = args[0]
 		}
 
+		if len(scanFlag) > 0 {
+			return runCheckScan(cmd, file, scanFlag, writeFlag, format)
+		}
+
 		f, err := os.Open(file)
 		if err != nil {
 			return fmt.Errorf("cannot open %s: %w", file, err)
 		}
-		defer f.Close()
-
-		var required []string
-		scanner := bufio.NewScanner(f)
-		for scanner.Scan() {
-			line := strings.TrimSpace(scanner.Text())
-			if line == "" || strings.HasPrefix(line, "#") {
-				continue
-			}
-			required = append(required, line)
-		}
-		if err := scanner.Err(); err != nil {
-			return err
+		rules, err := schema.Parse(f)
+		f.Close()
+		if err != nil {
+			return fmt.Errorf("%s: %w", file, err)
 		}
 
-		if len(required) == 0 {
-			fmt.Fprintln(cmd.OutOrStdout(), "No keys listed in", file)
+		out := cmd.OutOrStdout()
+		if len(rules) == 0 {
+			if format != outputTable {
+				return writeCheckReport(cmd, format, file, nil)
+			}
+			fmt.Fprintln(out, "No keys listed in", file)
 			return nil
 		}
 
@@ -57,32 +100,298 @@ Examples:
 		if err != nil {
 			return err
 		}
+		results := schema.Validate(rules, allKeys, time.Now())
 
-		have := make(map[string]bool)
-		for _, k := range allKeys {
-			have[k.Name] = true
+		if fixFlag {
+			if results, err = fixViolations(cmd, rules, results); err != nil {
+				return err
+			}
 		}
 
-		missing := 0
-		for _, name := range required {
-			if have[name] {
-				fmt.Fprintf(cmd.OutOrStdout(), "  ✓ %s\n", name)
-			} else {
-				fmt.Fprintf(cmd.OutOrStdout(), "  ✗ %s — missing\n", name)
-				missing++
+		failed := 0
+		for _, r := range results {
+			if !r.OK() {
+				failed++
 			}
 		}
 
-		if missing > 0 {
-			fmt.Fprintf(cmd.OutOrStdout(), "\n%d of %d keys missing.\n", missing, len(required))
-			os.Exit(1)
+		if format != outputTable {
+			if err := writeCheckReport(cmd, format, file, results); err != nil {
+				return err
+			}
+		} else {
+			printCheckResults(cmd, results, failed)
 		}
 
-		fmt.Fprintf(cmd.OutOrStdout(), "\nAll %d keys present.\n", len(required))
+		if failed > 0 {
+			return exitWith(cmd, 1)
+		}
 		return nil
 	},
 }
 
+func printCheckResults(cmd *cobra.Command, results []schema.Result, failed int) {
+	out := cmd.OutOrStdout()
+	for _, r := range results {
+		name := r.Rule.Name
+		switch {
+		case !r.OK():
+			var msgs []string
+			for _, v := range r.Violations {
+				msgs = append(msgs, v.Message)
+			}
+			fmt.Fprintf(out, "  ✗ %s — %s\n", name, strings.Join(msgs, ", "))
+		case !r.Present:
+			fmt.Fprintf(out, "  ○ %s — optional, not set\n", name)
+		default:
+			fmt.Fprintf(out, "  ✓ %s\n", name)
+		}
+	}
+
+	if failed > 0 {
+		fmt.Fprintf(out, "\n%d of %d keys failed.\n", failed, len(results))
+		return
+	}
+	fmt.Fprintf(out, "\nAll %d keys OK.\n", len(results))
+}
+
+type checkKeyJSON struct {
+	Name        string             `json:"name" yaml:"name"`
+	Required    bool               `json:"required" yaml:"required"`
+	Present     bool               `json:"present" yaml:"present"`
+	OK          bool               `json:"ok" yaml:"ok"`
+	Description string             `json:"description,omitempty" yaml:"description,omitempty"`
+	Violations  []schema.Violation `json:"violations" yaml:"violations"`
+}
+
+type checkJSON struct {
+	File    string         `json:"file" yaml:"file"`
+	Profile string         `json:"profile" yaml:"profile"`
+	OK      bool           `json:"ok" yaml:"ok"`
+	Keys    []checkKeyJSON `json:"keys" yaml:"keys"`
+}
+
+func writeCheckReport(cmd *cobra.Command, format, file string, results []schema.Result) error {
+	return writeData(cmd, format, checkReport(file, db.GetActiveProfile(), results))
+}
+
+func checkReport(file, profile string, results []schema.Result) checkJSON {
+	report := checkJSON{File: file, Profile: profile, OK: true, Keys: []checkKeyJSON{}}
+	for _, r := range results {
+		k := checkKeyJSON{
+			Name:        r.Rule.Name,
+			Required:    !r.Rule.Optional,
+			Present:     r.Present,
+			OK:          r.OK(),
+			Description: r.Rule.Description,
+			Violations:  r.Violations,
+		}
+		if k.Violations == nil {
+			k.Violations = []schema.Violation{}
+		}
+		if !k.OK {
+			report.OK = false
+		}
+		report.Keys = append(report.Keys, k)
+	}
+	return report
+}
+
+// fixViolations prompts for a new value for every failing key, stores the
+// ones that satisfy their rule and returns the updated results.
+func fixViolations(cmd *cobra.Command, rules []schema.Rule, results []schema.Result) ([]schema.Result, error) {
+	for _, r := range results {
+		if r.OK() {
+			continue
+		}
+		prompt := r.Rule.Name
+		if r.Rule.Description != "" {
+			prompt += " (" + r.Rule.Description + ")"
+		}
+		for {
+			value, err := promptSecret(cmd, fmt.Sprintf("Enter value for %s, or leave empty to skip: ", prompt), false)
+			if err != nil {
+				return nil, err
+			}
+			value = strings.TrimSpace(value)
+			if value == "" {
+				break
+			}
+			violations := schema.Check(r.Rule, db.Key{Name: r.Rule.Name, Value: value, UpdatedAt: time.Now().Unix()}, time.Now())
+			if len(violations) == 0 {
+				if err := db.AddKey(r.Rule.Name, value); err != nil {
+					return nil, err
+				}
+				fmt.Fprintf(cmd.ErrOrStderr(), "Stored %s\n", r.Rule.Name)
+				break
+			}
+			fmt.Fprintf(cmd.ErrOrStderr(), "  %s\n", violations[0].Message)
+		}
+	}
+
+	allKeys, err := db.GetAllKeys()
+	if err != nil {
+		return nil, err
+	}
+	return schema.Validate(rules, allKeys, time.Now()), nil
+}
+
+type scanVarJSON struct {
+	Name      string   `json:"name" yaml:"name"`
+	Present   bool     `json:"present" yaml:"present"`
+	Listed    bool     `json:"listed" yaml:"listed"`
+	Locations []string `json:"locations" yaml:"locations"`
+}
+
+// runCheckScan reports environment variables read by source code, whether
+// each is stored in the profile and whether it is listed in file.
+func runCheckScan(cmd *cobra.Command, file string, paths []string, write bool, format string) error {
+	refs, err := envscan.Scan(paths)
+	if err != nil {
+		return err
+	}
+
+	locations := make(map[string][]string)
+	var names []string
+	for _, r := range refs {
+		if envscan.IsSystem(r.Name) {
+			continue
+		}
+		if _, ok := locations[r.Name]; !ok {
+			names = append(names, r.Name)
+		}
+		locations[r.Name] = append(locations[r.Name], fmt.Sprintf("%s:%d", r.File, r.Line))
+	}
+	sort.Strings(names)
+
+	listed := make(map[string]bool)
+	if f, err := os.Open(file); err == nil {
+		rules, err := schema.Parse(f)
+		f.Close()
+		if err != nil {
+			return fmt.Errorf("%s: %w", file, err)
+		}
+		for _, r := range rules {
+			listed[r.Name] = true
+		}
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+
+	allKeys, err := db.GetAllKeys()
+	if err != nil {
+		return err
+	}
+	have := make(map[string]bool)
+	for _, k := range allKeys {
+		have[k.Name] = true
+	}
+
+	var missing, unlisted []string
+	for _, name := range names {
+		if !have[name] {
+			missing = append(missing, name)
+		}
+		if !listed[name] {
+			unlisted = append(unlisted, name)
+		}
+	}
+
+	if write && len(unlisted) > 0 {
+		if err := appendRequired(file, unlisted); err != nil {
+			return err
+		}
+	}
+
+	out := cmd.OutOrStdout()
+	if format != outputTable {
+		vars := make([]scanVarJSON, 0, len(names))
+		for _, name := range names {
+			vars = append(vars, scanVarJSON{
+				Name:      name,
+				Present:   have[name],
+				Listed:    listed[name] || write,
+				Locations: locations[name],
+			})
+		}
+		if err := writeData(cmd, format, vars); err != nil {
+			return err
+		}
+	} else {
+		if len(names) == 0 {
+			fmt.Fprintln(out, "No environment variable reads found.")
+			return nil
+		}
+		maxLen := 0
+		for _, name := range names {
+			if len(name) > maxLen {
+				maxLen = len(name)
+			}
+		}
+		for _, name := range names {
+			mark := "✓"
+			if !have[name] {
+				mark = "✗"
+			}
+			where := locations[name][0]
+			if n := len(locations[name]); n > 1 {
+				where += fmt.Sprintf(" (+%d more)", n-1)
+			}
+			var notes []string
+			if !have[name] {
+				notes = append(notes, "missing")
+			}
+			if !listed[name] && !write {
+				notes = append(notes, "not in "+file)
+			}
+			note := ""
+			if len(notes) > 0 {
+				note = " — " + strings.Join(notes, ", ")
+			}
+			fmt.Fprintf(out, "  %s %-*s  %s%s\n", mark, maxLen, name, where, note)
+		}
+		fmt.Fprintf(out, "\nFound %d variables: %d missing from profile %q.\n", len(names), len(missing), db.GetActiveProfile())
+		if write && len(unlisted) > 0 {
+			fmt.Fprintf(out, "Added %d keys to %s.\n", len(unlisted), file)
+		}
+	}
+
+	if len(missing) > 0 {
+		return exitWith(cmd, 1)
+	}
+	return nil
+}
+
+// appendRequired adds names to the end of a requirements file, creating it
+// if needed.
+func appendRequired(file string, names []string) error {
+	existing, err := os.ReadFile(file)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	var b strings.Builder
+	if len(existing) > 0 && existing[len(existing)-1] != '\n' {
+		b.WriteString("\n")
+	}
+	for _, name := range names {
+		b.WriteString(name + "\n")
+	}
+	f, err := os.OpenFile(file, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.WriteString(b.String()); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
 func init() {
+	checkCmd.Flags().Bool("fix", false, "prompt for missing or invalid values")
+	checkCmd.Flags().Bool("json", false, "same as --output json")
+	addOutputFlag(checkCmd)
+	checkCmd.Flags().StringSlice("scan", nil, "scan source paths for environment variable reads (e.g. ./...)")
+	checkCmd.Flags().Bool("write", false, "with --scan, add unlisted variables to the requirements file")
 	rootCmd.AddCommand(checkCmd)
 }
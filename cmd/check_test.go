@@ -0,0 +1,119 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stym06/keys/db"
+)
+
+func writeRequired(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), ".keys.required")
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestCheckAllPresent(t *testing.T) {
+	setupTestEnv(t)
+	db.AddKey("API_KEY", "sk-123")
+	path := writeRequired(t, "API_KEY pattern=^sk-\nOPTIONAL optional\n")
+
+	buf := new(bytes.Buffer)
+	rootCmd.SetOut(buf)
+	rootCmd.SetArgs([]string{"check", path, "--json=false"})
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	out := buf.String()
+	if !strings.Contains(out, "✓ API_KEY") || !strings.Contains(out, "○ OPTIONAL") {
+		t.Errorf("unexpected output:\n%s", out)
+	}
+}
+
+func TestCheckFailureExitCode(t *testing.T) {
+	setupTestEnv(t)
+	db.AddKey("API_KEY", "pk-123")
+	path := writeRequired(t, "API_KEY pattern=^sk-\nMISSING\n")
+
+	buf := new(bytes.Buffer)
+	rootCmd.SetOut(buf)
+	rootCmd.SetArgs([]string{"check", path, "--json=false"})
+	err := rootCmd.Execute()
+
+	var code exitCode
+	if !errors.As(err, &code) || code != 1 {
+		t.Fatalf("expected exit code 1, got %v", err)
+	}
+	out := buf.String()
+	if !strings.Contains(out, "✗ API_KEY — does not match ^sk-") || !strings.Contains(out, "✗ MISSING — missing") {
+		t.Errorf("unexpected output:\n%s", out)
+	}
+}
+
+func TestCheckJSON(t *testing.T) {
+	setupTestEnv(t)
+	db.AddKey("API_KEY", "sk-123")
+	path := writeRequired(t, "API_KEY # the api key\nMISSING\n")
+
+	buf := new(bytes.Buffer)
+	rootCmd.SetOut(buf)
+	rootCmd.SetArgs([]string{"check", path, "--json"})
+	rootCmd.Execute()
+
+	var report checkJSON
+	if err := json.Unmarshal(buf.Bytes(), &report); err != nil {
+		t.Fatalf("invalid JSON: %v\n%s", err, buf.String())
+	}
+	if report.OK || len(report.Keys) != 2 {
+		t.Fatalf("unexpected report: %+v", report)
+	}
+	if !report.Keys[0].OK || report.Keys[0].Description != "the api key" {
+		t.Errorf("unexpected API_KEY entry: %+v", report.Keys[0])
+	}
+	if report.Keys[1].Present || report.Keys[1].Violations[0].Kind != "missing" {
+		t.Errorf("unexpected MISSING entry: %+v", report.Keys[1])
+	}
+}
+
+func TestCheckFixPromptsWithoutEcho(t *testing.T) {
+	setupTestEnv(t)
+	path := writeRequired(t, "API_KEY pattern=^sk-\n")
+	defer func(isTerm func() bool, read func() (string, error)) {
+		stdinIsTerminal, readHidden = isTerm, read
+	}(stdinIsTerminal, readHidden)
+
+	stdinIsTerminal = func() bool { return true }
+	answers := []string{"pk-wrong", "sk-right"}
+	readHidden = func() (string, error) {
+		a := answers[0]
+		answers = answers[1:]
+		return a, nil
+	}
+
+	out, errBuf := new(bytes.Buffer), new(bytes.Buffer)
+	rootCmd.SetOut(out)
+	rootCmd.SetErr(errBuf)
+	defer rootCmd.SetErr(nil)
+	rootCmd.SetArgs([]string{"check", path, "--fix", "--json=false"})
+	defer checkCmd.Flags().Set("fix", "false")
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if strings.Contains(errBuf.String(), "sk-right") || strings.Contains(errBuf.String(), "pk-wrong") {
+		t.Errorf("values should not be echoed, got %q", errBuf.String())
+	}
+	if k, _ := db.GetKey("API_KEY"); k == nil || k.Value != "sk-right" {
+		t.Errorf("expected fixed value, got %+v", k)
+	}
+	if !strings.Contains(out.String(), "✓ API_KEY") {
+		t.Errorf("unexpected output:\n%s", out.String())
+	}
+}
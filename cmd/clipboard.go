@@ -0,0 +1,89 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/stym06/keys/clipboard"
+
+	"github.com/spf13/cobra"
+)
+
+// envClipboardClear sets how long copied values stay on the clipboard, as
+// seconds or a duration such as "1m". 0 keeps them.
+const envClipboardClear = "KEYS_CLIPBOARD_CLEAR"
+
+const defaultClipboardClear = 30 * time.Second
+
+// clipboardClearCmd runs in the background after a copy and empties the
+// clipboard once the timeout passes, unless something else was copied
+// since. It reads the hash of the copied text from stdin so neither the
+// value nor its hash shows up in the process list.
+var clipboardClearCmd = &cobra.Command{
+	Use:    "clipboard-clear",
+	Hidden: true,
+	Args:   cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		after, _ := cmd.Flags().GetDuration("after")
+		hash, err := bufio.NewReader(cmd.InOrStdin()).ReadString('\n')
+		if err != nil {
+			return err
+		}
+		time.Sleep(after)
+		_, err = clipboard.ClearIfUnchanged(strings.TrimSpace(hash))
+		return err
+	},
+}
+
+// clipboardClearAfter returns how long copied values stay on the clipboard:
+// --clear-after if given, else $KEYS_CLIPBOARD_CLEAR, else 30 seconds.
+func clipboardClearAfter(cmd *cobra.Command) (time.Duration, error) {
+	if cmd != nil && cmd.Flags().Changed("clear-after") {
+		return cmd.Flags().GetDuration("clear-after")
+	}
+	v := os.Getenv(envClipboardClear)
+	if v == "" {
+		return defaultClipboardClear, nil
+	}
+	if secs, err := strconv.Atoi(v); err == nil {
+		return time.Duration(secs) * time.Second, nil
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		return 0, fmt.Errorf("%s: invalid duration %q", envClipboardClear, v)
+	}
+	return d, nil
+}
+
+// copySecret puts text on the clipboard and arranges for it to be cleared
+// after the given time. It returns whether a clear was scheduled.
+func copySecret(text string, after time.Duration) (bool, error) {
+	method, err := clipboard.Copy(text)
+	if err != nil {
+		return false, err
+	}
+	if after <= 0 || !method.Readable() {
+		return false, nil
+	}
+	return true, scheduleClipboardClear(text, after)
+}
+
+// tuiCopy copies text for 'keys see' and 'keys peek', clearing it after
+// the configured time.
+func tuiCopy(text string) error {
+	after, err := clipboardClearAfter(nil)
+	if err != nil {
+		return err
+	}
+	_, err = copySecret(text, after)
+	return err
+}
+
+func init() {
+	clipboardClearCmd.Flags().Duration("after", defaultClipboardClear, "time to wait before clearing")
+	rootCmd.AddCommand(clipboardClearCmd)
+}
@@ -0,0 +1,34 @@
+//go:build !windows
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"syscall"
+	"time"
+
+	"github.com/stym06/keys/clipboard"
+)
+
+// scheduleClipboardClear starts 'keys clipboard-clear' in its own session
+// so it outlives this process and the terminal.
+func scheduleClipboardClear(text string, after time.Duration) error {
+	self, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	c := exec.Command(self, "clipboard-clear", "--after", after.String())
+	c.SysProcAttr = &syscall.SysProcAttr{Setsid: true}
+	stdin, err := c.StdinPipe()
+	if err != nil {
+		return err
+	}
+	if err := c.Start(); err != nil {
+		return err
+	}
+	fmt.Fprintln(stdin, clipboard.Hash(text))
+	stdin.Close()
+	return c.Process.Release()
+}
@@ -0,0 +1,38 @@
+//go:build windows
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"syscall"
+	"time"
+
+	"github.com/stym06/keys/clipboard"
+)
+
+// detachedProcess is the DETACHED_PROCESS process creation flag, which
+// syscall does not define.
+const detachedProcess = 0x00000008
+
+// scheduleClipboardClear starts 'keys clipboard-clear' detached from the
+// console so it outlives this process and the terminal.
+func scheduleClipboardClear(text string, after time.Duration) error {
+	self, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	c := exec.Command(self, "clipboard-clear", "--after", after.String())
+	c.SysProcAttr = &syscall.SysProcAttr{CreationFlags: syscall.CREATE_NEW_PROCESS_GROUP | detachedProcess}
+	stdin, err := c.StdinPipe()
+	if err != nil {
+		return err
+	}
+	if err := c.Start(); err != nil {
+		return err
+	}
+	fmt.Fprintln(stdin, clipboard.Hash(text))
+	stdin.Close()
+	return c.Process.Release()
+}
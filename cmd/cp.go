@@ -0,0 +1,227 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/stym06/keys/db"
+
+	"github.com/spf13/cobra"
+)
+
+// Overwrite policies for keys that already exist in the target profile.
+const (
+	overwriteNever  = "never"
+	overwriteAlways = "always"
+	overwriteNewer  = "newer"
+)
+
+var cpCmd = &cobra.Command{
+	Use:   "cp <name|pattern>... --to <profile>",
+	Short: "Copy keys to another profile",
+	Long: `Copy keys from one profile to another. Names may be globs such as 'STRIPE_*'.
+
+Metadata, timestamps and any previous value kept by 'keys rotate' are copied
+with each key. Keys that already exist in the target profile are skipped
+unless --overwrite is 'always', or 'newer' and the source was updated more
+recently.
+
+Examples:
+  keys cp STRIPE_KEY --to staging
+  keys cp 'AWS_*' --from dev --to prod --overwrite newer`,
+	Args:              cobra.MinimumNArgs(1),
+	ValidArgsFunction: completeKeyNames,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runTransfer(cmd, args, false)
+	},
+}
+
+var mvCmd = &cobra.Command{
+	Use:   "mv <name|pattern>... --to <profile>",
+	Short: "Move keys to another profile",
+	Long: `Move keys from one profile to another. Names may be globs such as 'STRIPE_*'.
+
+Like 'keys cp', but each key is removed from the source profile once copied.
+Its audit history stays with the source profile, and the move is logged in
+both. Keys skipped by --overwrite stay where they are. When a glob is given,
+the matched keys are listed and you are asked to confirm; --yes skips the
+prompt.
+
+Examples:
+  keys mv OLD_TOKEN --to archive
+  keys mv 'LEGACY_*' --from prod --to archive --overwrite always --yes`,
+	Args:              cobra.MinimumNArgs(1),
+	ValidArgsFunction: completeKeyNames,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runTransfer(cmd, args, true)
+	},
+}
+
+func runTransfer(cmd *cobra.Command, args []string, move bool) error {
+	to, _ := cmd.Flags().GetString("to")
+	from, _ := cmd.Flags().GetString("from")
+	overwrite, _ := cmd.Flags().GetString("overwrite")
+	if from == "" {
+		from = db.GetActiveProfile()
+	}
+	if from == to {
+		return fmt.Errorf("--to must differ from the source profile %q", from)
+	}
+	switch overwrite {
+	case overwriteNever, overwriteAlways, overwriteNewer:
+	default:
+		return fmt.Errorf("invalid --overwrite %q (want never, always or newer)", overwrite)
+	}
+
+	keys, err := db.GetAllKeysForProfile(from)
+	if err != nil {
+		return err
+	}
+	// Resolve every pattern before touching anything
+	var names []string
+	seen := make(map[string]bool)
+	for _, pattern := range args {
+		sel, err := newKeySelector(pattern, false)
+		if err != nil {
+			return err
+		}
+		matched := sel.Filter(keys)
+		if len(matched) == 0 {
+			return fmt.Errorf("no keys in profile %q match %q", from, pattern)
+		}
+		for _, k := range matched {
+			if !seen[k.Name] {
+				seen[k.Name] = true
+				names = append(names, k.Name)
+			}
+		}
+	}
+	sort.Strings(names)
+
+	yes, _ := cmd.Flags().GetBool("yes")
+	if move && !yes && usesPatterns(cmd, args) {
+		out := cmd.OutOrStdout()
+		fmt.Fprintf(out, "This will move %d key(s) from profile %q to %q:\n", len(names), from, to)
+		for _, name := range names {
+			fmt.Fprintf(out, "  %s\n", name)
+		}
+		fmt.Fprint(out, "Move? [y/N]: ")
+		input, _ := bufio.NewReader(cmd.InOrStdin()).ReadString('\n')
+		input = strings.TrimSpace(strings.ToLower(input))
+		if input != "y" && input != "yes" {
+			fmt.Fprintln(out, "Cancelled.")
+			return nil
+		}
+	}
+
+	_, err = transferKeys(cmd.OutOrStdout(), from, to, names, overwrite, move)
+	return err
+}
+
+// transferKeys copies or moves the named keys from one profile to another,
+// applying the overwrite policy, and prints a line per key and a summary.
+// It returns the number of keys transferred.
+func transferKeys(w io.Writer, from, to string, names []string, overwrite string, move bool) (int, error) {
+	existing, err := db.GetAllKeysForProfile(to)
+	if err != nil {
+		return 0, err
+	}
+	target := make(map[string]db.Key, len(existing))
+	for _, k := range existing {
+		target[k.Name] = k
+	}
+	source, err := db.GetKeysByNamesForProfile(names, from)
+	if err != nil {
+		return 0, err
+	}
+	byName := make(map[string]db.Key, len(source))
+	for _, k := range source {
+		byName[k.Name] = k
+	}
+
+	verb, action := "Copied", "copy"
+	if move {
+		verb, action = "Moved", "move"
+	}
+	done, skipped := 0, 0
+	for _, name := range names {
+		src, ok := byName[name]
+		if !ok {
+			return done, fmt.Errorf("key %q not found in profile %q", name, from)
+		}
+		if dst, exists := target[name]; exists {
+			skip := ""
+			switch {
+			case overwrite == overwriteNever:
+				skip = fmt.Sprintf("already in %s", to)
+			case overwrite == overwriteNewer && src.UpdatedAt <= dst.UpdatedAt:
+				skip = fmt.Sprintf("not newer than %s", to)
+			}
+			if skip != "" {
+				fmt.Fprintf(w, "Skipped %s (%s)\n", name, skip)
+				skipped++
+				continue
+			}
+		}
+
+		_ = db.LogAccessForProfile(from, name, action, "cli")
+		if move {
+			err = db.MoveKey(from, to, name)
+		} else {
+			err = db.CopyKey(from, to, name)
+		}
+		if err != nil {
+			return done, err
+		}
+		if move {
+			_ = db.LogAccessForProfile(to, name, action, "cli")
+		}
+		fmt.Fprintf(w, "%s %s\n", verb, name)
+		done++
+	}
+
+	fmt.Fprintf(w, "%s %d key(s) from %s to %s", verb, done, from, to)
+	if skipped > 0 {
+		fmt.Fprintf(w, ", skipped %d", skipped)
+	}
+	fmt.Fprintln(w)
+	return done, nil
+}
+
+// sendToProfile handles the ctrl+t action of 'keys see' and 'keys peek':
+// it asks for a target profile and copies the keys there, never overwriting.
+func sendToProfile(keys []db.Key) error {
+	fmt.Printf("Send %d key(s) to profile: ", len(keys))
+	reader := bufio.NewReader(os.Stdin)
+	input, _ := reader.ReadString('\n')
+	to := strings.TrimSpace(input)
+	if to == "" {
+		fmt.Println("Cancelled.")
+		return nil
+	}
+	from := db.GetActiveProfile()
+	if to == from {
+		return fmt.Errorf("keys are already in profile %q", from)
+	}
+	names := make([]string, len(keys))
+	for i, k := range keys {
+		names[i] = k.Name
+	}
+	_, err := transferKeys(os.Stdout, from, to, names, overwriteNever, false)
+	return err
+}
+
+func init() {
+	for _, c := range []*cobra.Command{cpCmd, mvCmd} {
+		c.Flags().String("to", "", "target profile")
+		c.Flags().String("from", "", "source profile (default: active profile)")
+		c.Flags().String("overwrite", overwriteNever, "when the key exists in the target: never, always or newer")
+		_ = c.MarkFlagRequired("to")
+		rootCmd.AddCommand(c)
+	}
+	mvCmd.Flags().BoolP("yes", "y", false, "move glob matches without asking for confirmation")
+}
@@ -0,0 +1,112 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stym06/keys/db"
+)
+
+func TestCpGlobSkipsExisting(t *testing.T) {
+	setupTestEnv(t)
+	db.AddKey("AWS_ACCESS_KEY_ID", "AKIA1")
+	db.AddKey("AWS_SECRET_ACCESS_KEY", "secret1")
+	db.AddKey("OTHER", "x")
+	db.SetActiveProfile("staging")
+	db.AddKey("AWS_ACCESS_KEY_ID", "AKIA-staging")
+	db.SetActiveProfile("default")
+
+	out, err := runCmd(t, "cp", "AWS_*", "--to", "staging")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(out, "Skipped AWS_ACCESS_KEY_ID") || !strings.Contains(out, "Copied 1 key(s) from default to staging, skipped 1") {
+		t.Errorf("unexpected output:\n%s", out)
+	}
+	if k, _ := db.GetKeyForProfile("staging", "AWS_ACCESS_KEY_ID"); k.Value != "AKIA-staging" {
+		t.Errorf("existing key should be kept, got %q", k.Value)
+	}
+	if _, err := db.GetKeyForProfile("staging", "OTHER"); err == nil {
+		t.Error("OTHER should not be copied")
+	}
+
+	if _, err := runCmd(t, "cp", "AWS_*", "--to", "staging", "--overwrite", "always"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if k, _ := db.GetKeyForProfile("staging", "AWS_ACCESS_KEY_ID"); k.Value != "AKIA1" {
+		t.Errorf("--overwrite always should replace, got %q", k.Value)
+	}
+}
+
+func TestMvFromProfile(t *testing.T) {
+	setupTestEnv(t)
+	db.SetActiveProfile("dev")
+	db.AddKey("TOKEN", "dev-token")
+	db.SetActiveProfile("default")
+
+	out, err := runCmd(t, "mv", "TOKEN", "--from", "dev", "--to", "prod")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(out, "Moved TOKEN") {
+		t.Errorf("unexpected output:\n%s", out)
+	}
+	if _, err := db.GetKeyForProfile("dev", "TOKEN"); err == nil {
+		t.Error("key should be removed from dev")
+	}
+	if k, err := db.GetKeyForProfile("prod", "TOKEN"); err != nil || k.Value != "dev-token" {
+		t.Errorf("key should be in prod, got %+v, %v", k, err)
+	}
+}
+
+func TestMvGlobConfirms(t *testing.T) {
+	setupTestEnv(t)
+	db.AddKey("LEGACY_A", "a")
+	db.AddKey("LEGACY_B", "b")
+	db.LogAccess("LEGACY_A", "get", "cli")
+
+	out, err := runCmdWithInput(t, "n\n", "mv", "LEGACY_*", "--to", "archive")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(out, "  LEGACY_A\n  LEGACY_B\n") || !strings.Contains(out, "Cancelled.") {
+		t.Errorf("expected a preview and a cancel, got:\n%s", out)
+	}
+	if _, err := db.GetKeyForProfile("archive", "LEGACY_A"); err == nil {
+		t.Error("nothing should move when the prompt is declined")
+	}
+
+	if out, err := runCmdWithInput(t, "y\n", "mv", "LEGACY_*", "--to", "archive"); err != nil || !strings.Contains(out, "Moved 2 key(s)") {
+		t.Fatalf("expected the keys to move, got %v:\n%s", err, out)
+	}
+	entries, _ := db.QueryAuditLog(db.AuditQuery{Profile: "default", Key: "LEGACY_A"})
+	if len(entries) != 2 || entries[0].Action != "move" || entries[1].Action != "get" {
+		t.Errorf("the source history should stay and record the move, got %+v", entries)
+	}
+	if entries, _ := db.QueryAuditLog(db.AuditQuery{Profile: "archive", Key: "LEGACY_A"}); len(entries) != 1 || entries[0].Action != "move" {
+		t.Errorf("the target should log the move, got %+v", entries)
+	}
+
+	db.AddKey("LEGACY_C", "c")
+	if out, err := runCmd(t, "mv", "LEGACY_*", "--to", "archive", "--yes"); err != nil || strings.Contains(out, "Move?") {
+		t.Errorf("--yes should not prompt, got %v:\n%s", err, out)
+	}
+}
+
+func TestCpErrors(t *testing.T) {
+	setupTestEnv(t)
+	db.AddKey("TOKEN", "x")
+
+	for _, args := range [][]string{
+		{"cp", "NOPE_*", "--to", "staging"},
+		{"cp", "TOKEN", "--to", "default"},
+		{"cp", "TOKEN", "--to", "staging", "--overwrite", "sometimes"},
+	} {
+		if _, err := runCmd(t, args...); err == nil {
+			t.Errorf("%v: expected an error", args)
+		}
+	}
+	if _, err := db.GetKeyForProfile("staging", "TOKEN"); err == nil {
+		t.Error("nothing should be copied on error")
+	}
+}
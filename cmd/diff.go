@@ -0,0 +1,177 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/stym06/keys/db"
+	"github.com/stym06/keys/importer"
+
+	"github.com/spf13/cobra"
+)
+
+var diffCmd = &cobra.Command{
+	Use:   "diff <file>",
+	Short: "Compare a .env file with the keys in a profile",
+	Long: `Compare a .env file with the keys in a profile before overwriting or
+importing it.
+
+Lists keys only in the file, keys only in the vault, and keys whose values
+differ. Values are masked unless --reveal is given. Exits with code 1 if
+anything differs, so it can gate deploys.
+
+Examples:
+  keys diff .env
+  keys diff deploy/.env --profile prod
+  keys diff .env --reveal
+  keys diff .env --output json`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		profileFlag, _ := cmd.Flags().GetString("profile")
+		reveal, _ := cmd.Flags().GetBool("reveal")
+		format, err := outputFormat(cmd)
+		if err != nil {
+			return err
+		}
+
+		profile := profileFlag
+		if profile == "" {
+			profile = db.GetActiveProfile()
+		}
+
+		data, err := os.ReadFile(args[0])
+		if err != nil {
+			return err
+		}
+		entries, err := importer.Parse(importer.FormatDotenv, data, importer.Options{})
+		if err != nil {
+			return err
+		}
+		keys, err := db.GetAllKeysForProfile(profile)
+		if err != nil {
+			return err
+		}
+
+		file := make(map[string]string, len(entries))
+		for _, e := range entries {
+			file[e.Name] = e.Value
+		}
+		vault := make(map[string]string, len(keys))
+		for _, k := range keys {
+			vault[k.Name] = k.Value
+		}
+
+		var onlyFile, onlyVault, changed []string
+		for name, value := range file {
+			v, ok := vault[name]
+			switch {
+			case !ok:
+				onlyFile = append(onlyFile, name)
+			case v != value:
+				changed = append(changed, name)
+			}
+		}
+		for name := range vault {
+			if _, ok := file[name]; !ok {
+				onlyVault = append(onlyVault, name)
+			}
+		}
+		sort.Strings(onlyFile)
+		sort.Strings(onlyVault)
+		sort.Strings(changed)
+
+		show := func(v string) string {
+			if reveal {
+				return v
+			}
+			return maskValue(v)
+		}
+
+		out := cmd.OutOrStdout()
+		drift := len(onlyFile) + len(onlyVault) + len(changed)
+		if format != outputTable {
+			report := diffJSON{
+				File:        args[0],
+				Profile:     profile,
+				OK:          drift == 0,
+				OnlyInFile:  nonNil(onlyFile),
+				OnlyInVault: nonNil(onlyVault),
+				Changed:     []diffChangeJSON{},
+			}
+			for _, name := range changed {
+				report.Changed = append(report.Changed, diffChangeJSON{Name: name, Vault: show(vault[name]), File: show(file[name])})
+				if reveal {
+					_ = db.LogAccessForProfile(profile, name, "diff", "cli")
+				}
+			}
+			if err := writeData(cmd, format, report); err != nil {
+				return err
+			}
+			if drift > 0 {
+				return exitWith(cmd, 1)
+			}
+			return nil
+		}
+		if drift == 0 {
+			fmt.Fprintf(out, "No differences: %s matches profile %q (%d keys).\n", args[0], profile, len(file))
+			return nil
+		}
+
+		fmt.Fprintf(out, "Comparing %s with profile %q\n", args[0], profile)
+		if len(onlyFile) > 0 {
+			fmt.Fprintf(out, "\nOnly in %s:\n", args[0])
+			for _, name := range onlyFile {
+				fmt.Fprintf(out, "  + %s\n", name)
+			}
+		}
+		if len(onlyVault) > 0 {
+			fmt.Fprintln(out, "\nOnly in vault:")
+			for _, name := range onlyVault {
+				fmt.Fprintf(out, "  - %s\n", name)
+			}
+		}
+		if len(changed) > 0 {
+			fmt.Fprintln(out, "\nDifferent values:")
+			for _, name := range changed {
+				fmt.Fprintf(out, "  ~ %s\n      vault: %s\n      file:  %s\n", name, show(vault[name]), show(file[name]))
+				if reveal {
+					_ = db.LogAccessForProfile(profile, name, "diff", "cli")
+				}
+			}
+		}
+
+		fmt.Fprintf(out, "\n%d difference(s).\n", drift)
+		return exitWith(cmd, 1)
+	},
+}
+
+// diffJSON is the machine-readable form of 'keys diff'. Values are masked
+// unless --reveal is given.
+type diffJSON struct {
+	File        string           `json:"file" yaml:"file"`
+	Profile     string           `json:"profile" yaml:"profile"`
+	OK          bool             `json:"ok" yaml:"ok"`
+	OnlyInFile  []string         `json:"only_in_file" yaml:"only_in_file"`
+	OnlyInVault []string         `json:"only_in_vault" yaml:"only_in_vault"`
+	Changed     []diffChangeJSON `json:"changed" yaml:"changed"`
+}
+
+type diffChangeJSON struct {
+	Name  string `json:"name" yaml:"name"`
+	Vault string `json:"vault" yaml:"vault"`
+	File  string `json:"file" yaml:"file"`
+}
+
+// maskValue hides a value but keeps its length, so that values of
+// different shapes can still be told apart.
+func maskValue(v string) string {
+	return fmt.Sprintf("*** (%d chars)", len(v))
+}
+
+func init() {
+	diffCmd.Flags().StringP("profile", "p", "", "compare against a specific profile")
+	diffCmd.Flags().Bool("reveal", false, "show differing values instead of masking them")
+	addOutputFlag(diffCmd)
+	rootCmd.AddCommand(diffCmd)
+}
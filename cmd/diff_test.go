@@ -0,0 +1,59 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stym06/keys/db"
+)
+
+func TestDiffDrift(t *testing.T) {
+	setupTestEnv(t)
+	db.AddKey("SAME", "one")
+	db.AddKey("CHANGED", "vault-secret")
+	db.AddKey("VAULT_ONLY", "x")
+
+	path := filepath.Join(t.TempDir(), ".env")
+	os.WriteFile(path, []byte("SAME=one\nCHANGED=file-secret\nFILE_ONLY=y\n"), 0600)
+
+	buf := new(bytes.Buffer)
+	rootCmd.SetOut(buf)
+	rootCmd.SetArgs([]string{"diff", path, "--reveal=false"})
+	err := rootCmd.Execute()
+
+	var code exitCode
+	if !errors.As(err, &code) || code != 1 {
+		t.Fatalf("expected exit code 1, got %v", err)
+	}
+	out := buf.String()
+	for _, want := range []string{"+ FILE_ONLY", "- VAULT_ONLY", "~ CHANGED", "3 difference(s)"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output missing %q:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "SAME") || strings.Contains(out, "vault-secret") {
+		t.Errorf("output should not list matching keys or reveal values:\n%s", out)
+	}
+}
+
+func TestDiffNoDrift(t *testing.T) {
+	setupTestEnv(t)
+	db.AddKey("API_KEY", "sk-123")
+
+	path := filepath.Join(t.TempDir(), ".env")
+	os.WriteFile(path, []byte("export API_KEY=\"sk-123\"\n"), 0600)
+
+	buf := new(bytes.Buffer)
+	rootCmd.SetOut(buf)
+	rootCmd.SetArgs([]string{"diff", path, "--reveal=false"})
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(buf.String(), "No differences") {
+		t.Errorf("unexpected output:\n%s", buf.String())
+	}
+}
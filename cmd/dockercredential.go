@@ -0,0 +1,201 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/stym06/keys/db"
+	"github.com/stym06/keys/importer"
+
+	"github.com/spf13/cobra"
+)
+
+// dockerProfile is where registry credentials are kept by default, apart
+// from application keys.
+const dockerProfile = "docker"
+
+// metaDockerServer holds the registry URL a key is the credential for.
+const metaDockerServer = "docker_server"
+
+// dockerCredentialBinary is the name docker runs for '"credsStore": "keys"'.
+const dockerCredentialBinary = "docker-credential-keys"
+
+// errDockerNotFound is the message docker recognises as "no credentials".
+const errDockerNotFound = "credentials not found in native keychain"
+
+var dockerCredentialCmd = &cobra.Command{
+	Use:   "docker-credential <get|store|erase|list>",
+	Short: "Act as a docker credential helper",
+	Long: `Serve registry credentials from the vault using the docker-credential-helpers
+protocol. Install keys as docker-credential-keys and point docker at it:
+
+  ln -s "$(which keys)" /usr/local/bin/docker-credential-keys
+  # ~/.docker/config.json
+  { "credsStore": "keys" }
+
+'docker login' then stores registry passwords in the "docker" profile
+instead of config.json, as DOCKER_<REGISTRY> keys. Accesses are audited
+with source "docker".`,
+	Args:      cobra.ExactArgs(1),
+	ValidArgs: []string{"get", "store", "erase", "list"},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		profile, _ := cmd.Flags().GetString("profile")
+		out := cmd.OutOrStdout()
+
+		var err error
+		switch args[0] {
+		case "get":
+			err = dockerCredentialGet(cmd.InOrStdin(), out, profile)
+		case "store":
+			err = dockerCredentialStore(cmd.InOrStdin(), profile)
+		case "erase":
+			err = dockerCredentialErase(cmd.InOrStdin(), profile)
+		case "list":
+			err = dockerCredentialList(out, profile)
+		default:
+			err = fmt.Errorf("unknown credential action %q", args[0])
+		}
+		if err != nil {
+			// Helpers report errors on stdout, where docker looks for them
+			fmt.Fprintln(out, err)
+			return exitWith(cmd, 1)
+		}
+		return nil
+	},
+}
+
+// dockerCredential is the JSON document of the helper protocol.
+type dockerCredential struct {
+	ServerURL string
+	Username  string
+	Secret    string
+}
+
+// normalizeRegistry reduces a registry URL to host and path, so
+// "https://ghcr.io/" and "ghcr.io" are the same registry.
+func normalizeRegistry(serverURL string) string {
+	s := strings.TrimSpace(serverURL)
+	if _, rest, ok := strings.Cut(s, "://"); ok {
+		s = rest
+	}
+	return strings.ToLower(strings.TrimSuffix(s, "/"))
+}
+
+// dockerKeyName returns the key name for a registry, e.g. DOCKER_GHCR_IO.
+func dockerKeyName(serverURL string) string {
+	return "DOCKER_" + strings.ToUpper(strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' {
+			return r
+		}
+		return '_'
+	}, normalizeRegistry(serverURL)))
+}
+
+// readServerURL reads the registry URL that get and erase receive on stdin.
+func readServerURL(r io.Reader) (string, error) {
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return "", err
+	}
+	server := strings.TrimSpace(string(b))
+	if server == "" {
+		return "", fmt.Errorf("no server URL given")
+	}
+	return server, nil
+}
+
+// findDockerKey returns the key holding the credential for serverURL.
+func findDockerKey(profile, serverURL string) (*db.Key, map[string]string, error) {
+	keys, err := db.GetAllKeysForProfile(profile)
+	if err != nil {
+		return nil, nil, err
+	}
+	meta, err := db.GetAllKeyMetaForProfile(profile)
+	if err != nil {
+		return nil, nil, err
+	}
+	want := normalizeRegistry(serverURL)
+	for _, k := range keys {
+		if server := meta[k.Name][metaDockerServer]; server != "" && normalizeRegistry(server) == want {
+			return &k, meta[k.Name], nil
+		}
+	}
+	return nil, nil, fmt.Errorf("%s", errDockerNotFound)
+}
+
+func dockerCredentialGet(r io.Reader, w io.Writer, profile string) error {
+	server, err := readServerURL(r)
+	if err != nil {
+		return err
+	}
+	k, meta, err := findDockerKey(profile, server)
+	if err != nil {
+		return err
+	}
+	_ = db.LogAccessForProfile(profile, k.Name, "get", "docker")
+	return json.NewEncoder(w).Encode(dockerCredential{
+		ServerURL: meta[metaDockerServer],
+		Username:  meta[importer.MetaUsername],
+		Secret:    k.Value,
+	})
+}
+
+func dockerCredentialStore(r io.Reader, profile string) error {
+	var c dockerCredential
+	if err := json.NewDecoder(r).Decode(&c); err != nil {
+		return fmt.Errorf("invalid credentials: %w", err)
+	}
+	if c.ServerURL == "" {
+		return fmt.Errorf("no server URL given")
+	}
+
+	name := dockerKeyName(c.ServerURL)
+	if k, _, err := findDockerKey(profile, c.ServerURL); err == nil {
+		name = k.Name
+	}
+	if err := db.AddKeyForProfile(profile, name, c.Secret); err != nil {
+		return err
+	}
+	if err := db.SetKeyMetaForProfile(profile, name, metaDockerServer, c.ServerURL); err != nil {
+		return err
+	}
+	if err := db.SetKeyMetaForProfile(profile, name, importer.MetaUsername, c.Username); err != nil {
+		return err
+	}
+	_ = db.LogAccessForProfile(profile, name, "store", "docker")
+	return nil
+}
+
+func dockerCredentialErase(r io.Reader, profile string) error {
+	server, err := readServerURL(r)
+	if err != nil {
+		return err
+	}
+	k, _, err := findDockerKey(profile, server)
+	if err != nil {
+		return err
+	}
+	_ = db.LogAccessForProfile(profile, k.Name, "erase", "docker")
+	return db.DeleteKeyForProfile(profile, k.Name)
+}
+
+func dockerCredentialList(w io.Writer, profile string) error {
+	meta, err := db.GetAllKeyMetaForProfile(profile)
+	if err != nil {
+		return err
+	}
+	list := make(map[string]string)
+	for _, fields := range meta {
+		if server := fields[metaDockerServer]; server != "" {
+			list[server] = fields[importer.MetaUsername]
+		}
+	}
+	return json.NewEncoder(w).Encode(list)
+}
+
+func init() {
+	dockerCredentialCmd.Flags().StringP("profile", "p", dockerProfile, "profile holding registry credentials")
+	rootCmd.AddCommand(dockerCredentialCmd)
+}
@@ -0,0 +1,61 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stym06/keys/db"
+)
+
+func runDockerCredential(t *testing.T, op, input string) (string, error) {
+	t.Helper()
+	buf := new(bytes.Buffer)
+	rootCmd.SetOut(buf)
+	rootCmd.SetIn(strings.NewReader(input))
+	defer rootCmd.SetIn(nil)
+	rootCmd.SetArgs([]string{"docker-credential", op})
+	err := rootCmd.Execute()
+	return buf.String(), err
+}
+
+func TestDockerCredentialRoundTrip(t *testing.T) {
+	setupTestEnv(t)
+
+	if _, err := runDockerCredential(t, "store", `{"ServerURL":"https://ghcr.io","Username":"alice","Secret":"ghp_registry"}`); err != nil {
+		t.Fatalf("store: %v", err)
+	}
+	k, err := db.GetKeyForProfile(dockerProfile, "DOCKER_GHCR_IO")
+	if err != nil || k.Value != "ghp_registry" {
+		t.Fatalf("expected DOCKER_GHCR_IO in the docker profile, got %+v, %v", k, err)
+	}
+	if exists, _ := db.KeyExists("DOCKER_GHCR_IO"); exists {
+		t.Error("registry credentials should not land in the active profile")
+	}
+
+	out, err := runDockerCredential(t, "get", "ghcr.io/\n")
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	var c dockerCredential
+	if err := json.Unmarshal([]byte(out), &c); err != nil {
+		t.Fatalf("invalid JSON %q: %v", out, err)
+	}
+	if c.ServerURL != "https://ghcr.io" || c.Username != "alice" || c.Secret != "ghp_registry" {
+		t.Errorf("unexpected credential %+v", c)
+	}
+
+	out, _ = runDockerCredential(t, "list", "")
+	if strings.TrimSpace(out) != `{"https://ghcr.io":"alice"}` {
+		t.Errorf("unexpected list %q", out)
+	}
+
+	if _, err := runDockerCredential(t, "erase", "https://ghcr.io\n"); err != nil {
+		t.Fatalf("erase: %v", err)
+	}
+	out, err = runDockerCredential(t, "get", "https://ghcr.io\n")
+	if err == nil || strings.TrimSpace(out) != errDockerNotFound {
+		t.Errorf("expected the not-found message on stdout, got %q, %v", out, err)
+	}
+}
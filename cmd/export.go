@@ -0,0 +1,120 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/stym06/keys/db"
+	"github.com/stym06/keys/importer"
+	"github.com/stym06/keys/k8s"
+
+	"github.com/spf13/cobra"
+)
+
+// Formats accepted by 'keys export --output'.
+const (
+	outputEnv       = "env"
+	outputK8sSecret = "k8s-secret"
+)
+
+var exportCmd = &cobra.Command{
+	Use:   "export [names or patterns...]",
+	Short: "Export keys as a .env file or Kubernetes Secret manifest",
+	Long: `Export keys from a profile in a file format other tools understand.
+
+Exports every key in the profile unless key names or globs such as 'AWS_*'
+are given. --match selects by regular expression and --exclude leaves out
+keys matching a glob.
+
+Formats (--output):
+  env          KEY=VALUE lines (default), quoted where needed so that
+               'keys import' reads them back unchanged
+  k8s-secret   Kubernetes Secret manifest (requires --name)
+
+Examples:
+  keys export > .env
+  keys export API_KEY DB_URL --output-file app.env
+  keys export --match '^STRIPE_' --exclude '*_TEST' > stripe.env
+  keys export --output k8s-secret --name app-secrets --namespace prod
+  keys export --output k8s-secret --name app-secrets --string-data | kubectl apply -f -`,
+	ValidArgsFunction: completeKeyNamesMulti,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		format, _ := cmd.Flags().GetString("output")
+		if format != outputEnv && format != outputK8sSecret {
+			return fmt.Errorf("invalid --output %q (use env or k8s-secret)", format)
+		}
+		profileFlag, _ := cmd.Flags().GetString("profile")
+		outFlag, _ := cmd.Flags().GetString("output-file")
+		nameFlag, _ := cmd.Flags().GetString("name")
+		namespaceFlag, _ := cmd.Flags().GetString("namespace")
+		stringData, _ := cmd.Flags().GetBool("string-data")
+
+		profile := profileFlag
+		if profile == "" {
+			profile = db.GetActiveProfile()
+		}
+
+		all, err := db.GetAllKeysForProfile(profile)
+		if err != nil {
+			return err
+		}
+		keys, err := selectKeys(cmd, all, args, true)
+		if err != nil {
+			return err
+		}
+		if len(keys) == 0 {
+			return fmt.Errorf("no keys to export in profile %q", profile)
+		}
+
+		var out []byte
+		switch format {
+		case outputEnv:
+			var b strings.Builder
+			for _, k := range keys {
+				fmt.Fprintf(&b, "%s=%s\n", k.Name, importer.QuoteDotenv(k.Value))
+			}
+			out = []byte(b.String())
+		case outputK8sSecret:
+			if nameFlag == "" {
+				return fmt.Errorf("--name is required for k8s-secret")
+			}
+			values := make(map[string]string, len(keys))
+			for _, k := range keys {
+				values[k.Name] = k.Value
+			}
+			secret, err := k8s.NewSecret(nameFlag, namespaceFlag, values, stringData)
+			if err != nil {
+				return err
+			}
+			if out, err = secret.Marshal(); err != nil {
+				return err
+			}
+		}
+
+		for _, k := range keys {
+			_ = db.LogAccessForProfile(profile, k.Name, "export", "cli")
+		}
+
+		if outFlag != "" {
+			if err := os.WriteFile(outFlag, out, 0600); err != nil {
+				return err
+			}
+			fmt.Fprintf(cmd.ErrOrStderr(), "Wrote %d key(s) to %s\n", len(keys), outFlag)
+			return nil
+		}
+		_, err = cmd.OutOrStdout().Write(out)
+		return err
+	},
+}
+
+func init() {
+	exportCmd.Flags().String("output", outputEnv, "output format: env or k8s-secret")
+	exportCmd.Flags().StringP("profile", "p", "", "use a specific profile")
+	exportCmd.Flags().String("output-file", "", "write to a file instead of stdout")
+	exportCmd.Flags().String("name", "", "Secret name (k8s-secret)")
+	exportCmd.Flags().String("namespace", "", "Secret namespace (k8s-secret)")
+	addSelectFlags(exportCmd)
+	exportCmd.Flags().Bool("string-data", false, "write plain values under stringData instead of base64 data (k8s-secret)")
+	rootCmd.AddCommand(exportCmd)
+}
@@ -0,0 +1,77 @@
+package cmd
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stym06/keys/db"
+)
+
+func TestExportK8sSecretRoundTrip(t *testing.T) {
+	setupTestEnv(t)
+	db.AddKey("API_KEY", "sk-123")
+	db.AddKey("DB_URL", "postgres://db")
+
+	out, err := runCmd(t, "export", "--output", "k8s-secret", "--name", "app-secrets", "--namespace", "prod")
+	if err != nil {
+		t.Fatalf("export: %v", err)
+	}
+	if !strings.Contains(out, "kind: Secret") || !strings.Contains(out, "API_KEY: c2stMTIz") {
+		t.Fatalf("unexpected manifest:\n%s", out)
+	}
+
+	path := t.TempDir() + "/secret.yaml"
+	if err := os.WriteFile(path, []byte(out), 0600); err != nil {
+		t.Fatal(err)
+	}
+	db.SetActiveProfile("imported")
+	if _, err := runCmd(t, "import", path); err != nil {
+		t.Fatalf("import: %v", err)
+	}
+	k, err := db.GetKey("DB_URL")
+	if err != nil {
+		t.Fatalf("GetKey: %v", err)
+	}
+	if k.Value != "postgres://db" {
+		t.Errorf("expected postgres://db, got %q", k.Value)
+	}
+}
+
+func TestExportK8sSecretRequiresName(t *testing.T) {
+	setupTestEnv(t)
+	db.AddKey("API_KEY", "sk-123")
+
+	if _, err := runCmd(t, "export", "--output", "k8s-secret", "--name", ""); err == nil {
+		t.Fatal("expected error without --name")
+	}
+}
+
+func TestExportEnvRoundTrip(t *testing.T) {
+	setupTestEnv(t)
+	values := map[string]string{
+		"PEM":    "-----BEGIN KEY-----\nMIIB\n-----END KEY-----",
+		"HASH":   "abc # def",
+		"SPACED": " padded value ",
+		"QUOTED": `"already quoted"`,
+		"MIXED":  `it's "both"`,
+		"PLAIN":  "sk-123",
+	}
+	for name, value := range values {
+		db.AddKey(name, value)
+	}
+
+	path := t.TempDir() + "/app.env"
+	if _, err := runCmd(t, "export", "--output-file", path); err != nil {
+		t.Fatalf("export: %v", err)
+	}
+	db.SetActiveProfile("imported")
+	if _, err := runCmd(t, "import", path); err != nil {
+		t.Fatalf("import: %v", err)
+	}
+	for name, want := range values {
+		if k, err := db.GetKey(name); err != nil || k.Value != want {
+			t.Errorf("%s: expected %q, got %v, %v", name, want, k, err)
+		}
+	}
+}
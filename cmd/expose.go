@@ -9,21 +9,37 @@ import (
 )
 
 var exposeCmd = &cobra.Command{
-	Use:   "expose",
+	Use:   "expose [names or patterns...]",
 	Short: "Print export statements for all stored keys",
+	Long: `Print export statements for stored keys, for use with eval.
+
+Every key is printed unless names or globs such as 'AWS_*' are given.
+--match selects by regular expression and --exclude leaves out keys
+matching a glob.
+
+Examples:
+  eval $(keys expose)
+  eval $(keys expose --match '^STRIPE_')
+  eval $(keys expose 'AWS_*' --exclude '*_PROD')`,
+	ValidArgsFunction: completeKeyNamesMulti,
 	RunE: func(cmd *cobra.Command, args []string) error {
-		keys, err := db.GetAllKeys()
+		all, err := db.GetAllKeys()
+		if err != nil {
+			return err
+		}
+		keys, err := selectKeys(cmd, all, args, true)
 		if err != nil {
 			return err
 		}
 		for _, k := range keys {
 			_ = db.LogAccess(k.Name, "expose", "cli")
-			fmt.Printf("export %s=%s\n", k.Name, k.Value)
+			fmt.Fprintf(cmd.OutOrStdout(), "export %s=%s\n", k.Name, k.Value)
 		}
 		return nil
 	},
 }
 
 func init() {
+	addSelectFlags(exposeCmd)
 	rootCmd.AddCommand(exposeCmd)
 }
@@ -2,6 +2,7 @@ package cmd
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/stym06/keys/db"
 	"github.com/stym06/keys/tui"
@@ -11,21 +12,77 @@ import (
 )
 
 var getCmd = &cobra.Command{
-	Use:               "get [name]",
-	Short:             "Print the value of a stored key",
+	Use:   "get [name or pattern]",
+	Short: "Print the value of a stored key",
+	Long: `Print the value of a stored key, or pick one interactively when no name is given.
+
+With a glob such as 'AWS_*', or --match and --exclude, every matched key is
+printed as a NAME=value line.
+
+Examples:
+  keys get OPENAI_KEY
+  keys get OPENAI_KEY --copy --clear-after 10s
+  keys get 'AWS_*'
+  keys get --match '^STRIPE_' --exclude '*_TEST'`,
 	Args:              cobra.MaximumNArgs(1),
 	ValidArgsFunction: completeKeyNames,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		out := cmd.OutOrStdout()
+		previous, _ := cmd.Flags().GetBool("previous")
+		copyFlag, _ := cmd.Flags().GetBool("copy")
+		format, err := outputFormat(cmd)
+		if err != nil {
+			return err
+		}
+		if copyFlag {
+			if len(args) == 0 || usesPatterns(cmd, args) || previous {
+				return fmt.Errorf("--copy needs a single key name")
+			}
+			return copyKey(cmd, args[0])
+		}
+		if previous {
+			if len(args) == 0 {
+				return fmt.Errorf("--previous needs a key name")
+			}
+			prev, err := db.GetPreviousValue(args[0])
+			if err != nil {
+				return err
+			}
+			_ = db.LogAccess(args[0], "get-previous", "cli")
+			if format != outputTable {
+				return writeData(cmd, format, previousItem{
+					Name:      args[0],
+					Profile:   db.GetActiveProfile(),
+					Value:     prev.Value,
+					RotatedAt: timestamp(prev.RotatedAt),
+					ExpiresAt: timestamp(prev.ExpiresAt),
+				})
+			}
+			fmt.Fprintln(out, prev.Value)
+			return nil
+		}
+
+		if usesPatterns(cmd, args) {
+			return getMatching(cmd, format, args)
+		}
+
 		if len(args) == 1 {
 			key, err := db.GetKey(args[0])
 			if err != nil {
 				return err
 			}
 			_ = db.LogAccess(key.Name, "get", "cli")
+			if format != outputTable {
+				item := newKeyItem(db.GetActiveProfile(), *key)
+				item.Value = key.Value
+				return writeData(cmd, format, item)
+			}
 			fmt.Fprintln(out, key.Value)
 			return nil
 		}
+		if format != outputTable {
+			return fmt.Errorf("a key name is required with --output %s", format)
+		}
 
 		// No arg: launch interactive picker
 		keys, err := db.GetAllKeys()
@@ -56,6 +113,73 @@ var getCmd = &cobra.Command{
 	},
 }
 
+// copyKey puts a key's value on the clipboard instead of printing it.
+func copyKey(cmd *cobra.Command, name string) error {
+	after, err := clipboardClearAfter(cmd)
+	if err != nil {
+		return err
+	}
+	key, err := db.GetKey(name)
+	if err != nil {
+		return err
+	}
+	scheduled, err := copySecret(key.Value, after)
+	if err != nil {
+		return err
+	}
+	_ = db.LogAccess(key.Name, "get", "clipboard")
+	if scheduled {
+		fmt.Fprintf(cmd.ErrOrStderr(), "Copied %s to the clipboard (clears in %s)\n", key.Name, after)
+	} else {
+		fmt.Fprintf(cmd.ErrOrStderr(), "Copied %s to the clipboard\n", key.Name)
+	}
+	return nil
+}
+
+// getMatching prints every key selected by a pattern as NAME=value lines.
+func getMatching(cmd *cobra.Command, format string, args []string) error {
+	all, err := db.GetAllKeys()
+	if err != nil {
+		return err
+	}
+	keys, err := selectKeys(cmd, all, args, false)
+	if err != nil {
+		return err
+	}
+
+	profile := db.GetActiveProfile()
+	items := []keyItem{}
+	for _, k := range keys {
+		_ = db.LogAccess(k.Name, "get", "cli")
+		if format != outputTable {
+			item := newKeyItem(profile, k)
+			item.Value = k.Value
+			items = append(items, item)
+			continue
+		}
+		fmt.Fprintf(cmd.OutOrStdout(), "%s=%s\n", k.Name, k.Value)
+	}
+	if format != outputTable {
+		return writeData(cmd, format, items)
+	}
+	return nil
+}
+
+// previousItem is the machine-readable form of a value replaced by
+// 'keys rotate'.
+type previousItem struct {
+	Name      string     `json:"name" yaml:"name"`
+	Profile   string     `json:"profile" yaml:"profile"`
+	Value     string     `json:"value" yaml:"value"`
+	RotatedAt *time.Time `json:"rotated_at" yaml:"rotated_at"`
+	ExpiresAt *time.Time `json:"expires_at" yaml:"expires_at"`
+}
+
 func init() {
+	addOutputFlag(getCmd)
+	addSelectFlags(getCmd)
+	getCmd.Flags().BoolP("copy", "c", false, "copy the value to the clipboard instead of printing it")
+	getCmd.Flags().Duration("clear-after", defaultClipboardClear, "clear the clipboard after this long if it still holds the value (0 keeps it; default from $"+envClipboardClear+")")
+	getCmd.Flags().Bool("previous", false, "print the value from before the last rotation, during its grace period")
 	rootCmd.AddCommand(getCmd)
 }
@@ -1,8 +1,8 @@
 package cmd
 
 import (
-	"bytes"
 	"testing"
+	"time"
 
 	"github.com/stym06/keys/db"
 )
@@ -17,14 +17,11 @@ func TestGetDirectLookup(t *testing.T) {
 	setupTestEnv(t)
 	db.AddKey("MY_KEY", "my_secret_value")
 
-	buf := new(bytes.Buffer)
-	rootCmd.SetOut(buf)
-	rootCmd.SetArgs([]string{"get", "MY_KEY"})
-	if err := rootCmd.Execute(); err != nil {
+	out, err := runCmd(t, "get", "MY_KEY")
+	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
 
-	out := buf.String()
 	if out != "my_secret_value\n" {
 		t.Errorf("expected 'my_secret_value\\n', got %q", out)
 	}
@@ -33,9 +30,7 @@ func TestGetDirectLookup(t *testing.T) {
 func TestGetNotFound(t *testing.T) {
 	setupTestEnv(t)
 
-	rootCmd.SetArgs([]string{"get", "NONEXISTENT"})
-	err := rootCmd.Execute()
-	if err == nil {
+	if _, err := runCmd(t, "get", "NONEXISTENT"); err == nil {
 		t.Fatal("expected error for missing key")
 	}
 }
@@ -45,14 +40,11 @@ func TestGetMultipleKeys(t *testing.T) {
 	db.AddKey("FIRST", "val1")
 	db.AddKey("SECOND", "val2")
 
-	buf := new(bytes.Buffer)
-	rootCmd.SetOut(buf)
-	rootCmd.SetArgs([]string{"get", "SECOND"})
-	if err := rootCmd.Execute(); err != nil {
+	out, err := runCmd(t, "get", "SECOND")
+	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
 
-	out := buf.String()
 	if out != "val2\n" {
 		t.Errorf("expected 'val2\\n', got %q", out)
 	}
@@ -68,28 +60,22 @@ func TestGetRespectsProfile(t *testing.T) {
 	db.AddKey("SHARED", "dev_val")
 
 	// Get from dev profile
-	buf := new(bytes.Buffer)
-	rootCmd.SetOut(buf)
-	rootCmd.SetArgs([]string{"get", "SHARED"})
-	if err := rootCmd.Execute(); err != nil {
+	out, err := runCmd(t, "get", "SHARED")
+	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
 
-	out := buf.String()
 	if out != "dev_val\n" {
 		t.Errorf("expected 'dev_val\\n', got %q", out)
 	}
 
 	// Switch to default and get again
 	db.SetActiveProfile("default")
-	buf.Reset()
-	rootCmd.SetOut(buf)
-	rootCmd.SetArgs([]string{"get", "SHARED"})
-	if err := rootCmd.Execute(); err != nil {
+	out, err = runCmd(t, "get", "SHARED")
+	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
 
-	out = buf.String()
 	if out != "default_val\n" {
 		t.Errorf("expected 'default_val\\n', got %q", out)
 	}
@@ -98,9 +84,35 @@ func TestGetRespectsProfile(t *testing.T) {
 func TestGetTooManyArgs(t *testing.T) {
 	setupTestEnv(t)
 
-	rootCmd.SetArgs([]string{"get", "A", "B"})
-	err := rootCmd.Execute()
-	if err == nil {
+	if _, err := runCmd(t, "get", "A", "B"); err == nil {
 		t.Fatal("expected error with too many args")
 	}
 }
+
+func TestClipboardClearAfter(t *testing.T) {
+	for env, want := range map[string]time.Duration{
+		"":   30 * time.Second,
+		"45": 45 * time.Second,
+		"2m": 2 * time.Minute,
+		"0":  0,
+	} {
+		t.Setenv(envClipboardClear, env)
+		got, err := clipboardClearAfter(nil)
+		if err != nil || got != want {
+			t.Errorf("%s=%q: got %v, %v; want %v", envClipboardClear, env, got, err, want)
+		}
+	}
+	t.Setenv(envClipboardClear, "soon")
+	if _, err := clipboardClearAfter(nil); err == nil {
+		t.Error("expected an error for an invalid duration")
+	}
+}
+
+func TestGetCopyNeedsOneName(t *testing.T) {
+	setupTestEnv(t)
+	db.AddKey("MY_KEY", "v")
+
+	if _, err := runCmd(t, "get", "MY_*", "--copy"); err == nil {
+		t.Error("--copy with a pattern should fail")
+	}
+}
@@ -0,0 +1,332 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+
+	"github.com/stym06/keys/db"
+	"github.com/stym06/keys/importer"
+
+	"github.com/spf13/cobra"
+)
+
+// Metadata set on keys stored by the helper: the host and protocol the
+// credential was stored for.
+const (
+	metaGitHost     = "git_host"
+	metaGitProtocol = "git_protocol"
+)
+
+// defaultGitProtocol is assumed for requests that name no protocol and for
+// keys that record none.
+const defaultGitProtocol = "https"
+
+// gitCredentialBinary is the name git runs for 'credential.helper keys'.
+const gitCredentialBinary = "git-credential-keys"
+
+// Well-known key names and usernames for hosted git services, used when no
+// key is mapped to the host explicitly.
+var (
+	gitHostKeys = map[string][]string{
+		"github.com": {"GITHUB_TOKEN", "GH_TOKEN"},
+		"gitlab.com": {"GITLAB_TOKEN"},
+	}
+	gitHostUsers = map[string]string{
+		"github.com": "x-access-token",
+		"gitlab.com": "oauth2",
+	}
+)
+
+var gitCredentialCmd = &cobra.Command{
+	Use:   "git-credential <get|store|erase>",
+	Short: "Act as a git credential helper",
+	Long: `Serve git credentials from the active profile using git's credential helper
+protocol. Set it up with either:
+
+  git config --global credential.helper '!keys git-credential'
+  ln -s "$(which keys)" /usr/local/bin/git-credential-keys && git config --global credential.helper keys
+
+For a host such as github.com, the first of these is used:
+
+  1. a key stored by this helper for the host (metadata git_host)
+  2. a key whose url metadata points at the host, e.g. from a password manager import
+  3. GIT_GITHUB_COM_<USERNAME>, then GIT_GITHUB_COM
+  4. GITHUB_TOKEN or GH_TOKEN for github.com, GITLAB_TOKEN for gitlab.com
+
+'store' saves credentials typed at a git prompt as GIT_<HOST>, or as
+GIT_<HOST>_<USERNAME> when another user's credential already has that name.
+'erase' only removes keys that 'store' created, and only when the rejected
+password is the stored one.
+
+A key is only returned for the protocol it was stored for (metadata
+git_protocol) or its url points at; other keys are served over https only,
+so an http request never receives an https credential. Accesses are audited
+with source "git".`,
+	Args:      cobra.ExactArgs(1),
+	ValidArgs: []string{"get", "store", "erase"},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cred, err := readGitCredential(cmd.InOrStdin())
+		if err != nil {
+			return err
+		}
+		if cred.Host == "" {
+			return nil
+		}
+
+		switch args[0] {
+		case "get":
+			return gitCredentialGet(cmd.OutOrStdout(), cred)
+		case "store":
+			return gitCredentialStore(cred)
+		case "erase":
+			return gitCredentialErase(cred)
+		}
+		// Unknown operations are ignored, as git's protocol asks
+		return nil
+	},
+}
+
+// gitCredential holds the attributes of git's credential helper protocol
+// that the helper uses.
+type gitCredential struct {
+	Protocol string
+	Host     string
+	Username string
+	Password string
+}
+
+// readGitCredential reads key=value lines up to a blank line or EOF.
+func readGitCredential(r io.Reader) (gitCredential, error) {
+	var c gitCredential
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" {
+			break
+		}
+		k, v, ok := strings.Cut(line, "=")
+		if !ok {
+			continue
+		}
+		switch k {
+		case "protocol":
+			c.Protocol = v
+		case "host":
+			c.Host = strings.ToLower(v)
+		case "username":
+			c.Username = v
+		case "password":
+			c.Password = v
+		}
+	}
+	return c, scanner.Err()
+}
+
+// protocol returns the request's protocol, or https if git sent none.
+func (c gitCredential) protocol() string {
+	if c.Protocol == "" {
+		return defaultGitProtocol
+	}
+	return c.Protocol
+}
+
+// gitStoredFor reports whether a key's metadata marks it as stored by the
+// helper for cred's host, protocol and username.
+func gitStoredFor(meta map[string]string, cred gitCredential) bool {
+	protocol := meta[metaGitProtocol]
+	if protocol == "" {
+		protocol = defaultGitProtocol
+	}
+	return meta[metaGitHost] == cred.Host && protocol == cred.protocol() && meta[importer.MetaUsername] == cred.Username
+}
+
+// gitKeyName returns the conventional key name for a host and optional
+// username, e.g. GIT_GITHUB_COM or GIT_GITHUB_COM_ALICE.
+func gitKeyName(host, username string) string {
+	name := "GIT_" + host
+	if username != "" {
+		name += "_" + username
+	}
+	return strings.ToUpper(strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' {
+			return r
+		}
+		return '_'
+	}, name))
+}
+
+// gitMatch is a key found for a credential request.
+type gitMatch struct {
+	Key      db.Key
+	Username string
+	Stored   bool // created by 'store'
+	Meta     map[string]string
+}
+
+// findGitKey looks up the key for cred in the active profile.
+func findGitKey(cred gitCredential) (*gitMatch, error) {
+	profile := db.GetActiveProfile()
+	keys, err := db.GetAllKeysForProfile(profile)
+	if err != nil {
+		return nil, err
+	}
+	meta, err := db.GetAllKeyMetaForProfile(profile)
+	if err != nil {
+		return nil, err
+	}
+	byName := make(map[string]db.Key, len(keys))
+	for _, k := range keys {
+		byName[k.Name] = k
+	}
+
+	match := func(k db.Key) *gitMatch {
+		m := &gitMatch{Key: k, Username: meta[k.Name][importer.MetaUsername], Stored: meta[k.Name][metaGitHost] != "", Meta: meta[k.Name]}
+		if m.Username == "" {
+			m.Username = cred.Username
+		}
+		if m.Username == "" {
+			m.Username = gitHostUsers[cred.Host]
+		}
+		return m
+	}
+	sameUser := func(k db.Key) bool {
+		u := meta[k.Name][importer.MetaUsername]
+		return cred.Username == "" || u == "" || u == cred.Username
+	}
+	sameProtocol := func(k db.Key) bool {
+		p := meta[k.Name][metaGitProtocol]
+		if p == "" {
+			p = defaultGitProtocol
+		}
+		return p == cred.protocol()
+	}
+
+	for _, k := range keys {
+		if meta[k.Name][metaGitHost] == cred.Host && sameUser(k) && sameProtocol(k) {
+			return match(k), nil
+		}
+	}
+	for _, k := range keys {
+		raw := meta[k.Name][importer.MetaURL]
+		if raw == "" {
+			continue
+		}
+		if !strings.Contains(raw, "://") {
+			raw = "https://" + raw
+		}
+		u, err := url.Parse(raw)
+		if err == nil && strings.ToLower(u.Host) == cred.Host && strings.EqualFold(u.Scheme, cred.protocol()) && sameUser(k) {
+			return match(k), nil
+		}
+	}
+	var names []string
+	if cred.Username != "" {
+		names = append(names, gitKeyName(cred.Host, cred.Username))
+	}
+	names = append(names, gitKeyName(cred.Host, ""))
+	names = append(names, gitHostKeys[cred.Host]...)
+	for _, name := range names {
+		if k, ok := byName[name]; ok && sameUser(k) && sameProtocol(k) {
+			return match(k), nil
+		}
+	}
+	return nil, nil
+}
+
+func gitCredentialGet(w io.Writer, cred gitCredential) error {
+	m, err := findGitKey(cred)
+	if err != nil || m == nil {
+		return err
+	}
+	_ = db.LogAccess(m.Key.Name, "get", "git")
+	if m.Username != "" {
+		fmt.Fprintf(w, "username=%s\n", m.Username)
+	}
+	fmt.Fprintf(w, "password=%s\n", m.Key.Value)
+	return nil
+}
+
+func gitCredentialStore(cred gitCredential) error {
+	if cred.Password == "" {
+		return nil
+	}
+	m, err := findGitKey(cred)
+	if err != nil {
+		return err
+	}
+	if m != nil && m.Key.Value == cred.Password {
+		return nil
+	}
+	// Only update a key stored for this same user; another user's credential
+	// for the host is left alone
+	name := ""
+	if m != nil && gitStoredFor(m.Meta, cred) {
+		name = m.Key.Name
+	} else if name, err = gitStoreName(cred); err != nil {
+		return err
+	}
+
+	if err := db.AddKey(name, cred.Password); err != nil {
+		return err
+	}
+	if err := db.SetKeyMeta(name, metaGitHost, cred.Host); err != nil {
+		return err
+	}
+	if err := db.SetKeyMeta(name, metaGitProtocol, cred.protocol()); err != nil {
+		return err
+	}
+	if err := db.SetKeyMeta(name, importer.MetaUsername, cred.Username); err != nil {
+		return err
+	}
+	_ = db.LogAccess(name, "store", "git")
+	return nil
+}
+
+// gitStoreName picks the key a new credential is stored under: GIT_<HOST>,
+// then GIT_<HOST>_<USER>, then numbered variants. Only keys this helper
+// created for the same user and protocol are overwritten; a key the user
+// stored under one of these names, or another user's credential, is skipped.
+func gitStoreName(cred gitCredential) (string, error) {
+	candidates := []string{gitKeyName(cred.Host, "")}
+	base := candidates[0]
+	if cred.Username != "" {
+		base = gitKeyName(cred.Host, cred.Username)
+		candidates = append(candidates, base)
+	}
+	for i := 2; i <= 100; i++ {
+		candidates = append(candidates, fmt.Sprintf("%s_%d", base, i))
+	}
+	for _, name := range candidates {
+		exists, err := db.KeyExists(name)
+		if err != nil {
+			return "", err
+		}
+		if !exists {
+			return name, nil
+		}
+		meta, err := db.GetKeyMeta(name)
+		if err != nil {
+			return "", err
+		}
+		if gitStoredFor(meta, cred) {
+			return name, nil
+		}
+	}
+	return "", fmt.Errorf("no free key name for %s credentials", cred.Host)
+}
+
+func gitCredentialErase(cred gitCredential) error {
+	m, err := findGitKey(cred)
+	if err != nil || m == nil || !m.Stored || m.Key.Value != cred.Password {
+		return err
+	}
+	_ = db.LogAccess(m.Key.Name, "erase", "git")
+	return db.DeleteKey(m.Key.Name)
+}
+
+func init() {
+	rootCmd.AddCommand(gitCredentialCmd)
+}
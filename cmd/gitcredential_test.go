@@ -0,0 +1,181 @@
+package cmd
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stym06/keys/db"
+	"github.com/stym06/keys/importer"
+)
+
+func runGitCredential(t *testing.T, op, input string) string {
+	t.Helper()
+	buf := new(bytes.Buffer)
+	rootCmd.SetOut(buf)
+	rootCmd.SetIn(strings.NewReader(input))
+	defer rootCmd.SetIn(nil)
+	rootCmd.SetArgs([]string{"git-credential", op})
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return buf.String()
+}
+
+func TestGitKeyName(t *testing.T) {
+	if got := gitKeyName("github.com", ""); got != "GIT_GITHUB_COM" {
+		t.Errorf("got %s", got)
+	}
+	if got := gitKeyName("git.example.org:8443", "alice"); got != "GIT_GIT_EXAMPLE_ORG_8443_ALICE" {
+		t.Errorf("got %s", got)
+	}
+}
+
+func TestGitCredentialGet(t *testing.T) {
+	setupTestEnv(t)
+	db.AddKey("GITHUB_TOKEN", "ghp_fallback")
+	db.AddKey("WORK_GITLAB", "glpat-work")
+	db.SetKeyMeta("WORK_GITLAB", importer.MetaURL, "https://gitlab.example.com/users/sign_in")
+	db.SetKeyMeta("WORK_GITLAB", importer.MetaUsername, "alice")
+
+	out := runGitCredential(t, "get", "protocol=https\nhost=github.com\n\n")
+	if out != "username=x-access-token\npassword=ghp_fallback\n" {
+		t.Errorf("unexpected github credential:\n%s", out)
+	}
+	out = runGitCredential(t, "get", "protocol=https\nhost=gitlab.example.com\n\n")
+	if out != "username=alice\npassword=glpat-work\n" {
+		t.Errorf("unexpected credential from url metadata:\n%s", out)
+	}
+	if out := runGitCredential(t, "get", "protocol=https\nhost=unknown.org\n\n"); out != "" {
+		t.Errorf("unknown hosts should print nothing, got:\n%s", out)
+	}
+
+	db.AddKey("GIT_GITHUB_COM", "ghp_convention")
+	out = runGitCredential(t, "get", "protocol=https\nhost=github.com\n\n")
+	if !strings.Contains(out, "password=ghp_convention") {
+		t.Errorf("GIT_<HOST> should win over GITHUB_TOKEN, got:\n%s", out)
+	}
+
+	log, _ := db.GetAuditLog(10)
+	if len(log) == 0 || log[0].Source != "git" {
+		t.Errorf("expected accesses audited with source git, got %+v", log)
+	}
+}
+
+func TestGitCredentialStoreAndErase(t *testing.T) {
+	setupTestEnv(t)
+	db.AddKey("GITHUB_TOKEN", "ghp_mine")
+
+	runGitCredential(t, "store", "protocol=https\nhost=example.org\nusername=bob\npassword=pw1\n\n")
+	k, err := db.GetKey("GIT_EXAMPLE_ORG")
+	if err != nil || k.Value != "pw1" {
+		t.Fatalf("expected GIT_EXAMPLE_ORG to be stored, got %+v, %v", k, err)
+	}
+	if out := runGitCredential(t, "get", "protocol=https\nhost=example.org\n\n"); out != "username=bob\npassword=pw1\n" {
+		t.Errorf("unexpected stored credential:\n%s", out)
+	}
+
+	// Rejecting a key the helper didn't store leaves it alone
+	runGitCredential(t, "erase", "protocol=https\nhost=github.com\npassword=ghp_mine\n\n")
+	if exists, _ := db.KeyExists("GITHUB_TOKEN"); !exists {
+		t.Error("erase should not delete keys the helper did not store")
+	}
+	runGitCredential(t, "erase", "protocol=https\nhost=example.org\npassword=other\n\n")
+	if exists, _ := db.KeyExists("GIT_EXAMPLE_ORG"); !exists {
+		t.Error("erase should only delete when the password matches")
+	}
+	runGitCredential(t, "erase", "protocol=https\nhost=example.org\nusername=bob\npassword=pw1\n\n")
+	if exists, _ := db.KeyExists("GIT_EXAMPLE_ORG"); exists {
+		t.Error("erase should delete the stored credential")
+	}
+}
+
+func TestGitCredentialStoreKeepsUserKeys(t *testing.T) {
+	setupTestEnv(t)
+	db.AddKey("GIT_EXAMPLE_COM", "user-token")
+	db.AddKey("GIT_EXAMPLE_COM_BOB", "user-bob-token")
+
+	runGitCredential(t, "store", "protocol=https\nhost=example.com\nusername=bob\npassword=helper-token\n\n")
+	runGitCredential(t, "store", "protocol=https\nhost=example.com\nusername=bob\npassword=helper-token-2\n\n")
+
+	for name, want := range map[string]string{"GIT_EXAMPLE_COM": "user-token", "GIT_EXAMPLE_COM_BOB": "user-bob-token"} {
+		if k, err := db.GetKey(name); err != nil || k.Value != want {
+			t.Errorf("expected %s to keep the user's value, got %v, %v", name, k, err)
+		}
+		if meta, _ := db.GetKeyMeta(name); meta[metaGitHost] != "" {
+			t.Errorf("expected %s not to be tagged as helper-stored", name)
+		}
+	}
+	if k, err := db.GetKey("GIT_EXAMPLE_COM_BOB_2"); err != nil || k.Value != "helper-token-2" {
+		t.Fatalf("expected the helper's key under a free name, got %v, %v", k, err)
+	}
+
+	runGitCredential(t, "erase", "protocol=https\nhost=example.com\nusername=bob\npassword=helper-token-2\n\n")
+	keys, _ := db.GetAllKeys()
+	if len(keys) != 2 {
+		t.Errorf("expected only the helper's key to be erased, got %v", keys)
+	}
+}
+
+func TestGitCredentialTwoUsersOneHost(t *testing.T) {
+	setupTestEnv(t)
+
+	runGitCredential(t, "store", "protocol=https\nhost=git.example.com\nusername=alice\npassword=alice-pw\n\n")
+	runGitCredential(t, "store", "protocol=https\nhost=git.example.com\nusername=bob\npassword=bob-pw\n\n")
+
+	for name, want := range map[string]string{"GIT_GIT_EXAMPLE_COM": "alice-pw", "GIT_GIT_EXAMPLE_COM_BOB": "bob-pw"} {
+		if k, err := db.GetKey(name); err != nil || k.Value != want {
+			t.Errorf("expected %s=%s, got %v, %v", name, want, k, err)
+		}
+	}
+	for user, want := range map[string]string{"alice": "alice-pw", "bob": "bob-pw"} {
+		out := runGitCredential(t, "get", "protocol=https\nhost=git.example.com\nusername="+user+"\n\n")
+		if out != "username="+user+"\npassword="+want+"\n" {
+			t.Errorf("unexpected credential for %s:\n%s", user, out)
+		}
+	}
+
+	// A new password for bob updates bob's key only
+	runGitCredential(t, "store", "protocol=https\nhost=git.example.com\nusername=bob\npassword=bob-pw-2\n\n")
+	if k, err := db.GetKey("GIT_GIT_EXAMPLE_COM"); err != nil || k.Value != "alice-pw" {
+		t.Errorf("bob's store should not touch alice's key, got %v, %v", k, err)
+	}
+	if k, err := db.GetKey("GIT_GIT_EXAMPLE_COM_BOB"); err != nil || k.Value != "bob-pw-2" {
+		t.Errorf("expected bob's key to be updated, got %v, %v", k, err)
+	}
+
+	runGitCredential(t, "erase", "protocol=https\nhost=git.example.com\nusername=alice\npassword=bob-pw-2\n\n")
+	runGitCredential(t, "erase", "protocol=https\nhost=git.example.com\nusername=alice\npassword=alice-pw\n\n")
+	if exists, _ := db.KeyExists("GIT_GIT_EXAMPLE_COM"); exists {
+		t.Error("erase should delete alice's credential")
+	}
+	if exists, _ := db.KeyExists("GIT_GIT_EXAMPLE_COM_BOB"); !exists {
+		t.Error("erasing alice's credential should keep bob's")
+	}
+	if out := runGitCredential(t, "get", "protocol=https\nhost=git.example.com\nusername=alice\n\n"); out != "" {
+		t.Errorf("alice should get nothing once her credential is erased, got:\n%s", out)
+	}
+}
+
+func TestGitCredentialProtocol(t *testing.T) {
+	setupTestEnv(t)
+	db.AddKey("GIT_EXAMPLE_ORG", "convention-token")
+	runGitCredential(t, "store", "protocol=https\nhost=git.example.org\nusername=bob\npassword=https-pw\n\n")
+
+	for _, host := range []string{"example.org", "git.example.org"} {
+		if out := runGitCredential(t, "get", "protocol=http\nhost="+host+"\nusername=bob\n\n"); out != "" {
+			t.Errorf("http requests for %s should not get an https credential, got:\n%s", host, out)
+		}
+	}
+
+	runGitCredential(t, "store", "protocol=http\nhost=git.example.org\nusername=bob\npassword=http-pw\n\n")
+	if meta, _ := db.GetKeyMeta("GIT_GIT_EXAMPLE_ORG_BOB"); meta[metaGitProtocol] != "http" {
+		t.Errorf("expected the http credential to record its protocol, got %v", meta)
+	}
+	for protocol, want := range map[string]string{"http": "http-pw", "https": "https-pw"} {
+		out := runGitCredential(t, "get", "protocol="+protocol+"\nhost=git.example.org\nusername=bob\n\n")
+		if out != "username=bob\npassword="+want+"\n" {
+			t.Errorf("unexpected %s credential:\n%s", protocol, out)
+		}
+	}
+}
@@ -0,0 +1,192 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/stym06/keys/db"
+	"github.com/stym06/keys/leak"
+
+	"github.com/spf13/cobra"
+)
+
+const hookMarker = "# keys pre-commit hook"
+
+var hookCmd = &cobra.Command{
+	Use:   "hook",
+	Short: "Git hooks that keep stored keys out of commits, and shell hooks that load project keys",
+}
+
+var hookInstallCmd = &cobra.Command{
+	Use:   "install",
+	Short: "Install a pre-commit hook that blocks commits containing stored keys",
+	Long: `Install a git pre-commit hook in the current repository. Before each commit
+the hook scans the staged changes for any value stored in the vault (or in
+the profiles given with --profile) and blocks the commit, naming the key and
+file.
+
+The hook matches against keyed fingerprints of the stored values, so it
+does not read the values themselves. False positives can be allowed in a
+.keys-allowlist file at the repository root:
+
+  testdata/**                  # any key under testdata
+  docs/example.md  DEMO_KEY    # one key in one file
+  *  PUBLIC_ANON_KEY           # one key anywhere
+
+Examples:
+  keys hook install
+  keys hook install --profile prod --profile staging`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		profiles, _ := cmd.Flags().GetStringSlice("profile")
+		force, _ := cmd.Flags().GetBool("force")
+
+		path, err := preCommitPath()
+		if err != nil {
+			return err
+		}
+		if existing, err := os.ReadFile(path); err == nil && !bytes.Contains(existing, []byte(hookMarker)) && !force {
+			return fmt.Errorf("a pre-commit hook already exists at %s; use --force to replace it", path)
+		}
+
+		bin, err := keysBinary()
+		if err != nil {
+			return err
+		}
+		run := shellQuote(bin) + " hook pre-commit"
+		for _, p := range profiles {
+			run += " --profile " + shellQuote(p)
+		}
+
+		script := "#!/bin/sh\n" +
+			hookMarker + ": blocks commits that contain stored keys.\n" +
+			"# Installed by 'keys hook install'; remove with 'keys hook uninstall'.\n" +
+			"exec " + run + "\n"
+
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(path, []byte(script), 0755); err != nil {
+			return err
+		}
+		fmt.Fprintf(cmd.OutOrStdout(), "Installed pre-commit hook at %s\n", path)
+		return nil
+	},
+}
+
+var hookUninstallCmd = &cobra.Command{
+	Use:   "uninstall",
+	Short: "Remove the pre-commit hook",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		path, err := preCommitPath()
+		if err != nil {
+			return err
+		}
+		existing, err := os.ReadFile(path)
+		if os.IsNotExist(err) || (err == nil && !bytes.Contains(existing, []byte(hookMarker))) {
+			return fmt.Errorf("no keys pre-commit hook installed")
+		}
+		if err != nil {
+			return err
+		}
+		if err := os.Remove(path); err != nil {
+			return err
+		}
+		fmt.Fprintf(cmd.OutOrStdout(), "Removed %s\n", path)
+		return nil
+	},
+}
+
+var hookPreCommitCmd = &cobra.Command{
+	Use:   "pre-commit",
+	Short: "Scan staged changes for stored keys (run by the git hook)",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		profiles, _ := cmd.Flags().GetStringSlice("profile")
+
+		top, err := gitOutput("rev-parse", "--show-toplevel")
+		if err != nil {
+			return err
+		}
+
+		index, err := db.GetFingerprintIndex(profiles)
+		if err != nil {
+			return err
+		}
+		if index.Empty() {
+			return nil
+		}
+
+		findings, err := leak.ScanStaged(top, index)
+		if err != nil {
+			return err
+		}
+		allow, err := loadAllowlist(filepath.Join(top, leak.AllowlistFile))
+		if err != nil {
+			return err
+		}
+		findings = allow.Filter(findings)
+		if len(findings) == 0 {
+			return nil
+		}
+
+		errOut := cmd.ErrOrStderr()
+		fmt.Fprintln(errOut, "keys: commit blocked — staged changes contain stored keys:")
+		for _, f := range findings {
+			fmt.Fprintf(errOut, "  %s  %s (%s)\n", f.Location(), f.Key, f.Profile)
+		}
+		fmt.Fprintf(errOut, "\nRemove the values, or allow false positives in %s.\n", leak.AllowlistFile)
+		return exitWith(cmd, 1)
+	},
+}
+
+func preCommitPath() (string, error) {
+	hooks, err := gitOutput("rev-parse", "--git-path", "hooks")
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(hooks, "pre-commit"), nil
+}
+
+func gitOutput(args ...string) (string, error) {
+	var stderr bytes.Buffer
+	c := exec.Command("git", args...)
+	c.Stderr = &stderr
+	out, err := c.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("git: %s", msg)
+		}
+		return "", err
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// keysBinary returns how hooks and other programs should run keys: by name
+// if it is on the PATH, else by the path of this binary.
+func keysBinary() (string, error) {
+	if _, err := exec.LookPath("keys"); err == nil {
+		return "keys", nil
+	}
+	return os.Executable()
+}
+
+// shellQuote quotes s for use as a single POSIX shell word.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+func init() {
+	hookInstallCmd.Flags().StringSliceP("profile", "p", nil, "only block keys from these profiles (default: all)")
+	hookInstallCmd.Flags().Bool("force", false, "replace an existing pre-commit hook")
+	hookPreCommitCmd.Flags().StringSliceP("profile", "p", nil, "only block keys from these profiles (default: all)")
+	hookCmd.AddCommand(hookInstallCmd)
+	hookCmd.AddCommand(hookUninstallCmd)
+	hookCmd.AddCommand(hookPreCommitCmd)
+	rootCmd.AddCommand(hookCmd)
+}
@@ -1,64 +1,82 @@
 package cmd
 
 import (
-	"bufio"
 	"fmt"
 	"os"
 	"strings"
 
 	"github.com/stym06/keys/db"
+	"github.com/stym06/keys/importer"
 
 	"github.com/spf13/cobra"
 )
 
 var importCmd = &cobra.Command{
 	Use:   "import <file>",
-	Short: "Import keys from a .env file",
-	Args:  cobra.ExactArgs(1),
+	Short: "Import keys from a .env file or password manager export",
+	Long: `Import keys from a .env file or an export from another secret store.
+
+The format is detected from the file name and contents, or set with --from:
+  dotenv          .env file (default)
+  1password-csv   1Password CSV export
+  1pux            1Password 1PUX export
+  bitwarden       Bitwarden unencrypted JSON export
+  keepass         KeePass 2 XML export
+  csv             generic CSV with a header row
+
+Item titles are converted to key names (e.g. "GitHub Token" becomes
+GITHUB_TOKEN). Notes, URLs and usernames are kept as key metadata.
+
+Examples:
+  keys import .env
+  keys import export.1pux
+  keys import bitwarden.json --from bitwarden
+  keys import tokens.csv --from csv --map name=Service --map value=Token`,
+	Args: cobra.ExactArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
-		f, err := os.Open(args[0])
+		fromFlag, _ := cmd.Flags().GetString("from")
+		mapFlag, _ := cmd.Flags().GetStringSlice("map")
+
+		data, err := os.ReadFile(args[0])
 		if err != nil {
 			return err
 		}
-		defer f.Close()
-
-		var newCount, updatedCount int
-		scanner := bufio.NewScanner(f)
-		for scanner.Scan() {
-			line := strings.TrimSpace(scanner.Text())
-			if line == "" || strings.HasPrefix(line, "#") {
-				continue
-			}
-
-			// Handle export prefix
-			line = strings.TrimPrefix(line, "export ")
 
-			parts := strings.SplitN(line, "=", 2)
-			if len(parts) != 2 {
-				continue
+		opts := importer.Options{Mapping: make(map[string]string)}
+		for _, m := range mapFlag {
+			field, col, ok := strings.Cut(m, "=")
+			if !ok {
+				return fmt.Errorf("invalid --map %q, use field=column", m)
 			}
+			opts.Mapping[strings.TrimSpace(field)] = col
+		}
 
-			name := strings.TrimSpace(parts[0])
-			value := strings.TrimSpace(parts[1])
-
-			// Strip surrounding quotes
-			if len(value) >= 2 {
-				if (value[0] == '"' && value[len(value)-1] == '"') ||
-					(value[0] == '\'' && value[len(value)-1] == '\'') {
-					value = value[1 : len(value)-1]
-				}
-			}
+		format := fromFlag
+		if format == "" {
+			format = importer.Detect(args[0], data)
+		}
 
-			if name == "" {
-				continue
-			}
+		entries, err := importer.Parse(format, data, opts)
+		if err != nil {
+			return err
+		}
 
-			exists, err := db.KeyExists(name)
+		var newCount, updatedCount int
+		for _, e := range entries {
+			exists, err := db.KeyExists(e.Name)
 			if err != nil {
 				return err
 			}
 
-			if err := db.AddKey(name, value); err != nil {
+			if err := db.AddKey(e.Name, e.Value); err != nil {
+				return err
+			}
+			for field, value := range e.Meta {
+				if err := db.SetKeyMeta(e.Name, field, value); err != nil {
+					return err
+				}
+			}
+			if err := tagProvider(cmd.ErrOrStderr(), e.Name, e.Value); err != nil {
 				return err
 			}
 
@@ -68,9 +86,6 @@ var importCmd = &cobra.Command{
 				newCount++
 			}
 		}
-		if err := scanner.Err(); err != nil {
-			return err
-		}
 
 		total := newCount + updatedCount
 		fmt.Printf("Imported %d keys (%d new, %d updated)\n", total, newCount, updatedCount)
@@ -79,5 +94,7 @@ var importCmd = &cobra.Command{
 }
 
 func init() {
+	importCmd.Flags().String("from", "", "import format (default: auto-detect)")
+	importCmd.Flags().StringSlice("map", nil, "CSV column mapping as field=column (fields: name, value, url, username, notes)")
 	rootCmd.AddCommand(importCmd)
 }
@@ -10,14 +10,18 @@ import (
 )
 
 var injectCmd = &cobra.Command{
-	Use:   "inject [key names...]",
+	Use:   "inject [names or patterns...]",
 	Short: "Output keys as inline env vars or Docker -e flags",
 	Long: `Output specified keys as inline environment variables or Docker -e flags.
 
+Keys can be named exactly or with globs such as 'AWS_*'. --match selects by
+regular expression and --exclude leaves out keys matching a glob.
+
 Examples:
   $(keys inject API_KEY DB_HOST) ./my-script.sh
   docker run $(keys inject -d API_KEY DB_HOST) my-image
-  $(keys inject --all) ./my-script.sh
+  $(keys inject 'AWS_*') aws s3 ls
+  $(keys inject --all --exclude '*_PROD') ./my-script.sh
   $(keys inject --all --profile dev) ./my-script.sh`,
 	ValidArgsFunction: completeKeyNamesMulti,
 	RunE: func(cmd *cobra.Command, args []string) error {
@@ -25,8 +29,11 @@ Examples:
 		dockerFlag, _ := cmd.Flags().GetBool("docker")
 		profileFlag, _ := cmd.Flags().GetString("profile")
 
-		if !allFlag && len(args) == 0 {
-			return fmt.Errorf("specify key names or use --all")
+		if !allFlag && !selectsKeys(cmd, args) {
+			return fmt.Errorf("specify key names, patterns or use --all")
+		}
+		if allFlag {
+			args = nil
 		}
 
 		profile := profileFlag
@@ -34,14 +41,11 @@ Examples:
 			profile = db.GetActiveProfile()
 		}
 
-		var keys []db.Key
-		var err error
-
-		if allFlag {
-			keys, err = db.GetAllKeysForProfile(profile)
-		} else {
-			keys, err = db.GetKeysByNamesForProfile(args, profile)
+		all, err := db.GetAllKeysForProfile(profile)
+		if err != nil {
+			return err
 		}
+		keys, err := selectKeys(cmd, all, args, allFlag)
 		if err != nil {
 			return err
 		}
@@ -85,5 +89,6 @@ func init() {
 	injectCmd.Flags().BoolP("docker", "d", false, "output as Docker -e flags")
 	injectCmd.Flags().BoolP("all", "a", false, "inject all keys from the profile")
 	injectCmd.Flags().StringP("profile", "p", "", "use a specific profile")
+	addSelectFlags(injectCmd)
 	rootCmd.AddCommand(injectCmd)
 }
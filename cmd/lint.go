@@ -0,0 +1,179 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/stym06/keys/db"
+	"github.com/stym06/keys/lint"
+	"github.com/stym06/keys/schema"
+
+	"github.com/spf13/cobra"
+)
+
+var lintCmd = &cobra.Command{
+	Use:   "lint",
+	Short: "Report hygiene problems across every profile",
+	Long: `Check every key in every profile for:
+
+  duplicate-value   the same value stored under another name or profile
+  invalid-name      names that are not valid environment variables
+  whitespace        leading or trailing whitespace in the value
+  empty-value       empty values
+  never-accessed    keys never read according to the audit log
+  stale             values not changed for longer than --max-age
+  live-in-dev       live provider credentials (e.g. sk_live_) in the dev profile
+
+Findings can be suppressed in ~/.keys/lint.ignore (or --ignore-file), one
+rule per line with optional NAME or profile/NAME globs:
+
+  never-accessed                # turn a rule off
+  stale  prod/LEGACY_*          # one rule for some keys in one profile
+  *  dev/TEST_*                 # every rule for some keys
+
+Exits with code 1 if anything is reported.
+
+Examples:
+  keys lint
+  keys lint --max-age 180d
+  keys lint --output json`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		jsonFlag, _ := cmd.Flags().GetBool("json")
+		maxAgeFlag, _ := cmd.Flags().GetString("max-age")
+		ignoreFlag, _ := cmd.Flags().GetString("ignore-file")
+
+		format, err := outputFormat(cmd)
+		if err != nil {
+			return err
+		}
+		if jsonFlag {
+			format = outputJSON
+		}
+
+		maxAge, err := schema.ParseDuration(maxAgeFlag)
+		if err != nil {
+			return fmt.Errorf("--max-age: %w", err)
+		}
+
+		ignorePath := ignoreFlag
+		if ignorePath == "" {
+			home, err := os.UserHomeDir()
+			if err != nil {
+				return err
+			}
+			ignorePath = filepath.Join(home, ".keys", "lint.ignore")
+		}
+		ignore, err := loadLintIgnore(ignorePath, ignoreFlag != "")
+		if err != nil {
+			return err
+		}
+
+		keys, err := lintKeys()
+		if err != nil {
+			return err
+		}
+		findings := ignore.Filter(lint.Run(keys, lint.Options{MaxAge: maxAge, Now: time.Now()}))
+
+		if format != outputTable {
+			report := lintJSON{OK: len(findings) == 0, Keys: len(keys), Findings: findings}
+			if report.Findings == nil {
+				report.Findings = []lint.Finding{}
+			}
+			if err := writeData(cmd, format, report); err != nil {
+				return err
+			}
+		} else {
+			printLintFindings(cmd, findings, len(keys))
+		}
+
+		if len(findings) > 0 {
+			return exitWith(cmd, 1)
+		}
+		return nil
+	},
+}
+
+type lintJSON struct {
+	OK       bool           `json:"ok" yaml:"ok"`
+	Keys     int            `json:"keys" yaml:"keys"`
+	Findings []lint.Finding `json:"findings" yaml:"findings"`
+}
+
+// lintKeys loads every key in every profile along with its last access.
+func lintKeys() ([]lint.Key, error) {
+	profiles, err := db.ListProfiles()
+	if err != nil {
+		return nil, err
+	}
+	var keys []lint.Key
+	for _, p := range profiles {
+		stored, err := db.GetAllKeysForProfile(p)
+		if err != nil {
+			return nil, err
+		}
+		access, err := accessByKey(p)
+		if err != nil {
+			return nil, err
+		}
+		for _, k := range stored {
+			keys = append(keys, lint.Key{Key: k, Profile: p, LastAccess: access[k.Name].AccessedAt})
+		}
+	}
+	return keys, nil
+}
+
+// loadLintIgnore reads an ignore file. A missing file is only an error if
+// it was asked for explicitly.
+func loadLintIgnore(path string, explicit bool) (*lint.Ignore, error) {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) && !explicit {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	ignore, err := lint.ParseIgnore(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return ignore, nil
+}
+
+func printLintFindings(cmd *cobra.Command, findings []lint.Finding, total int) {
+	out := cmd.OutOrStdout()
+	if len(findings) == 0 {
+		fmt.Fprintf(out, "No problems found in %d keys.\n", total)
+		return
+	}
+
+	width := 0
+	for _, f := range findings {
+		width = max(width, len(f.Rule))
+	}
+	rule := ""
+	for _, f := range findings {
+		if f.Rule != rule && rule != "" {
+			fmt.Fprintln(out)
+		}
+		rule = f.Rule
+		fmt.Fprintf(out, "  %-*s  %s/%s — %s\n", width, f.Rule, f.Profile, f.Key, f.Message)
+	}
+
+	plural := "s"
+	if len(findings) == 1 {
+		plural = ""
+	}
+	fmt.Fprintf(out, "\n%d problem%s found in %d keys.\n", len(findings), plural, total)
+}
+
+func init() {
+	lintCmd.Flags().Bool("json", false, "same as --output json")
+	addOutputFlag(lintCmd)
+	lintCmd.Flags().String("max-age", "90d", "report values unchanged for longer than this (0 to disable)")
+	lintCmd.Flags().String("ignore-file", "", "suppress findings listed in this file (default ~/.keys/lint.ignore)")
+	rootCmd.AddCommand(lintCmd)
+}
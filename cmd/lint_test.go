@@ -0,0 +1,64 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stym06/keys/db"
+	"github.com/stym06/keys/lint"
+)
+
+func TestLintReportsProblems(t *testing.T) {
+	setupTestEnv(t)
+	db.AddKey("API_KEY", "same-value")
+	db.AddKey("OTHER_KEY", "same-value")
+	db.AddKey("PADDED", "value ")
+
+	buf := new(bytes.Buffer)
+	rootCmd.SetOut(buf)
+	rootCmd.SetArgs([]string{"lint", "--json=false", "--ignore-file", ""})
+	err := rootCmd.Execute()
+
+	var code exitCode
+	if !errors.As(err, &code) || code != 1 {
+		t.Fatalf("expected exit code 1, got %v", err)
+	}
+	out := buf.String()
+	for _, want := range []string{
+		"duplicate-value  default/API_KEY — same value as default/OTHER_KEY",
+		"whitespace       default/PADDED",
+		"3 problems found in 3 keys.",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output missing %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestLintJSONWithIgnore(t *testing.T) {
+	setupTestEnv(t)
+	db.AddKey("API_KEY", "same-value")
+	db.AddKey("OTHER_KEY", "same-value")
+	db.AddKey("PADDED", "value ")
+
+	ignore := filepath.Join(t.TempDir(), "lint.ignore")
+	os.WriteFile(ignore, []byte("duplicate-value\n"), 0600)
+
+	buf := new(bytes.Buffer)
+	rootCmd.SetOut(buf)
+	rootCmd.SetArgs([]string{"lint", "--json", "--ignore-file", ignore})
+	rootCmd.Execute()
+
+	var report lintJSON
+	if err := json.Unmarshal(buf.Bytes(), &report); err != nil {
+		t.Fatalf("invalid JSON: %v\n%s", err, buf.String())
+	}
+	if report.OK || len(report.Findings) != 1 || report.Findings[0].Rule != lint.Whitespace {
+		t.Errorf("unexpected report: %+v", report)
+	}
+}
@@ -0,0 +1,203 @@
+package cmd
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/stym06/keys/db"
+	"github.com/stym06/keys/provider"
+	"github.com/stym06/keys/schema"
+
+	"github.com/spf13/cobra"
+)
+
+var lsCmd = &cobra.Command{
+	Use:   "ls [pattern]",
+	Short: "List stored key names without values",
+	Long: `List the keys in a profile without printing their values. Listing is not
+recorded in the audit log.
+
+The pattern is a glob such as 'STRIPE_*', or a regular expression with
+--regex. --long adds the age of the value, a masked preview, tags and when
+the key was last accessed.
+
+Examples:
+  keys ls
+  keys ls 'AWS_*' --long
+  keys ls '^(GH|GITHUB)_' --regex
+  keys ls --older-than 90d --sort age
+  keys ls --unused-since 30d --profile prod
+  keys ls --sort usage --output json`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		profileFlag, _ := cmd.Flags().GetString("profile")
+		regexFlag, _ := cmd.Flags().GetBool("regex")
+		long, _ := cmd.Flags().GetBool("long")
+		olderThan, _ := cmd.Flags().GetString("older-than")
+		unusedSince, _ := cmd.Flags().GetString("unused-since")
+		sortFlag, _ := cmd.Flags().GetString("sort")
+		format, err := outputFormat(cmd)
+		if err != nil {
+			return err
+		}
+
+		var sel *keySelector
+		if len(args) == 1 {
+			if sel, err = newKeySelector(args[0], regexFlag); err != nil {
+				return err
+			}
+		}
+		var minAge, unused time.Duration
+		if olderThan != "" {
+			if minAge, err = schema.ParseDuration(olderThan); err != nil {
+				return fmt.Errorf("--older-than: %w", err)
+			}
+		}
+		if unusedSince != "" {
+			if unused, err = schema.ParseDuration(unusedSince); err != nil {
+				return fmt.Errorf("--unused-since: %w", err)
+			}
+		}
+		switch sortFlag {
+		case "name", "age", "usage":
+		default:
+			return fmt.Errorf("invalid --sort %q (use name, age or usage)", sortFlag)
+		}
+
+		profile := profileFlag
+		if profile == "" {
+			profile = db.GetActiveProfile()
+		}
+		keys, err := db.GetAllKeysForProfile(profile)
+		if err != nil {
+			return err
+		}
+		access, err := accessByKey(profile)
+		if err != nil {
+			return err
+		}
+		meta, err := db.GetAllKeyMetaForProfile(profile)
+		if err != nil {
+			return err
+		}
+
+		now := time.Now()
+		var items []keyItem
+		for _, k := range sel.Filter(keys) {
+			if minAge > 0 && now.Sub(time.Unix(k.LastRotated(), 0)) <= minAge {
+				continue
+			}
+			last := access[k.Name].AccessedAt
+			if unused > 0 && last != 0 && now.Sub(time.Unix(last, 0)) <= unused {
+				continue
+			}
+			item := newKeyItem(profile, k)
+			item.Preview = previewValue(k.Value)
+			item.Tags = keyTags(k, meta[k.Name])
+			item.LastAccessed = timestamp(last)
+			item.AccessCount = access[k.Name].Count
+			items = append(items, item)
+		}
+
+		switch sortFlag {
+		case "age":
+			sort.SliceStable(items, func(i, j int) bool {
+				return unixTime(items[i].RotatedAt) < unixTime(items[j].RotatedAt)
+			})
+		case "usage":
+			sort.SliceStable(items, func(i, j int) bool {
+				return items[i].AccessCount > items[j].AccessCount
+			})
+		}
+
+		if format != outputTable {
+			if items == nil {
+				items = []keyItem{}
+			}
+			return writeData(cmd, format, items)
+		}
+		printKeyList(cmd, items, long)
+		return nil
+	},
+}
+
+func printKeyList(cmd *cobra.Command, items []keyItem, long bool) {
+	out := cmd.OutOrStdout()
+	if !long {
+		for _, item := range items {
+			fmt.Fprintln(out, item.Name)
+		}
+		return
+	}
+
+	rows := [][]string{{"NAME", "AGE", "PREVIEW", "TAGS", "LAST USED"}}
+	for _, item := range items {
+		lastUsed := formatTimeAgo(unixTime(item.LastAccessed))
+		age := strings.TrimSuffix(formatTimeAgo(unixTime(item.RotatedAt)), " ago")
+		tags := strings.Join(item.Tags, ",")
+		if tags == "" {
+			tags = "-"
+		}
+		rows = append(rows, []string{item.Name, age, item.Preview, tags, lastUsed})
+	}
+	printTable(out, rows)
+}
+
+// keyItem is the machine-readable form of a stored key. Values are only
+// included by commands that print them.
+type keyItem struct {
+	Name         string     `json:"name" yaml:"name"`
+	Profile      string     `json:"profile" yaml:"profile"`
+	Value        string     `json:"value,omitempty" yaml:"value,omitempty"`
+	Preview      string     `json:"preview,omitempty" yaml:"preview,omitempty"`
+	Provider     string     `json:"provider,omitempty" yaml:"provider,omitempty"`
+	Tags         []string   `json:"tags,omitempty" yaml:"tags,omitempty"`
+	UpdatedAt    *time.Time `json:"updated_at" yaml:"updated_at"`
+	RotatedAt    *time.Time `json:"rotated_at" yaml:"rotated_at"`
+	LastAccessed *time.Time `json:"last_accessed,omitempty" yaml:"last_accessed,omitempty"`
+	AccessCount  int        `json:"access_count,omitempty" yaml:"access_count,omitempty"`
+}
+
+func newKeyItem(profile string, k db.Key) keyItem {
+	item := keyItem{
+		Name:      k.Name,
+		Profile:   profile,
+		UpdatedAt: timestamp(k.UpdatedAt),
+		RotatedAt: timestamp(k.LastRotated()),
+	}
+	if p := provider.Detect(k.Value); p != nil {
+		item.Provider = p.ID
+	}
+	return item
+}
+
+// previewValue masks a value, keeping only a recognised provider prefix
+// such as "ghp_" and the length.
+func previewValue(v string) string {
+	return provider.Prefix(v) + maskValue(v)
+}
+
+// keyTags returns the tags of a key. Keys stored before provider tagging
+// fall back to detecting the provider from the value.
+func keyTags(k db.Key, meta map[string]string) []string {
+	if id := meta[metaProvider]; id != "" {
+		return []string{id}
+	}
+	if p := provider.Detect(k.Value); p != nil {
+		return []string{p.ID}
+	}
+	return nil
+}
+
+func init() {
+	lsCmd.Flags().StringP("profile", "p", "", "list a specific profile")
+	lsCmd.Flags().Bool("regex", false, "treat the pattern as a regular expression")
+	lsCmd.Flags().BoolP("long", "l", false, "show age, masked preview, tags and last access")
+	lsCmd.Flags().String("older-than", "", "only keys whose value is older than this, e.g. 90d")
+	lsCmd.Flags().String("unused-since", "", "only keys not accessed for this long, e.g. 30d")
+	lsCmd.Flags().String("sort", "name", "sort by name, age (oldest first) or usage (most used first)")
+	addOutputFlag(lsCmd)
+	rootCmd.AddCommand(lsCmd)
+}
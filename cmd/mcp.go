@@ -0,0 +1,457 @@
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/stym06/keys/db"
+	"github.com/stym06/keys/mcp"
+	"github.com/stym06/keys/schema"
+
+	"github.com/spf13/cobra"
+)
+
+const (
+	agentSource = "agent"
+
+	// maxAgentOutput caps the command output returned by inject_and_run.
+	maxAgentOutput = 64 * 1024
+)
+
+var mcpCmd = &cobra.Command{
+	Use:   "mcp",
+	Short: "Let AI agents use keys through the Model Context Protocol",
+	Long: `Run an MCP server on stdin and stdout so AI agents can work with keys
+without being handed raw values by 'keys get'. It offers these tools:
+
+  list_keys        names of the keys the agent may ask for
+  check_keys       whether keys are present and valid, without their values
+  request_key      one value, once you approve it
+  inject_and_run   run a command with keys in its environment, once you
+                   approve it; the values are redacted from its output
+
+Only keys matching an --allow glob can be requested or injected, and only
+from the profiles given with --profile (default: the active profile). Each
+request is shown on your terminal: y allows it once, a allows the same
+request for the rest of the session. An "always" covers only the tool that
+asked and, for inject_and_run, the exact command. Every call is recorded in
+the audit log with source "agent", including denied ones.
+
+Example MCP client configuration:
+  {"mcpServers": {"keys": {"command": "keys", "args": ["mcp", "--allow", "OPENAI_*"]}}}`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		allow, _ := cmd.Flags().GetStringSlice("allow")
+		profiles, _ := cmd.Flags().GetStringSlice("profile")
+
+		for _, p := range allow {
+			if _, err := path.Match(p, ""); err != nil {
+				return fmt.Errorf("invalid --allow pattern %q", p)
+			}
+		}
+		if len(profiles) == 0 {
+			profiles = []string{db.GetActiveProfile()}
+		}
+		policy := &agentPolicy{profiles: profiles, allow: allow, session: make(map[string]bool)}
+
+		ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+		defer stop()
+		server := mcp.NewServer("keys", Version, policy.tools()...)
+		return server.Serve(ctx, cmd.InOrStdin(), cmd.OutOrStdout())
+	},
+}
+
+// agentPolicy decides which keys an agent may read and asks the user to
+// approve each read.
+type agentPolicy struct {
+	profiles []string
+	allow    []string
+	session  map[string]bool // approved for the rest of the session, by approvalRequest.sessionKey
+}
+
+// profile returns the profile a call works on: the one requested, which
+// must be available to agents, or the first available one.
+func (p *agentPolicy) profile(requested string) (string, error) {
+	if requested == "" {
+		return p.profiles[0], nil
+	}
+	for _, profile := range p.profiles {
+		if profile == requested {
+			return profile, nil
+		}
+	}
+	return "", fmt.Errorf("profile %q is not available to agents; the user can allow it with 'keys mcp --profile %s'", requested, requested)
+}
+
+func (p *agentPolicy) allowed(name string) bool {
+	for _, glob := range p.allow {
+		if ok, _ := path.Match(glob, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// checkAllowed refuses names outside the allowlist. authorize runs it before
+// keys are looked up, so a refusal doesn't tell the agent whether the key
+// exists.
+func (p *agentPolicy) checkAllowed(profile string, names []string) error {
+	for _, name := range names {
+		if !p.allowed(name) {
+			_ = db.LogAccessForProfile(profile, name, "denied", agentSource)
+			return fmt.Errorf("%s is not in the allowlist; the user can allow it with 'keys mcp --allow %s'", name, name)
+		}
+	}
+	return nil
+}
+
+// authorize asks the user to approve the keys of req not already approved
+// for the session. Session approvals only cover the same tool, and for
+// inject_and_run the same command, so approving a command never lets an
+// agent read the value or run something else with it.
+func (p *agentPolicy) authorize(req approvalRequest) error {
+	if err := p.checkAllowed(req.Profile, req.Keys); err != nil {
+		return err
+	}
+	var pending []string
+	for _, name := range req.Keys {
+		if !p.session[req.sessionKey(name)] {
+			pending = append(pending, name)
+		}
+	}
+	if len(pending) == 0 {
+		return nil
+	}
+
+	req.Keys = pending
+	answer, err := approve(req)
+	if err != nil || answer == approveNo {
+		for _, name := range pending {
+			_ = db.LogAccessForProfile(req.Profile, name, "denied", agentSource)
+		}
+		if err != nil {
+			return fmt.Errorf("could not ask the user for approval: %w", err)
+		}
+		return fmt.Errorf("the user denied access to %s", strings.Join(pending, ", "))
+	}
+	if answer == approveSession {
+		for _, name := range pending {
+			p.session[req.sessionKey(name)] = true
+		}
+	}
+	return nil
+}
+
+type approval int
+
+const (
+	approveNo approval = iota
+	approveOnce
+	approveSession
+)
+
+type approvalRequest struct {
+	Tool    string
+	Profile string
+	Keys    []string
+	Command []string // set for inject_and_run
+	Reason  string
+}
+
+// sessionKey identifies what an "always" answer approves for one key.
+func (r approvalRequest) sessionKey(name string) string {
+	command, _ := json.Marshal(r.Command)
+	return r.Tool + "|" + r.Profile + "|" + name + "|" + string(command)
+}
+
+// approve asks the user whether an agent may read keys. Tests replace it.
+var approve = approveOnTerminal
+
+// approveOnTerminal asks on the controlling terminal, since stdin and
+// stdout belong to the agent.
+func approveOnTerminal(req approvalRequest) (approval, error) {
+	tty, err := os.OpenFile("/dev/tty", os.O_RDWR, 0)
+	if err != nil {
+		return approveNo, errors.New("no terminal available")
+	}
+	defer tty.Close()
+
+	keys := strings.Join(req.Keys, ", ")
+	if len(req.Command) > 0 {
+		fmt.Fprintf(tty, "\nkeys: an agent wants to run `%s` with %s (%s)\n", strings.Join(req.Command, " "), keys, req.Profile)
+	} else {
+		fmt.Fprintf(tty, "\nkeys: an agent asks for %s (%s)\n", keys, req.Profile)
+	}
+	if req.Reason != "" {
+		fmt.Fprintf(tty, "  reason: %s\n", req.Reason)
+	}
+	fmt.Fprint(tty, "Allow? [y]es, [N]o, [a]lways this session: ")
+
+	line, _ := bufio.NewReader(tty).ReadString('\n')
+	switch strings.ToLower(strings.TrimSpace(line)) {
+	case "y", "yes":
+		return approveOnce, nil
+	case "a", "always":
+		return approveSession, nil
+	}
+	return approveNo, nil
+}
+
+func (p *agentPolicy) tools() []mcp.Tool {
+	profileProp := map[string]any{"type": "string", "description": "profile to use (default: the first profile available to agents)"}
+	return []mcp.Tool{
+		{
+			Name:        "list_keys",
+			Description: "List the names of stored keys this agent may request. Never returns values.",
+			InputSchema: objectSchema(map[string]any{"profile": profileProp}),
+			Handler:     p.listKeys,
+		},
+		{
+			Name:        "check_keys",
+			Description: "Check whether keys are present and satisfy the rules in a requirements file such as .keys.required. Never returns values.",
+			InputSchema: objectSchema(map[string]any{
+				"names":   map[string]any{"type": "array", "items": map[string]any{"type": "string"}, "description": "key names to check; if empty, the rules in file are checked"},
+				"file":    map[string]any{"type": "string", "description": "requirements file (default .keys.required)"},
+				"profile": profileProp,
+			}),
+			Handler: p.checkKeys,
+		},
+		{
+			Name:        "request_key",
+			Description: "Get the value of one key. The user must approve the request on their terminal, so explain why it is needed.",
+			InputSchema: objectSchema(map[string]any{
+				"name":    map[string]any{"type": "string"},
+				"reason":  map[string]any{"type": "string", "description": "why the value is needed, shown to the user"},
+				"profile": profileProp,
+			}, "name", "reason"),
+			Handler: p.requestKey,
+		},
+		{
+			Name:        "inject_and_run",
+			Description: "Run a command with keys set as environment variables, without seeing their values. The user must approve it on their terminal. Returns the exit code and output, with the values redacted.",
+			InputSchema: objectSchema(map[string]any{
+				"command":         map[string]any{"type": "array", "items": map[string]any{"type": "string"}, "description": "program and arguments, e.g. [\"npm\", \"test\"]"},
+				"keys":            map[string]any{"type": "array", "items": map[string]any{"type": "string"}},
+				"reason":          map[string]any{"type": "string", "description": "why the command needs the keys, shown to the user"},
+				"profile":         profileProp,
+				"timeout_seconds": map[string]any{"type": "integer", "description": "default 300"},
+			}, "command", "keys"),
+			Handler: p.injectAndRun,
+		},
+	}
+}
+
+func objectSchema(props map[string]any, required ...string) map[string]any {
+	schema := map[string]any{"type": "object", "properties": props}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema
+}
+
+func decodeArgs(raw json.RawMessage, v any) error {
+	if err := json.Unmarshal(raw, v); err != nil {
+		return fmt.Errorf("invalid arguments: %v", err)
+	}
+	return nil
+}
+
+func jsonText(v any) (string, error) {
+	b, err := json.MarshalIndent(v, "", "  ")
+	return string(b), err
+}
+
+func (p *agentPolicy) listKeys(ctx context.Context, raw json.RawMessage) (string, error) {
+	var args struct {
+		Profile string `json:"profile"`
+	}
+	if err := decodeArgs(raw, &args); err != nil {
+		return "", err
+	}
+	profile, err := p.profile(args.Profile)
+	if err != nil {
+		return "", err
+	}
+	keys, err := db.GetAllKeysForProfile(profile)
+	if err != nil {
+		return "", err
+	}
+	names := []string{}
+	for _, k := range keys {
+		if p.allowed(k.Name) {
+			names = append(names, k.Name)
+		}
+	}
+	_ = db.LogAccessForProfile(profile, "*", "list", agentSource)
+	return jsonText(map[string]any{"profile": profile, "keys": names})
+}
+
+func (p *agentPolicy) checkKeys(ctx context.Context, raw json.RawMessage) (string, error) {
+	var args struct {
+		Names   []string `json:"names"`
+		File    string   `json:"file"`
+		Profile string   `json:"profile"`
+	}
+	if err := decodeArgs(raw, &args); err != nil {
+		return "", err
+	}
+	profile, err := p.profile(args.Profile)
+	if err != nil {
+		return "", err
+	}
+
+	var rules []schema.Rule
+	if len(args.Names) > 0 {
+		for _, name := range args.Names {
+			rules = append(rules, schema.Rule{Name: name})
+		}
+	} else {
+		if args.File == "" {
+			args.File = ".keys.required"
+		}
+		f, err := os.Open(args.File)
+		if err != nil {
+			return "", fmt.Errorf("cannot open %s: %w", args.File, err)
+		}
+		rules, err = schema.Parse(f)
+		f.Close()
+		if err != nil {
+			return "", fmt.Errorf("%s: %w", args.File, err)
+		}
+	}
+
+	keys, err := db.GetAllKeysForProfile(profile)
+	if err != nil {
+		return "", err
+	}
+	_ = db.LogAccessForProfile(profile, "*", "check", agentSource)
+	return jsonText(checkReport(args.File, profile, schema.Validate(rules, keys, time.Now())))
+}
+
+func (p *agentPolicy) requestKey(ctx context.Context, raw json.RawMessage) (string, error) {
+	var args struct {
+		Name    string `json:"name"`
+		Reason  string `json:"reason"`
+		Profile string `json:"profile"`
+	}
+	if err := decodeArgs(raw, &args); err != nil {
+		return "", err
+	}
+	if args.Name == "" {
+		return "", errors.New("name is required")
+	}
+	profile, err := p.profile(args.Profile)
+	if err != nil {
+		return "", err
+	}
+	if err := p.authorize(approvalRequest{Tool: "request_key", Profile: profile, Keys: []string{args.Name}, Reason: args.Reason}); err != nil {
+		return "", err
+	}
+	k, err := db.GetKeyForProfile(profile, args.Name)
+	if err != nil {
+		return "", err
+	}
+	_ = db.LogAccessForProfile(profile, k.Name, "get", agentSource)
+	return k.Value, nil
+}
+
+func (p *agentPolicy) injectAndRun(ctx context.Context, raw json.RawMessage) (string, error) {
+	var args struct {
+		Command []string `json:"command"`
+		Keys    []string `json:"keys"`
+		Reason  string   `json:"reason"`
+		Profile string   `json:"profile"`
+		Timeout int      `json:"timeout_seconds"`
+	}
+	if err := decodeArgs(raw, &args); err != nil {
+		return "", err
+	}
+	if len(args.Command) == 0 || len(args.Keys) == 0 {
+		return "", errors.New("command and keys are required")
+	}
+	profile, err := p.profile(args.Profile)
+	if err != nil {
+		return "", err
+	}
+
+	if err := p.authorize(approvalRequest{Tool: "inject_and_run", Profile: profile, Keys: args.Keys, Command: args.Command, Reason: args.Reason}); err != nil {
+		return "", err
+	}
+	keys, err := db.GetKeysByNamesForProfile(args.Keys, profile)
+	if err != nil {
+		return "", err
+	}
+	found := make(map[string]bool)
+	for _, k := range keys {
+		found[k.Name] = true
+	}
+	for _, name := range args.Keys {
+		if !found[name] {
+			return "", fmt.Errorf("key %q %w", name, db.ErrNotFound)
+		}
+	}
+
+	timeout := 300 * time.Second
+	if args.Timeout > 0 {
+		timeout = time.Duration(args.Timeout) * time.Second
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	c := exec.CommandContext(ctx, args.Command[0], args.Command[1:]...)
+	c.Env = os.Environ()
+	for _, k := range keys {
+		c.Env = append(c.Env, k.Name+"="+k.Value)
+		_ = db.LogAccessForProfile(profile, k.Name, "inject", agentSource)
+	}
+	out, err := c.CombinedOutput()
+
+	exitCode := 0
+	var exitErr *exec.ExitError
+	switch {
+	case ctx.Err() == context.DeadlineExceeded:
+		return "", fmt.Errorf("command timed out after %s", timeout)
+	case errors.As(err, &exitErr):
+		exitCode = exitErr.ExitCode()
+	case err != nil:
+		return "", err
+	}
+
+	output := redactValues(string(out), keys)
+	truncated := len(output) > maxAgentOutput
+	if truncated {
+		output = output[len(output)-maxAgentOutput:]
+	}
+	return jsonText(map[string]any{"exit_code": exitCode, "output": output, "truncated": truncated})
+}
+
+// redactValues replaces every stored value in s with the name of its key,
+// longest values first so that one value containing another is replaced
+// whole.
+func redactValues(s string, keys []db.Key) string {
+	sorted := append([]db.Key(nil), keys...)
+	sort.Slice(sorted, func(i, j int) bool { return len(sorted[i].Value) > len(sorted[j].Value) })
+	for _, k := range sorted {
+		if k.Value != "" {
+			s = strings.ReplaceAll(s, k.Value, "[redacted "+k.Name+"]")
+		}
+	}
+	return s
+}
+
+func init() {
+	mcpCmd.Flags().StringSlice("allow", nil, "key name globs agents may request or inject (default: none)")
+	mcpCmd.Flags().StringSliceP("profile", "p", nil, "profiles agents may use (default: the active profile)")
+	rootCmd.AddCommand(mcpCmd)
+}
@@ -0,0 +1,204 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stym06/keys/db"
+)
+
+// mcpCall runs one tools/call through 'keys mcp' and returns the text of the
+// result and whether it is an error.
+func mcpCall(t *testing.T, flags []string, tool string, args map[string]any) (string, bool) {
+	t.Helper()
+	params, _ := json.Marshal(map[string]any{"name": tool, "arguments": args})
+	in := fmt.Sprintf(`{"jsonrpc":"2.0","id":1,"method":"tools/call","params":%s}`+"\n", params)
+
+	buf := new(bytes.Buffer)
+	rootCmd.SetOut(buf)
+	rootCmd.SetIn(strings.NewReader(in))
+	rootCmd.SetArgs(append([]string{"mcp"}, flags...))
+	err := rootCmd.Execute()
+	rootCmd.SetIn(nil)
+	resetSliceFlag(t, "allow")
+	resetSliceFlag(t, "profile")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var resp struct {
+		Result struct {
+			Content []struct{ Text string }
+			IsError bool
+		}
+	}
+	line, _ := bufio.NewReader(buf).ReadBytes('\n')
+	if err := json.Unmarshal(line, &resp); err != nil || len(resp.Result.Content) != 1 {
+		t.Fatalf("unexpected response %q: %v", buf.String(), err)
+	}
+	return resp.Result.Content[0].Text, resp.Result.IsError
+}
+
+func resetSliceFlag(t *testing.T, name string) {
+	t.Helper()
+	f := mcpCmd.Flags().Lookup(name)
+	f.Value.(interface{ Replace([]string) error }).Replace(nil)
+	f.Changed = false
+}
+
+// fakeApprove answers every approval request with answer and counts them.
+func fakeApprove(t *testing.T, answer approval) *int {
+	t.Helper()
+	asked := 0
+	orig := approve
+	approve = func(req approvalRequest) (approval, error) {
+		asked++
+		return answer, nil
+	}
+	t.Cleanup(func() { approve = orig })
+	return &asked
+}
+
+func TestMCPRequestKey(t *testing.T) {
+	setupTestEnv(t)
+	db.AddKey("OPENAI_KEY", "sk-agent")
+	db.AddKey("STRIPE_KEY", "sk_live_x")
+	asked := fakeApprove(t, approveOnce)
+
+	allow := []string{"--allow", "OPENAI_*"}
+	out, isErr := mcpCall(t, allow, "request_key", map[string]any{"name": "OPENAI_KEY", "reason": "run tests"})
+	if isErr || out != "sk-agent" || *asked != 1 {
+		t.Errorf("expected the approved value, got %q (error %v, asked %d)", out, isErr, *asked)
+	}
+
+	out, isErr = mcpCall(t, allow, "request_key", map[string]any{"name": "STRIPE_KEY", "reason": "x"})
+	if !isErr || !strings.Contains(out, "not in the allowlist") || *asked != 1 {
+		t.Errorf("expected a key outside the allowlist to be refused without asking, got %q", out)
+	}
+
+	out, isErr = mcpCall(t, append(allow, "--profile", "prod"), "request_key", map[string]any{"name": "OPENAI_KEY", "profile": "default"})
+	if !isErr || !strings.Contains(out, "not available to agents") {
+		t.Errorf("expected a profile outside --profile to be refused, got %q", out)
+	}
+
+	list, _ := mcpCall(t, allow, "list_keys", nil)
+	if !strings.Contains(list, "OPENAI_KEY") || strings.Contains(list, "STRIPE_KEY") || strings.Contains(list, "sk-agent") {
+		t.Errorf("list_keys should show only allowed names: %s", list)
+	}
+
+	entries, _ := db.GetAuditLog(10)
+	actions := map[string]string{}
+	for _, e := range entries {
+		if e.Source != agentSource {
+			t.Errorf("expected source %q, got %+v", agentSource, e)
+		}
+		actions[e.KeyName+" "+e.Action] = e.Source
+	}
+	for _, want := range []string{"OPENAI_KEY get", "STRIPE_KEY denied", "* list"} {
+		if _, ok := actions[want]; !ok {
+			t.Errorf("missing audit entry %q in %v", want, actions)
+		}
+	}
+}
+
+func TestMCPRequestKeyDenied(t *testing.T) {
+	setupTestEnv(t)
+	db.AddKey("OPENAI_KEY", "sk-agent")
+	fakeApprove(t, approveNo)
+
+	out, isErr := mcpCall(t, []string{"--allow", "*"}, "request_key", map[string]any{"name": "OPENAI_KEY"})
+	if !isErr || strings.Contains(out, "sk-agent") || !strings.Contains(out, "denied") {
+		t.Errorf("expected the request to be denied, got %q", out)
+	}
+}
+
+func TestMCPInjectAndRunRedacts(t *testing.T) {
+	setupTestEnv(t)
+	db.AddKey("OPENAI_KEY", "sk-agent")
+	asked := fakeApprove(t, approveSession)
+
+	policy := &agentPolicy{profiles: []string{"default"}, allow: []string{"OPENAI_*"}, session: map[string]bool{}}
+	args, _ := json.Marshal(map[string]any{"command": []string{"sh", "-c", "echo key=$OPENAI_KEY; exit 3"}, "keys": []string{"OPENAI_KEY"}})
+	for i := 0; i < 2; i++ {
+		out, err := policy.injectAndRun(t.Context(), args)
+		if err != nil {
+			t.Fatalf("injectAndRun: %v", err)
+		}
+		var res struct {
+			ExitCode int `json:"exit_code"`
+			Output   string
+		}
+		json.Unmarshal([]byte(out), &res)
+		if res.ExitCode != 3 || strings.TrimSpace(res.Output) != "key=[redacted OPENAI_KEY]" {
+			t.Errorf("unexpected result: %s", out)
+		}
+	}
+	if *asked != 1 {
+		t.Errorf("expected one approval for the session, got %d", *asked)
+	}
+}
+
+func TestMCPCheckKeys(t *testing.T) {
+	setupTestEnv(t)
+	db.AddKey("OPENAI_KEY", "sk-agent")
+	fakeApprove(t, approveNo)
+
+	out, isErr := mcpCall(t, nil, "check_keys", map[string]any{"names": []string{"OPENAI_KEY", "MISSING_KEY"}})
+	var report checkJSON
+	if err := json.Unmarshal([]byte(out), &report); err != nil || isErr {
+		t.Fatalf("unexpected result %q: %v", out, err)
+	}
+	if report.OK || !report.Keys[0].Present || report.Keys[1].Present || strings.Contains(out, "sk-agent") {
+		t.Errorf("unexpected report: %s", out)
+	}
+}
+
+func TestMCPSessionApprovalIsPerToolAndCommand(t *testing.T) {
+	setupTestEnv(t)
+	db.AddKey("OPENAI_KEY", "sk-agent")
+	asked := fakeApprove(t, approveSession)
+
+	policy := &agentPolicy{profiles: []string{"default"}, allow: []string{"OPENAI_*"}, session: map[string]bool{}}
+	run := func(command ...string) {
+		t.Helper()
+		args, _ := json.Marshal(map[string]any{"command": command, "keys": []string{"OPENAI_KEY"}})
+		if _, err := policy.injectAndRun(t.Context(), args); err != nil {
+			t.Fatalf("injectAndRun: %v", err)
+		}
+	}
+	run("true")
+	run("true")
+	if *asked != 1 {
+		t.Fatalf("expected the same command to be approved for the session, asked %d times", *asked)
+	}
+	run("sh", "-c", "echo $OPENAI_KEY | base64")
+	if *asked != 2 {
+		t.Errorf("expected a different command to ask again, asked %d times", *asked)
+	}
+	args, _ := json.Marshal(map[string]any{"name": "OPENAI_KEY"})
+	if _, err := policy.requestKey(t.Context(), args); err != nil {
+		t.Fatalf("requestKey: %v", err)
+	}
+	if *asked != 3 {
+		t.Errorf("expected request_key to ask despite inject approvals, asked %d times", *asked)
+	}
+}
+
+func TestMCPAllowlistCheckedBeforeLookup(t *testing.T) {
+	setupTestEnv(t)
+	fakeApprove(t, approveOnce)
+
+	allow := []string{"--allow", "OPENAI_*"}
+	out, _ := mcpCall(t, allow, "request_key", map[string]any{"name": "SECRET_MISSING"})
+	if !strings.Contains(out, "not in the allowlist") {
+		t.Errorf("expected an allowlist refusal for a missing key, got %q", out)
+	}
+	out, _ = mcpCall(t, allow, "inject_and_run", map[string]any{"command": []string{"true"}, "keys": []string{"SECRET_MISSING"}})
+	if !strings.Contains(out, "not in the allowlist") {
+		t.Errorf("expected an allowlist refusal for a missing key, got %q", out)
+	}
+}
@@ -0,0 +1,94 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+// Output formats accepted by --output. json and yaml are meant for
+// scripts: they print only the data, with the field names documented in
+// the README, and never prompt.
+const (
+	outputTable = "table"
+	outputJSON  = "json"
+	outputYAML  = "yaml"
+)
+
+func addOutputFlag(c *cobra.Command) {
+	c.Flags().String("output", outputTable, "output format: table, json or yaml")
+}
+
+// outputFormat returns the command's --output value.
+func outputFormat(cmd *cobra.Command) (string, error) {
+	format, _ := cmd.Flags().GetString("output")
+	switch format {
+	case outputTable, outputJSON, outputYAML:
+		return format, nil
+	}
+	return "", fmt.Errorf("invalid --output %q (use table, json or yaml)", format)
+}
+
+// writeData prints v as JSON or YAML.
+func writeData(cmd *cobra.Command, format string, v any) error {
+	out := cmd.OutOrStdout()
+	if format == outputYAML {
+		enc := yaml.NewEncoder(out)
+		enc.SetIndent(2)
+		if err := enc.Encode(v); err != nil {
+			return err
+		}
+		return enc.Close()
+	}
+	enc := json.NewEncoder(out)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+// timestamp converts a unix time for machine output. Unknown times (0)
+// become null.
+func timestamp(unix int64) *time.Time {
+	if unix == 0 {
+		return nil
+	}
+	t := time.Unix(unix, 0).UTC()
+	return &t
+}
+
+// unixTime is the inverse of timestamp.
+func unixTime(t *time.Time) int64 {
+	if t == nil {
+		return 0
+	}
+	return t.Unix()
+}
+
+// printTable writes rows as left-aligned columns, the first row being the
+// header.
+func printTable(w io.Writer, rows [][]string) {
+	if len(rows) == 0 {
+		return
+	}
+	widths := make([]int, len(rows[0]))
+	for _, row := range rows {
+		for i, col := range row {
+			widths[i] = max(widths[i], len(col))
+		}
+	}
+	for _, row := range rows {
+		var b strings.Builder
+		for i, col := range row {
+			if i == len(row)-1 {
+				b.WriteString(col)
+				break
+			}
+			fmt.Fprintf(&b, "%-*s  ", widths[i], col)
+		}
+		fmt.Fprintln(w, b.String())
+	}
+}
@@ -19,7 +19,7 @@ var peekCmd = &cobra.Command{
 			return err
 		}
 
-		m := tui.NewPeek(keys)
+		m := tui.NewPeek(keys).WithCopy(tuiCopy)
 		p := tea.NewProgram(m)
 		result, err := p.Run()
 		if err != nil {
@@ -30,6 +30,9 @@ var peekCmd = &cobra.Command{
 		if msg := final.Message(); msg != "" {
 			fmt.Println(msg)
 		}
+		if final.SendProfile() {
+			return sendToProfile(final.SendKeys())
+		}
 		return nil
 	},
 }
@@ -17,6 +17,10 @@ var profileListCmd = &cobra.Command{
 	Use:   "list",
 	Short: "List all profiles",
 	RunE: func(cmd *cobra.Command, args []string) error {
+		format, err := outputFormat(cmd)
+		if err != nil {
+			return err
+		}
 		active := db.GetActiveProfile()
 		profiles, err := db.ListProfiles()
 		if err != nil {
@@ -35,17 +39,32 @@ var profileListCmd = &cobra.Command{
 			profiles = append([]string{active}, profiles...)
 		}
 
+		if format != outputTable {
+			items := make([]profileItem, 0, len(profiles))
+			for _, p := range profiles {
+				items = append(items, profileItem{Name: p, Active: p == active})
+			}
+			return writeData(cmd, format, items)
+		}
+
+		out := cmd.OutOrStdout()
 		for _, p := range profiles {
 			if p == active {
-				fmt.Printf("* %s\n", p)
+				fmt.Fprintf(out, "* %s\n", p)
 			} else {
-				fmt.Printf("  %s\n", p)
+				fmt.Fprintf(out, "  %s\n", p)
 			}
 		}
 		return nil
 	},
 }
 
+// profileItem is the machine-readable form of 'keys profile list'.
+type profileItem struct {
+	Name   string `json:"name" yaml:"name"`
+	Active bool   `json:"active" yaml:"active"`
+}
+
 var profileUseCmd = &cobra.Command{
 	Use:   "use <name>",
 	Short: "Switch to a profile",
@@ -61,6 +80,7 @@ var profileUseCmd = &cobra.Command{
 }
 
 func init() {
+	addOutputFlag(profileListCmd)
 	profileCmd.AddCommand(profileListCmd)
 	profileCmd.AddCommand(profileUseCmd)
 	rootCmd.AddCommand(profileCmd)
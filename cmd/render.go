@@ -0,0 +1,125 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"text/template"
+
+	"github.com/stym06/keys/db"
+
+	"github.com/spf13/cobra"
+)
+
+var renderCmd = &cobra.Command{
+	Use:   "render <template>",
+	Short: "Render a config file from a Go template with stored keys",
+	Long: `Render a config file from a Go text/template, filling in stored keys.
+
+Template functions:
+  key "NAME"              value of NAME; fails if it is not stored
+  keyOr "NAME" "default"  value of NAME, or the default if it is not stored
+  profile "p" "NAME"      value of NAME from profile p
+  b64                     base64-encode, e.g. {{ key "TOKEN" | b64 }}
+  json                    encode as a JSON string, e.g. {{ key "PASSWORD" | json }}
+
+Nothing is written if the template fails. Each key read is recorded in the
+audit log with source "render".
+
+Examples:
+  keys render config.yaml.tmpl
+  keys render config.yaml.tmpl -o config.yaml
+  keys render settings.json.tmpl -p prod -o settings.json`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		outFlag, _ := cmd.Flags().GetString("output-file")
+		profileFlag, _ := cmd.Flags().GetString("profile")
+
+		profile := profileFlag
+		if profile == "" {
+			profile = db.GetActiveProfile()
+		}
+
+		src, err := os.ReadFile(args[0])
+		if err != nil {
+			return err
+		}
+		var buf bytes.Buffer
+		if err := renderTemplate(&buf, filepath.Base(args[0]), string(src), profile); err != nil {
+			return err
+		}
+
+		if outFlag == "" {
+			_, err := cmd.OutOrStdout().Write(buf.Bytes())
+			return err
+		}
+		if err := os.WriteFile(outFlag, buf.Bytes(), 0600); err != nil {
+			return err
+		}
+		fmt.Fprintf(cmd.ErrOrStderr(), "Rendered %s to %s\n", args[0], outFlag)
+		return nil
+	},
+}
+
+// renderTemplate executes src with key lookups against defaultProfile.
+// Every key read is audited once per render.
+func renderTemplate(buf *bytes.Buffer, name, src, defaultProfile string) error {
+	audited := make(map[[2]string]bool)
+	lookup := func(profile, key string) (string, bool, error) {
+		keys, err := db.GetKeysByNamesForProfile([]string{key}, profile)
+		if err != nil || len(keys) == 0 {
+			return "", false, err
+		}
+		if id := [2]string{profile, key}; !audited[id] {
+			audited[id] = true
+			_ = db.LogAccessForProfile(profile, key, "get", "render")
+		}
+		return keys[0].Value, true, nil
+	}
+	required := func(profile, key string) (string, error) {
+		value, ok, err := lookup(profile, key)
+		if err != nil {
+			return "", err
+		}
+		if !ok {
+			return "", fmt.Errorf("key %q not found in profile %q", key, profile)
+		}
+		return value, nil
+	}
+
+	funcs := template.FuncMap{
+		"key": func(key string) (string, error) {
+			return required(defaultProfile, key)
+		},
+		"keyOr": func(key, def string) (string, error) {
+			value, ok, err := lookup(defaultProfile, key)
+			if err != nil || !ok {
+				return def, err
+			}
+			return value, nil
+		},
+		"profile": required,
+		"b64": func(s string) string {
+			return base64.StdEncoding.EncodeToString([]byte(s))
+		},
+		"json": func(s string) (string, error) {
+			b, err := json.Marshal(s)
+			return string(b), err
+		},
+	}
+
+	tmpl, err := template.New(name).Funcs(funcs).Option("missingkey=error").Parse(src)
+	if err != nil {
+		return err
+	}
+	return tmpl.Execute(buf, nil)
+}
+
+func init() {
+	renderCmd.Flags().StringP("output-file", "o", "", "write to a file (mode 0600) instead of stdout")
+	renderCmd.Flags().StringP("profile", "p", "", "profile for key and keyOr lookups")
+	rootCmd.AddCommand(renderCmd)
+}
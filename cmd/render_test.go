@@ -0,0 +1,74 @@
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stym06/keys/db"
+)
+
+func writeTemplate(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.tmpl")
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestRenderTemplate(t *testing.T) {
+	setupTestEnv(t)
+	db.AddKey("DB_PASSWORD", `p"ss`)
+	db.AddKey("TOKEN", "abc")
+	db.SetActiveProfile("prod")
+	db.AddKey("TOKEN", "prod-token")
+	db.SetActiveProfile("default")
+
+	path := writeTemplate(t, `password: {{ key "DB_PASSWORD" | json }}
+token: {{ key "TOKEN" | b64 }}
+prod: {{ profile "prod" "TOKEN" }}
+region: {{ keyOr "REGION" "us-east-1" }}
+`)
+
+	buf := new(bytes.Buffer)
+	rootCmd.SetOut(buf)
+	rootCmd.SetArgs([]string{"render", path, "-o", "", "-p", ""})
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := "password: \"p\\\"ss\"\ntoken: YWJj\nprod: prod-token\nregion: us-east-1\n"
+	if buf.String() != want {
+		t.Errorf("expected:\n%s\ngot:\n%s", want, buf.String())
+	}
+
+	entries, _ := db.GetAuditLog(10)
+	var renders int
+	for _, e := range entries {
+		if e.Source == "render" {
+			renders++
+		}
+	}
+	// The prod read is audited under the prod profile
+	if renders != 2 {
+		t.Errorf("expected 2 audited reads, got %d", renders)
+	}
+}
+
+func TestRenderMissingKey(t *testing.T) {
+	setupTestEnv(t)
+	path := writeTemplate(t, `token: {{ key "MISSING" }}`)
+	out := filepath.Join(t.TempDir(), "config.yaml")
+
+	rootCmd.SetArgs([]string{"render", path, "-o", out, "-p", ""})
+	err := rootCmd.Execute()
+	if err == nil || !strings.Contains(err.Error(), `key "MISSING" not found`) {
+		t.Fatalf("expected missing key error, got %v", err)
+	}
+	if _, err := os.Stat(out); !os.IsNotExist(err) {
+		t.Error("output file should not be written on failure")
+	}
+}
@@ -1,7 +1,9 @@
 package cmd
 
 import (
+	"bufio"
 	"fmt"
+	"strings"
 
 	"github.com/stym06/keys/db"
 
@@ -9,20 +11,76 @@ import (
 )
 
 var rmCmd = &cobra.Command{
-	Use:               "rm <name>",
-	Short:             "Delete a stored key",
-	Args:              cobra.ExactArgs(1),
-	ValidArgsFunction: completeKeyNames,
+	Use:   "rm <name or pattern>...",
+	Short: "Delete stored keys",
+	Long: `Delete keys from the active profile.
+
+Keys can be named exactly or with globs such as 'OLD_*'. --match selects by
+regular expression and --exclude leaves out keys matching a glob. When more
+than one key could be affected, the matched keys are listed and you are asked
+to confirm; --yes skips the prompt.
+
+Examples:
+  keys rm OLD_TOKEN
+  keys rm 'OLD_*' --yes
+  keys rm --match '^LEGACY_' --exclude '*_PROD'`,
+	ValidArgsFunction: completeKeyNamesMulti,
 	RunE: func(cmd *cobra.Command, args []string) error {
-		name := args[0]
-		if err := db.DeleteKey(name); err != nil {
+		yes, _ := cmd.Flags().GetBool("yes")
+		out := cmd.OutOrStdout()
+		patterns := usesPatterns(cmd, args)
+		if !selectsKeys(cmd, args) {
+			return fmt.Errorf("specify key names or patterns")
+		}
+
+		// A single exact name is deleted straight away, as it always was
+		if len(args) == 1 && !patterns {
+			if err := db.DeleteKey(args[0]); err != nil {
+				return err
+			}
+			fmt.Fprintf(out, "Deleted %s\n", args[0])
+			return nil
+		}
+
+		all, err := db.GetAllKeys()
+		if err != nil {
 			return err
 		}
-		fmt.Printf("Deleted %s\n", name)
+		keys, err := selectKeys(cmd, all, args, false)
+		if err != nil {
+			return err
+		}
+		if len(keys) == 0 {
+			fmt.Fprintln(out, "No keys matched.")
+			return nil
+		}
+
+		if !yes {
+			fmt.Fprintf(out, "This will delete %d key(s) from profile %q:\n", len(keys), db.GetActiveProfile())
+			for _, k := range keys {
+				fmt.Fprintf(out, "  %s\n", k.Name)
+			}
+			fmt.Fprint(out, "Delete? [y/N]: ")
+			input, _ := bufio.NewReader(cmd.InOrStdin()).ReadString('\n')
+			input = strings.TrimSpace(strings.ToLower(input))
+			if input != "y" && input != "yes" {
+				fmt.Fprintln(out, "Cancelled.")
+				return nil
+			}
+		}
+
+		for _, k := range keys {
+			if err := db.DeleteKey(k.Name); err != nil {
+				return err
+			}
+			fmt.Fprintf(out, "Deleted %s\n", k.Name)
+		}
 		return nil
 	},
 }
 
 func init() {
+	rmCmd.Flags().BoolP("yes", "y", false, "delete without asking for confirmation")
+	addSelectFlags(rmCmd)
 	rootCmd.AddCommand(rmCmd)
 }
@@ -1,19 +1,45 @@
 package cmd
 
 import (
+	"errors"
+	"fmt"
 	"os"
+	"path/filepath"
+	"strings"
 
 	"github.com/stym06/keys/db"
 
 	"github.com/spf13/cobra"
 )
 
-// commands that don't need Touch ID
+// commands that don't need Touch ID, by top-level name or full path
 var noAuthCommands = map[string]bool{
 	"profile":    true,
 	"completion": true,
 	"help":       true,
 	"version":    true,
+
+	// the git hook only reads value fingerprints
+	"hook install":    true,
+	"hook uninstall":  true,
+	"hook pre-commit": true,
+
+	// shell hook setup; 'hook export' authenticates only when it loads keys
+	"hook bash":   true,
+	"hook zsh":    true,
+	"hook fish":   true,
+	"hook export": true,
+	"hook deny":   true,
+
+	// never sees the value, only its hash
+	"clipboard-clear": true,
+}
+
+// helperBinaries maps the names keys can be installed under to the command
+// they run.
+var helperBinaries = map[string]string{
+	gitCredentialBinary:    "git-credential",
+	dockerCredentialBinary: "docker-credential",
 }
 
 var rootCmd = &cobra.Command{
@@ -22,13 +48,27 @@ var rootCmd = &cobra.Command{
 	Version: Version,
 }
 
+// exitCode is returned by commands that have already reported their
+// outcome and only need the process to exit with a given status.
+type exitCode int
+
+func (e exitCode) Error() string {
+	return fmt.Sprintf("exit status %d", int(e))
+}
+
+// exitWith makes cmd fail with the given exit status without printing an
+// error or usage.
+func exitWith(cmd *cobra.Command, code int) error {
+	cmd.SilenceErrors = true
+	cmd.SilenceUsage = true
+	return exitCode(code)
+}
+
 func init() {
 	rootCmd.PersistentPreRunE = func(cmd *cobra.Command, args []string) error {
-		name := cmd.Name()
-		if p := cmd.Parent(); p != nil && p != rootCmd {
-			name = p.Name()
-		}
-		if noAuthCommands[name] {
+		path := strings.TrimPrefix(cmd.CommandPath(), rootCmd.Name()+" ")
+		top, _, _ := strings.Cut(path, " ")
+		if noAuthCommands[top] || noAuthCommands[path] {
 			return nil
 		}
 		return db.Authenticate()
@@ -36,7 +76,15 @@ func init() {
 }
 
 func Execute() {
+	// Installed under a credential helper's name, git or docker runs us as one
+	if helper, ok := helperBinaries[filepath.Base(os.Args[0])]; ok {
+		rootCmd.SetArgs(append([]string{helper}, os.Args[1:]...))
+	}
 	if err := rootCmd.Execute(); err != nil {
+		var code exitCode
+		if errors.As(err, &code) {
+			os.Exit(int(code))
+		}
 		os.Exit(1)
 	}
 }
@@ -0,0 +1,45 @@
+package cmd
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// runCmd runs keys with args and returns what the command wrote to stdout.
+func runCmd(t *testing.T, args ...string) (string, error) {
+	t.Helper()
+	return runCmdWithInput(t, "", args...)
+}
+
+// runCmdWithInput is runCmd with stdin as the command's input. Flag values
+// persist on the shared command tree, so the flags of the command that ran
+// are reset to their defaults afterwards.
+func runCmdWithInput(t *testing.T, stdin string, args ...string) (string, error) {
+	t.Helper()
+	buf := new(bytes.Buffer)
+	rootCmd.SetOut(buf)
+	rootCmd.SetIn(strings.NewReader(stdin))
+	defer func() {
+		rootCmd.SetOut(nil)
+		rootCmd.SetIn(nil)
+	}()
+	rootCmd.SetArgs(args)
+	c, err := rootCmd.ExecuteC()
+	resetFlags(c)
+	return buf.String(), err
+}
+
+func resetFlags(c *cobra.Command) {
+	c.Flags().VisitAll(func(f *pflag.Flag) {
+		if v, ok := f.Value.(pflag.SliceValue); ok {
+			v.Replace(nil)
+		} else {
+			f.Value.Set(f.DefValue)
+		}
+		f.Changed = false
+	})
+}
@@ -0,0 +1,213 @@
+package cmd
+
+import (
+	"bufio"
+	"crypto/rand"
+	"fmt"
+	"math/big"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/stym06/keys/db"
+	"github.com/stym06/keys/schema"
+
+	"github.com/spf13/cobra"
+)
+
+// metaRotateEvery holds a key's rotation interval, e.g. "90d".
+const metaRotateEvery = "rotate_every"
+
+var rotateCmd = &cobra.Command{
+	Use:   "rotate <name> [new-value]",
+	Short: "Replace a key's value, keeping the old one for a grace period",
+	Long: `Replace a key's value with a new one, given as an argument, generated with
+--generate, or typed at the prompt without echo.
+
+The old value stays available with 'keys get <name> --previous' until the
+grace period ends, so running services can be moved over first.
+
+--every records how often the key should be rotated; with no new value it
+only sets the interval. 'keys rotate --due' lists keys past their interval,
+also as --output json or yaml.
+
+Examples:
+  keys rotate STRIPE_KEY sk_live_new...
+  keys rotate WEBHOOK_SECRET --generate --grace 7d
+  keys rotate DB_PASSWORD --every 90d
+  keys rotate --due
+  keys rotate --due --output json`,
+	Args:              cobra.RangeArgs(0, 2),
+	ValidArgsFunction: completeKeyNames,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		due, _ := cmd.Flags().GetBool("due")
+		generate, _ := cmd.Flags().GetBool("generate")
+		length, _ := cmd.Flags().GetInt("length")
+		graceFlag, _ := cmd.Flags().GetString("grace")
+		every, _ := cmd.Flags().GetString("every")
+		format, err := outputFormat(cmd)
+		if err != nil {
+			return err
+		}
+
+		if due {
+			if len(args) > 0 {
+				return fmt.Errorf("--due does not take a key name")
+			}
+			return listDueRotations(cmd, format)
+		}
+		if format != outputTable {
+			return fmt.Errorf("--output is only supported with --due")
+		}
+		if len(args) == 0 {
+			return fmt.Errorf("requires a key name, or --due")
+		}
+		name := args[0]
+
+		key, err := db.GetKey(name)
+		if err != nil {
+			return err
+		}
+		grace, err := schema.ParseDuration(graceFlag)
+		if err != nil {
+			return fmt.Errorf("--grace: %w", err)
+		}
+		if every != "" {
+			if _, err := schema.ParseDuration(every); err != nil {
+				return fmt.Errorf("--every: %w", err)
+			}
+			if err := db.SetKeyMeta(name, metaRotateEvery, every); err != nil {
+				return err
+			}
+			if len(args) == 1 && !generate {
+				fmt.Fprintf(cmd.OutOrStdout(), "%s is due for rotation every %s\n", name, every)
+				return nil
+			}
+		}
+
+		var value string
+		switch {
+		case len(args) == 2 && generate:
+			return fmt.Errorf("give a new value or --generate, not both")
+		case len(args) == 2:
+			value = args[1]
+		case generate:
+			if value, err = generateValue(length); err != nil {
+				return err
+			}
+		case stdinIsTerminal():
+			if value, err = promptSecret(cmd, fmt.Sprintf("New value for %s: ", name), false); err != nil {
+				return err
+			}
+			if value == "" {
+				fmt.Fprintln(cmd.ErrOrStderr(), "Cancelled.")
+				return nil
+			}
+		default:
+			fmt.Fprintf(cmd.ErrOrStderr(), "New value for %s: ", name)
+			input, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+			value = strings.TrimSpace(input)
+			if value == "" {
+				fmt.Fprintln(cmd.ErrOrStderr(), "Cancelled.")
+				return nil
+			}
+		}
+
+		if err := db.RotateKey(key.Name, value, grace); err != nil {
+			return err
+		}
+		_ = db.LogAccess(key.Name, "rotate", "cli")
+		if err := tagProvider(cmd.ErrOrStderr(), key.Name, value); err != nil {
+			return err
+		}
+
+		out := cmd.OutOrStdout()
+		fmt.Fprintf(out, "Rotated %s\n", key.Name)
+		if grace > 0 {
+			until := time.Now().Add(grace).Format("2006-01-02 15:04")
+			fmt.Fprintf(out, "Previous value available with 'keys get %s --previous' until %s\n", key.Name, until)
+		}
+		return nil
+	},
+}
+
+// dueRotationItem is the machine-readable form of 'keys rotate --due'.
+type dueRotationItem struct {
+	Name      string     `json:"name" yaml:"name"`
+	Profile   string     `json:"profile" yaml:"profile"`
+	Every     string     `json:"every" yaml:"every"`
+	RotatedAt *time.Time `json:"rotated_at" yaml:"rotated_at"`
+}
+
+func listDueRotations(cmd *cobra.Command, format string) error {
+	keys, err := db.GetAllKeys()
+	if err != nil {
+		return err
+	}
+
+	out := cmd.OutOrStdout()
+	profile := db.GetActiveProfile()
+	now := time.Now()
+	var due int
+	items := []dueRotationItem{}
+	for _, k := range keys {
+		meta, err := db.GetKeyMeta(k.Name)
+		if err != nil {
+			return err
+		}
+		every := meta[metaRotateEvery]
+		if every == "" {
+			continue
+		}
+		interval, err := schema.ParseDuration(every)
+		if err != nil || interval == 0 {
+			continue
+		}
+		age := now.Sub(time.Unix(k.LastRotated(), 0))
+		if age < interval {
+			continue
+		}
+		due++
+		if format != outputTable {
+			items = append(items, dueRotationItem{Name: k.Name, Profile: profile, Every: every, RotatedAt: timestamp(k.LastRotated())})
+			continue
+		}
+		fmt.Fprintf(out, "%s — last rotated %d days ago, every %s\n", k.Name, int(age.Hours()/24), every)
+	}
+	if format != outputTable {
+		return writeData(cmd, format, items)
+	}
+	if due == 0 {
+		fmt.Fprintln(out, "No keys are due for rotation.")
+	}
+	return nil
+}
+
+const generateAlphabet = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789"
+
+// generateValue returns a random alphanumeric string of length n.
+func generateValue(n int) (string, error) {
+	if n <= 0 {
+		return "", fmt.Errorf("--length must be positive")
+	}
+	max := big.NewInt(int64(len(generateAlphabet)))
+	b := make([]byte, n)
+	for i := range b {
+		j, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+		b[i] = generateAlphabet[j.Int64()]
+	}
+	return string(b), nil
+}
+
+func init() {
+	rotateCmd.Flags().Bool("generate", false, "generate a random value")
+	rotateCmd.Flags().Int("length", 32, "length of a generated value")
+	rotateCmd.Flags().String("grace", "24h", "how long the previous value stays available (0 to drop it)")
+	rotateCmd.Flags().String("every", "", "rotation interval for the key, e.g. 90d")
+	rotateCmd.Flags().Bool("due", false, "list keys past their rotation interval")
+	addOutputFlag(rotateCmd)
+	rootCmd.AddCommand(rotateCmd)
+}
@@ -0,0 +1,68 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stym06/keys/db"
+)
+
+func TestRotateAndGetPrevious(t *testing.T) {
+	setupTestEnv(t)
+	db.AddKey("API_KEY", "old-secret")
+
+	out, err := runCmd(t, "rotate", "API_KEY", "new-secret", "--grace", "1d")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(out, "Rotated API_KEY") {
+		t.Errorf("unexpected output:\n%s", out)
+	}
+
+	out, err = runCmd(t, "get", "API_KEY", "--previous")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if out != "old-secret\n" {
+		t.Errorf("expected previous value, got %q", out)
+	}
+
+	k, _ := db.GetKey("API_KEY")
+	if k.Value != "new-secret" {
+		t.Errorf("expected new value, got %q", k.Value)
+	}
+}
+
+func TestRotateGenerate(t *testing.T) {
+	setupTestEnv(t)
+	db.AddKey("WEBHOOK_SECRET", "old-secret")
+
+	if _, err := runCmd(t, "rotate", "WEBHOOK_SECRET", "--generate", "--length", "40"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	k, _ := db.GetKey("WEBHOOK_SECRET")
+	if len(k.Value) != 40 || k.Value == "old-secret" {
+		t.Errorf("expected a generated 40 character value, got %q", k.Value)
+	}
+}
+
+func TestRotateDue(t *testing.T) {
+	setupTestEnv(t)
+	db.AddKey("FRESH", "a")
+	db.AddKey("NO_INTERVAL", "b")
+	db.SetKeyMeta("FRESH", metaRotateEvery, "90d")
+	db.AddKey("STALE", "c")
+	db.SetKeyMeta("STALE", metaRotateEvery, "1ns") // any age is past due
+
+	out, err := runCmd(t, "rotate", "--due")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(out, "STALE — last rotated 0 days ago, every 1ns") {
+		t.Errorf("expected STALE to be due:\n%s", out)
+	}
+	if strings.Contains(out, "FRESH") || strings.Contains(out, "NO_INTERVAL") {
+		t.Errorf("only STALE should be due:\n%s", out)
+	}
+}
@@ -0,0 +1,192 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/stym06/keys/db"
+	"github.com/stym06/keys/leak"
+
+	"github.com/spf13/cobra"
+)
+
+var scanCmd = &cobra.Command{
+	Use:   "scan [paths...]",
+	Short: "Find leaked copies of stored keys in files and git history",
+	Long: `Search files for values stored in the vault, including their base64 and
+URL-encoded forms. Findings name the file, line and key — never the value.
+
+Scans the current directory by default, and every profile unless --profile
+is given. With --git, lines added anywhere in the current repository's
+history (git log -p --all) are scanned too; --git=PATH scans another
+repository's history. The "=" is required, since a separate word is read
+as a path to scan. Known false positives can be listed in
+a .keys-allowlist file (see 'keys hook install --help').
+
+Exits with code 1 if any stored value is found.
+
+Examples:
+  keys scan
+  keys scan src config --profile prod
+  keys scan --git
+  keys scan --git=../other-repo
+  keys scan --output json`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		profilesFlag, _ := cmd.Flags().GetStringSlice("profile")
+		gitFlag, _ := cmd.Flags().GetString("git")
+		minLength, _ := cmd.Flags().GetInt("min-length")
+		allowFlag, _ := cmd.Flags().GetString("allowlist")
+		format, err := outputFormat(cmd)
+		if err != nil {
+			return err
+		}
+
+		paths := args
+		if len(paths) == 0 {
+			paths = []string{"."}
+		}
+
+		secrets, err := vaultSecrets(profilesFlag)
+		if err != nil {
+			return err
+		}
+		scanner := leak.NewScanner(secrets, minLength)
+		if scanner.Empty() {
+			if format != outputTable {
+				return writeData(cmd, format, scanReport(nil))
+			}
+			fmt.Fprintln(cmd.OutOrStdout(), "No stored values to scan for.")
+			return nil
+		}
+
+		findings, err := scanner.ScanPaths(paths)
+		if err != nil {
+			return err
+		}
+		if gitFlag != "" {
+			history, err := leak.ScanGitHistory(gitFlag, scanner)
+			if err != nil {
+				return err
+			}
+			findings = append(findings, history...)
+		}
+
+		allow, err := loadAllowlist(allowFlag)
+		if err != nil {
+			return err
+		}
+		findings = allow.Filter(findings)
+
+		if format != outputTable {
+			if err := writeData(cmd, format, scanReport(findings)); err != nil {
+				return err
+			}
+		} else {
+			printFindings(cmd, findings)
+		}
+		if len(findings) > 0 {
+			return exitWith(cmd, 1)
+		}
+		return nil
+	},
+}
+
+// scanFindingJSON and scanJSON are the machine-readable form of 'keys scan'.
+type scanFindingJSON struct {
+	File     string `json:"file" yaml:"file"`
+	Commit   string `json:"commit,omitempty" yaml:"commit,omitempty"`
+	Line     int    `json:"line" yaml:"line"`
+	Profile  string `json:"profile" yaml:"profile"`
+	Key      string `json:"key" yaml:"key"`
+	Encoding string `json:"encoding" yaml:"encoding"`
+}
+
+type scanJSON struct {
+	OK       bool              `json:"ok" yaml:"ok"`
+	Findings []scanFindingJSON `json:"findings" yaml:"findings"`
+}
+
+func scanReport(findings []leak.Finding) scanJSON {
+	report := scanJSON{OK: len(findings) == 0, Findings: []scanFindingJSON{}}
+	for _, f := range findings {
+		report.Findings = append(report.Findings, scanFindingJSON{
+			File:     f.File,
+			Commit:   f.Commit,
+			Line:     f.Line,
+			Profile:  f.Profile,
+			Key:      f.Key,
+			Encoding: f.Encoding,
+		})
+	}
+	return report
+}
+
+// vaultSecrets returns the stored values of the given profiles, or of every
+// profile if none are given.
+func vaultSecrets(profiles []string) ([]leak.Secret, error) {
+	if len(profiles) == 0 {
+		var err error
+		if profiles, err = db.ListProfiles(); err != nil {
+			return nil, err
+		}
+	}
+	var secrets []leak.Secret
+	for _, p := range profiles {
+		keys, err := db.GetAllKeysForProfile(p)
+		if err != nil {
+			return nil, err
+		}
+		for _, k := range keys {
+			secrets = append(secrets, leak.Secret{Profile: p, Name: k.Name, Value: k.Value})
+		}
+	}
+	return secrets, nil
+}
+
+// loadAllowlist reads an allowlist file. A missing file means an empty
+// allowlist.
+func loadAllowlist(path string) (*leak.Allowlist, error) {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return leak.ParseAllowlist(f)
+}
+
+func printFindings(cmd *cobra.Command, findings []leak.Finding) {
+	out := cmd.OutOrStdout()
+	if len(findings) == 0 {
+		fmt.Fprintln(out, "No stored keys found.")
+		return
+	}
+
+	files := make(map[string]bool)
+	for _, f := range findings {
+		files[f.Commit+f.File] = true
+		detail := f.Profile
+		if f.Encoding != leak.EncodingRaw {
+			detail += ", " + f.Encoding
+		}
+		fmt.Fprintf(out, "%s  %s (%s)\n", f.Location(), f.Key, detail)
+	}
+
+	label := "files"
+	if len(files) == 1 {
+		label = "file"
+	}
+	fmt.Fprintf(out, "\nFound %d leaked value(s) in %d %s.\n", len(findings), len(files), label)
+}
+
+func init() {
+	scanCmd.Flags().StringSliceP("profile", "p", nil, "only scan for keys in these profiles (default: all)")
+	scanCmd.Flags().String("git", "", "also scan the git history of the current repository, or of another with --git=PATH")
+	scanCmd.Flags().Lookup("git").NoOptDefVal = "."
+	scanCmd.Flags().Int("min-length", leak.DefaultMinLength, "ignore stored values shorter than this")
+	scanCmd.Flags().String("allowlist", leak.AllowlistFile, "file listing allowed findings")
+	addOutputFlag(scanCmd)
+	rootCmd.AddCommand(scanCmd)
+}
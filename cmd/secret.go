@@ -0,0 +1,60 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/charmbracelet/x/term"
+	"github.com/spf13/cobra"
+)
+
+// stdinIsTerminal reports whether values can be prompted for without echo.
+// Tests replace it.
+var stdinIsTerminal = func() bool {
+	return term.IsTerminal(os.Stdin.Fd())
+}
+
+// readHidden reads a line from the terminal without echoing it. Tests
+// replace it.
+var readHidden = func() (string, error) {
+	b, err := term.ReadPassword(os.Stdin.Fd())
+	return string(b), err
+}
+
+// promptSecret asks for a value on stderr without echoing what is typed,
+// and, when confirm is set, asks for it a second time.
+func promptSecret(cmd *cobra.Command, prompt string, confirm bool) (string, error) {
+	errOut := cmd.ErrOrStderr()
+	fmt.Fprint(errOut, prompt)
+	value, err := readHidden()
+	fmt.Fprintln(errOut)
+	if err != nil {
+		return "", err
+	}
+	if confirm && value != "" {
+		fmt.Fprint(errOut, "Confirm: ")
+		again, err := readHidden()
+		fmt.Fprintln(errOut)
+		if err != nil {
+			return "", err
+		}
+		if again != value {
+			return "", fmt.Errorf("values do not match")
+		}
+	}
+	return value, nil
+}
+
+// readStdinValue reads a value piped on stdin, dropping one trailing
+// newline so `echo value | keys add NAME -` stores just the value.
+func readStdinValue(cmd *cobra.Command) (string, error) {
+	b, err := io.ReadAll(bufio.NewReader(cmd.InOrStdin()))
+	if err != nil {
+		return "", err
+	}
+	value := strings.TrimSuffix(string(b), "\n")
+	return strings.TrimSuffix(value, "\r"), nil
+}
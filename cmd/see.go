@@ -21,7 +21,7 @@ var seeCmd = &cobra.Command{
 			return err
 		}
 
-		m := tui.NewSee(keys)
+		m := tui.NewSee(keys).WithCopy(tuiCopy)
 		p := tea.NewProgram(m)
 		result, err := p.Run()
 		if err != nil {
@@ -51,6 +51,11 @@ var seeCmd = &cobra.Command{
 			}
 		}
 
+		// Handle ctrl+t send to profile
+		if final.SendProfile() {
+			return sendToProfile(final.SendKeys())
+		}
+
 		return nil
 	},
 }
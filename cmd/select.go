@@ -0,0 +1,178 @@
+package cmd
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/stym06/keys/db"
+
+	"github.com/spf13/cobra"
+)
+
+// keySelector matches key names against a glob such as "STRIPE_*", or a
+// regular expression when regex is set.
+type keySelector struct {
+	glob string
+	re   *regexp.Regexp
+}
+
+func newKeySelector(pattern string, regex bool) (*keySelector, error) {
+	if regex {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid regular expression %q: %w", pattern, err)
+		}
+		return &keySelector{re: re}, nil
+	}
+	if _, err := path.Match(pattern, ""); err != nil {
+		return nil, fmt.Errorf("invalid pattern %q", pattern)
+	}
+	return &keySelector{glob: pattern}, nil
+}
+
+func (s *keySelector) String() string {
+	if s.re != nil {
+		return s.re.String()
+	}
+	return s.glob
+}
+
+// Match reports whether name is selected. A nil selector selects every key.
+func (s *keySelector) Match(name string) bool {
+	if s == nil {
+		return true
+	}
+	if s.re != nil {
+		return s.re.MatchString(name)
+	}
+	ok, _ := path.Match(s.glob, name)
+	return ok
+}
+
+// Filter returns the keys whose names are selected.
+func (s *keySelector) Filter(keys []db.Key) []db.Key {
+	var out []db.Key
+	for _, k := range keys {
+		if s.Match(k.Name) {
+			out = append(out, k)
+		}
+	}
+	return out
+}
+
+// addSelectFlags registers --match and --exclude on a command that takes
+// key names.
+func addSelectFlags(c *cobra.Command) {
+	c.Flags().StringArray("match", nil, "select keys whose names match a regular expression (repeatable)")
+	c.Flags().StringArray("exclude", nil, "leave out keys whose names match a glob (repeatable)")
+}
+
+// isPattern reports whether a key name argument is a glob rather than an
+// exact name.
+func isPattern(arg string) bool {
+	return strings.ContainsAny(arg, "*?[")
+}
+
+// usesPatterns reports whether args or the --match/--exclude flags select
+// keys by pattern rather than by exact name.
+func usesPatterns(cmd *cobra.Command, args []string) bool {
+	match, _ := cmd.Flags().GetStringArray("match")
+	exclude, _ := cmd.Flags().GetStringArray("exclude")
+	if len(match) > 0 || len(exclude) > 0 {
+		return true
+	}
+	for _, a := range args {
+		if isPattern(a) {
+			return true
+		}
+	}
+	return false
+}
+
+// selectsKeys reports whether args or --match pick keys. --exclude on its
+// own only narrows a selection and never selects anything.
+func selectsKeys(cmd *cobra.Command, args []string) bool {
+	match, _ := cmd.Flags().GetStringArray("match")
+	return len(args) > 0 || len(match) > 0
+}
+
+// selectKeys picks keys by the names in args, each an exact name or a glob
+// such as 'AWS_*', and by --match regular expressions, then drops those
+// matching an --exclude glob. With no args and no --match every key is
+// selected if all is set, and otherwise it is an error. Unknown names and
+// patterns that select nothing are errors too.
+func selectKeys(cmd *cobra.Command, keys []db.Key, args []string, all bool) ([]db.Key, error) {
+	match, _ := cmd.Flags().GetStringArray("match")
+	exclude, _ := cmd.Flags().GetStringArray("exclude")
+	if !all && !selectsKeys(cmd, args) {
+		if len(exclude) > 0 {
+			return nil, fmt.Errorf("--exclude needs key names, patterns or --match to select from")
+		}
+		return nil, fmt.Errorf("specify key names or patterns")
+	}
+
+	var include []*keySelector
+	for _, a := range args {
+		if !isPattern(a) {
+			found := false
+			for _, k := range keys {
+				if k.Name == a {
+					found = true
+					break
+				}
+			}
+			if !found {
+				return nil, fmt.Errorf("key %q not found", a)
+			}
+		}
+		sel, err := newKeySelector(a, false)
+		if err != nil {
+			return nil, err
+		}
+		include = append(include, sel)
+	}
+	for _, m := range match {
+		sel, err := newKeySelector(m, true)
+		if err != nil {
+			return nil, err
+		}
+		include = append(include, sel)
+	}
+	var drop []*keySelector
+	for _, e := range exclude {
+		sel, err := newKeySelector(e, false)
+		if err != nil {
+			return nil, err
+		}
+		drop = append(drop, sel)
+	}
+
+	for _, sel := range include {
+		if len(sel.Filter(keys)) == 0 {
+			return nil, fmt.Errorf("no keys match %q", sel)
+		}
+	}
+
+	var out []db.Key
+	for _, k := range keys {
+		selected := len(include) == 0
+		for _, sel := range include {
+			if sel.Match(k.Name) {
+				selected = true
+				break
+			}
+		}
+		for _, sel := range drop {
+			if sel.Match(k.Name) {
+				selected = false
+				break
+			}
+		}
+		if selected {
+			out = append(out, k)
+		}
+	}
+	return out, nil
+}
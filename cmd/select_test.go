@@ -0,0 +1,111 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stym06/keys/db"
+)
+
+func addSelectKeys() {
+	db.AddKey("AWS_ACCESS_KEY_ID", "AKIA1")
+	db.AddKey("AWS_SECRET_PROD", "prod-secret")
+	db.AddKey("STRIPE_KEY", "sk_test_1")
+	db.AddKey("STRIPE_KEY_PROD", "sk_live_1")
+}
+
+func TestInjectAndExposeSelectors(t *testing.T) {
+	setupTestEnv(t)
+	addSelectKeys()
+
+	out, err := runCmdWithInput(t, "", "inject", "AWS_*", "--exclude", "*_PROD")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if out != "AWS_ACCESS_KEY_ID=AKIA1" {
+		t.Errorf("unexpected inject output %q", out)
+	}
+
+	out, err = runCmdWithInput(t, "", "expose", "--match", "^STRIPE_", "--exclude", "*_PROD")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if out != "export STRIPE_KEY=sk_test_1\n" {
+		t.Errorf("unexpected expose output %q", out)
+	}
+
+	out, err = runCmdWithInput(t, "", "get", "STRIPE_*")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if out != "STRIPE_KEY=sk_test_1\nSTRIPE_KEY_PROD=sk_live_1\n" {
+		t.Errorf("unexpected get output %q", out)
+	}
+}
+
+func TestSelectorErrors(t *testing.T) {
+	setupTestEnv(t)
+	addSelectKeys()
+
+	for _, args := range [][]string{
+		{"inject", "GCP_*"},
+		{"inject", "MISSING"},
+		{"expose", "--match", "("},
+		{"export", "--match", "^NOPE"},
+	} {
+		if _, err := runCmdWithInput(t, "", args...); err == nil {
+			t.Errorf("%v: expected an error", args)
+		}
+	}
+}
+
+func TestRmPatternConfirms(t *testing.T) {
+	setupTestEnv(t)
+	addSelectKeys()
+
+	out, err := runCmdWithInput(t, "n\n", "rm", "*_PROD")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(out, "This will delete 2 key(s)") || !strings.Contains(out, "  STRIPE_KEY_PROD\n") || !strings.Contains(out, "Cancelled.") {
+		t.Errorf("expected a preview and cancel, got:\n%s", out)
+	}
+	if keys, _ := db.GetAllKeys(); len(keys) != 4 {
+		t.Errorf("nothing should be deleted after cancel, have %d keys", len(keys))
+	}
+
+	if _, err := runCmdWithInput(t, "y\n", "rm", "*_PROD"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if keys, _ := db.GetAllKeys(); len(keys) != 2 {
+		t.Errorf("expected 2 keys left, have %d", len(keys))
+	}
+
+	out, err = runCmdWithInput(t, "", "rm", "--match", "^AWS_", "--yes")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if strings.Contains(out, "Delete?") || !strings.Contains(out, "Deleted AWS_ACCESS_KEY_ID") {
+		t.Errorf("--yes should skip the prompt, got:\n%s", out)
+	}
+}
+
+func TestExcludeAloneSelectsNothing(t *testing.T) {
+	setupTestEnv(t)
+	addSelectKeys()
+
+	if _, err := runCmdWithInput(t, "", "rm", "--exclude", "*_PROD", "--yes"); err == nil {
+		t.Error("expected rm with only --exclude to fail")
+	}
+	if keys, _ := db.GetAllKeys(); len(keys) != 4 {
+		t.Errorf("expected no keys deleted, %d left", len(keys))
+	}
+
+	if out, err := runCmdWithInput(t, "", "inject", "--exclude", "*_PROD"); err == nil {
+		t.Errorf("expected inject with only --exclude to fail, got %q", out)
+	}
+	out, err := runCmdWithInput(t, "", "inject", "--all", "--exclude", "*_PROD")
+	if err != nil || strings.Contains(out, "_PROD") || !strings.Contains(out, "STRIPE_KEY=") {
+		t.Errorf("expected --all --exclude to inject the rest, got %q, %v", out, err)
+	}
+}
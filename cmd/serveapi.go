@@ -0,0 +1,134 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"github.com/stym06/keys/api"
+
+	"github.com/spf13/cobra"
+)
+
+var serveAPICmd = &cobra.Command{
+	Use:   "serve-api",
+	Short: "Serve keys to local applications over a Unix socket",
+	Long: `Serve a small REST API that local applications can fetch keys from while
+they run, instead of having them baked into their environment at startup.
+
+The API listens on a Unix socket readable only by you (default
+~/.keys/api.sock), or on a loopback address with --addr. Every request
+needs a token from 'keys api-token create', is limited to the token's
+profiles and key patterns, and is recorded in the audit log with source
+"api:<client>". Revoking a token takes effect immediately.
+
+  GET /v1/keys?profile=P                 names the token may read
+  GET /v1/keys/NAME?profile=P            one value
+  GET /v1/bundle?profile=P&name=A&name=B several values at once
+
+Examples:
+  keys serve-api
+  curl --unix-socket ~/.keys/api.sock -H "Authorization: Bearer $TOKEN" http://keys/v1/keys/OPENAI_KEY`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		socket, _ := cmd.Flags().GetString("socket")
+		addr, _ := cmd.Flags().GetString("addr")
+
+		var listener net.Listener
+		var where string
+		var err error
+		if addr != "" {
+			if listener, err = listenLoopback(addr); err != nil {
+				return err
+			}
+			where = "http://" + listener.Addr().String()
+		} else {
+			if socket == "" {
+				if socket, err = api.DefaultSocket(); err != nil {
+					return err
+				}
+			}
+			if listener, err = listenSocket(socket); err != nil {
+				return err
+			}
+			defer os.Remove(socket)
+			where = socket
+		}
+
+		server := &http.Server{Handler: api.NewHandler(cmd.ErrOrStderr())}
+		errCh := make(chan error, 1)
+		go func() { errCh <- server.Serve(listener) }()
+
+		out := cmd.OutOrStdout()
+		fmt.Fprintf(out, "Serving the keys API on %s\n", where)
+		fmt.Fprintln(out, "Create client tokens with 'keys api-token create <client>'. Ctrl+C to stop.")
+
+		sigCh := make(chan os.Signal, 1)
+		signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
+		select {
+		case err := <-errCh:
+			return err
+		case <-sigCh:
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		fmt.Fprintln(out, "\nStopped.")
+		return server.Shutdown(ctx)
+	},
+}
+
+// listenSocket listens on a Unix socket only the current user can use,
+// replacing a socket left behind by a server that is no longer running.
+func listenSocket(path string) (net.Listener, error) {
+	if _, err := os.Stat(path); err == nil {
+		if c, err := net.Dial("unix", path); err == nil {
+			c.Close()
+			return nil, fmt.Errorf("the keys API is already being served on %s", path)
+		}
+		if err := os.Remove(path); err != nil {
+			return nil, err
+		}
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, err
+	}
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.Chmod(path, 0600); err != nil {
+		l.Close()
+		return nil, err
+	}
+	return l, nil
+}
+
+// listenLoopback listens on addr, which must be a loopback address: the
+// API is for applications on this machine only.
+func listenLoopback(addr string) (net.Listener, error) {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+	if host == "localhost" {
+		host = "127.0.0.1"
+		_, port, _ := net.SplitHostPort(addr)
+		addr = net.JoinHostPort(host, port)
+	}
+	if ip := net.ParseIP(host); ip == nil || !ip.IsLoopback() {
+		return nil, fmt.Errorf("--addr must be a loopback address such as 127.0.0.1:7878")
+	}
+	return net.Listen("tcp", addr)
+}
+
+func init() {
+	serveAPICmd.Flags().String("socket", "", "Unix socket to listen on (default $KEYS_API_SOCKET or ~/.keys/api.sock)")
+	serveAPICmd.Flags().String("addr", "", "listen on a loopback TCP address instead, e.g. 127.0.0.1:7878")
+	rootCmd.AddCommand(serveAPICmd)
+}
@@ -0,0 +1,467 @@
+package cmd
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/stym06/keys/db"
+
+	"github.com/spf13/cobra"
+)
+
+// manifestFile lists the keys a project wants in its shell environment.
+const manifestFile = ".keys"
+
+// Environment variables the shell hook uses to remember what it loaded.
+const (
+	envLoadedFrom = "KEYS_LOADED_FROM" // "<hash> <manifest path>"
+	envLoadedKeys = "KEYS_LOADED_KEYS" // comma-separated names
+	envBlocked    = "KEYS_BLOCKED"     // manifest last reported as not allowed
+	envPrevious   = "KEYS_PREVIOUS"    // JSON values the loaded keys replaced
+)
+
+var envName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// manifest is a parsed .keys file.
+type manifest struct {
+	Path    string
+	Hash    string
+	Profile string   // empty for the active profile
+	Names   []string // names or globs; empty loads the whole profile
+}
+
+// findManifest returns the nearest .keys file in dir or its parents, or ""
+// if there is none.
+func findManifest(dir string) string {
+	for {
+		path := filepath.Join(dir, manifestFile)
+		if info, err := os.Stat(path); err == nil && info.Mode().IsRegular() {
+			return path
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// readManifest parses a .keys file:
+//
+//	profile myapp    # optional, defaults to the active profile
+//	OPENAI_KEY
+//	STRIPE_*         # globs are allowed
+func readManifest(path string) (*manifest, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(data)
+	m := &manifest{Path: path, Hash: hex.EncodeToString(sum[:])}
+
+	scanner := bufio.NewScanner(strings.NewReader(string(data)))
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line, _, _ := strings.Cut(scanner.Text(), "#")
+		fields := strings.Fields(line)
+		switch {
+		case len(fields) == 0:
+		case fields[0] == "profile" && len(fields) == 2:
+			m.Profile = fields[1]
+		case len(fields) == 1:
+			if _, err := newKeySelector(fields[0], false); err != nil {
+				return nil, fmt.Errorf("%s:%d: %w", path, lineNo, err)
+			}
+			m.Names = append(m.Names, fields[0])
+		default:
+			return nil, fmt.Errorf("%s:%d: expected a key name or 'profile <name>'", path, lineNo)
+		}
+	}
+	return m, scanner.Err()
+}
+
+// loadedFrom is the value of $KEYS_LOADED_FROM for m.
+func (m *manifest) loadedFrom() string {
+	return m.Hash + " " + m.Path
+}
+
+// keys returns the keys m asks for, warning on w about names that are not
+// stored or cannot be exported.
+func (m *manifest) keys(w io.Writer) (string, []db.Key, error) {
+	profile := m.Profile
+	if profile == "" {
+		profile = db.GetActiveProfile()
+	}
+	all, err := db.GetAllKeysForProfile(profile)
+	if err != nil {
+		return "", nil, err
+	}
+
+	var keys []db.Key
+	if len(m.Names) == 0 {
+		keys = all
+	} else {
+		seen := make(map[string]bool)
+		for _, name := range m.Names {
+			sel, _ := newKeySelector(name, false)
+			matched := sel.Filter(all)
+			if len(matched) == 0 && !isPattern(name) {
+				fmt.Fprintf(w, "keys: %s is not in profile %q\n", name, profile)
+			}
+			for _, k := range matched {
+				if !seen[k.Name] {
+					seen[k.Name] = true
+					keys = append(keys, k)
+				}
+			}
+		}
+	}
+
+	var valid []db.Key
+	for _, k := range keys {
+		if !envName.MatchString(k.Name) {
+			fmt.Fprintf(w, "keys: skipping %s, not a valid variable name\n", k.Name)
+			continue
+		}
+		valid = append(valid, k)
+	}
+	return profile, valid, nil
+}
+
+// shellSyntax writes environment changes in one shell's language.
+type shellSyntax struct {
+	export func(name, value string) string
+	unset  func(name string) string
+}
+
+var shells = map[string]shellSyntax{
+	"bash": posixSyntax,
+	"zsh":  posixSyntax,
+	"fish": {
+		export: func(name, value string) string { return "set -gx " + name + " " + fishQuote(value) + ";\n" },
+		unset:  func(name string) string { return "set -e " + name + ";\n" },
+	},
+}
+
+var posixSyntax = shellSyntax{
+	export: func(name, value string) string { return "export " + name + "=" + shellQuote(value) + ";\n" },
+	unset:  func(name string) string { return "unset " + name + ";\n" },
+}
+
+// fishQuote quotes s for use as a single fish word.
+func fishQuote(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	return "'" + strings.ReplaceAll(s, "'", `\'`) + "'"
+}
+
+// hookScripts are the prompt hooks printed by 'keys hook <shell>'. %s is
+// the quoted path of this binary.
+var hookScripts = map[string]string{
+	"zsh": `_keys_hook() {
+  trap -- '' SIGINT
+  eval "$(%[1]s hook export zsh)"
+  trap - SIGINT
+}
+typeset -ag precmd_functions
+if (( ! ${precmd_functions[(I)_keys_hook]} )); then
+  precmd_functions=(_keys_hook $precmd_functions)
+fi
+typeset -ag chpwd_functions
+if (( ! ${chpwd_functions[(I)_keys_hook]} )); then
+  chpwd_functions=(_keys_hook $chpwd_functions)
+fi
+`,
+	"bash": `_keys_hook() {
+  local previous_exit_status=$?
+  trap -- '' SIGINT
+  eval "$(%[1]s hook export bash)"
+  trap - SIGINT
+  return $previous_exit_status
+}
+if [[ ";${PROMPT_COMMAND[*]:-};" != *";_keys_hook;"* ]]; then
+  PROMPT_COMMAND="_keys_hook${PROMPT_COMMAND:+;$PROMPT_COMMAND}"
+fi
+`,
+	"fish": `function __keys_hook --on-event fish_prompt --on-variable PWD
+  %[1]s hook export fish | source
+end
+`,
+}
+
+func newHookShellCmd(shell string) *cobra.Command {
+	setup := fmt.Sprintf(`eval "$(keys hook %s)"`, shell)
+	rc := "~/." + shell + "rc"
+	if shell == "fish" {
+		setup = "keys hook fish | source"
+		rc = "~/.config/fish/config.fish"
+	}
+	return &cobra.Command{
+		Use:   shell,
+		Short: fmt.Sprintf("Print the %s hook that loads project keys on cd", shell),
+		Long: fmt.Sprintf(`Print a %s prompt hook that exports the keys listed in the nearest .keys
+file when you enter a project, and unsets them when you leave. Variables the
+keys replaced get their previous values back.
+
+A .keys file lists key names or globs, one per line, and optionally the
+profile to load them from:
+
+  profile myapp
+  OPENAI_KEY
+  STRIPE_*
+
+Nothing is loaded from a .keys file until you run 'keys hook allow' in its
+directory, and again after every change to it.
+
+Add this to %s:
+
+  %s`, shell, rc, setup),
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			bin, err := keysBinary()
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), hookScripts[shell], shellQuote(bin))
+			return nil
+		},
+	}
+}
+
+var hookExportCmd = &cobra.Command{
+	Use:    "export <shell>",
+	Short:  "Print the environment changes for the current directory (run by the shell hook)",
+	Hidden: true,
+	Args:   cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		syntax, ok := shells[args[0]]
+		if !ok {
+			return fmt.Errorf("unsupported shell %q (supported: bash, zsh, fish)", args[0])
+		}
+		cwd, err := os.Getwd()
+		if err != nil {
+			return err
+		}
+		return writeHookExport(cmd.OutOrStdout(), cmd.ErrOrStderr(), syntax, cwd)
+	},
+}
+
+// writeHookExport writes to out the shell code that brings the environment
+// in line with the .keys file governing dir, and notes changes on info.
+func writeHookExport(out, info io.Writer, syntax shellSyntax, dir string) error {
+	var m *manifest
+	if path := findManifest(dir); path != "" {
+		var err error
+		if m, err = readManifest(path); err != nil {
+			return err
+		}
+	}
+	trusted := false
+	if m != nil {
+		var err error
+		if trusted, err = db.IsTrusted(m.Path, m.Hash); err != nil {
+			return err
+		}
+	}
+
+	// env is the environment as the shell will have it once out is
+	// evaluated; unloading restores values the loaded keys replaced
+	env := os.LookupEnv
+	loaded := os.Getenv(envLoadedFrom)
+	if loaded != "" && (m == nil || !trusted || m.loadedFrom() != loaded) {
+		var names []string
+		if v := os.Getenv(envLoadedKeys); v != "" {
+			names = strings.Split(v, ",")
+		}
+		previous := make(map[string]string)
+		if v := os.Getenv(envPrevious); v != "" {
+			if err := json.Unmarshal([]byte(v), &previous); err != nil {
+				fmt.Fprintf(info, "keys: cannot restore previous values: %v\n", err)
+			}
+		}
+		for _, name := range names {
+			if value, ok := previous[name]; ok {
+				io.WriteString(out, syntax.export(name, value))
+			} else {
+				io.WriteString(out, syntax.unset(name))
+			}
+		}
+		io.WriteString(out, syntax.unset(envLoadedFrom))
+		io.WriteString(out, syntax.unset(envLoadedKeys))
+		if _, ok := os.LookupEnv(envPrevious); ok {
+			io.WriteString(out, syntax.unset(envPrevious))
+		}
+		fmt.Fprintf(info, "keys: unloaded %d key(s)\n", len(names))
+		loaded = ""
+
+		unloaded := make(map[string]bool, len(names))
+		for _, name := range names {
+			unloaded[name] = true
+		}
+		env = func(name string) (string, bool) {
+			if unloaded[name] {
+				value, ok := previous[name]
+				return value, ok
+			}
+			return os.LookupEnv(name)
+		}
+	}
+
+	if m == nil {
+		return nil
+	}
+	if !trusted {
+		// Report a blocked manifest once, not at every prompt
+		if os.Getenv(envBlocked) != m.Path {
+			fmt.Fprintf(info, "keys: %s is not allowed. Run 'keys hook allow' to load its keys.\n", m.Path)
+			io.WriteString(out, syntax.export(envBlocked, m.Path))
+		}
+		return nil
+	}
+	if os.Getenv(envBlocked) != "" {
+		io.WriteString(out, syntax.unset(envBlocked))
+	}
+	if loaded != "" {
+		return nil
+	}
+
+	if err := db.Authenticate(); err != nil {
+		return err
+	}
+	profile, keys, err := m.keys(info)
+	if err != nil {
+		return err
+	}
+	names := make([]string, len(keys))
+	previous := make(map[string]string)
+	for i, k := range keys {
+		names[i] = k.Name
+		if value, ok := env(k.Name); ok {
+			previous[k.Name] = value
+		}
+		io.WriteString(out, syntax.export(k.Name, k.Value))
+		_ = db.LogAccessForProfile(profile, k.Name, "export", "shell")
+	}
+	io.WriteString(out, syntax.export(envLoadedFrom, m.loadedFrom()))
+	io.WriteString(out, syntax.export(envLoadedKeys, strings.Join(names, ",")))
+	if len(previous) > 0 {
+		data, err := json.Marshal(previous)
+		if err != nil {
+			return err
+		}
+		io.WriteString(out, syntax.export(envPrevious, string(data)))
+	}
+	fmt.Fprintf(info, "keys: loaded %d key(s) from profile %q\n", len(keys), profile)
+	return nil
+}
+
+// manifestArg returns the .keys file in the directory given in args, or
+// the current one.
+func manifestArg(args []string) (string, error) {
+	dir := "."
+	if len(args) == 1 {
+		dir = args[0]
+	}
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, manifestFile), nil
+}
+
+var hookAllowCmd = &cobra.Command{
+	Use:   "allow [dir]",
+	Short: "Let the shell hook load the .keys file in a directory",
+	Long: `Allow the shell hook to load the keys listed in the .keys file in dir (default:
+the current directory). The file's keys and profile are shown and you are asked
+to confirm; --yes skips the prompt.
+
+Changing the file revokes the permission until it is allowed again, so a
+pulled commit can't quietly ask for more keys.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		yes, _ := cmd.Flags().GetBool("yes")
+		path, err := manifestArg(args)
+		if err != nil {
+			return err
+		}
+		m, err := readManifest(path)
+		if err != nil {
+			return err
+		}
+
+		out := cmd.OutOrStdout()
+		profile := m.Profile
+		if profile == "" {
+			profile = "the active profile"
+		} else {
+			profile = fmt.Sprintf("profile %q", profile)
+		}
+		if !yes {
+			if len(m.Names) == 0 {
+				fmt.Fprintf(out, "%s asks for every key from %s.\n", m.Path, profile)
+			} else {
+				fmt.Fprintf(out, "%s asks for these keys from %s:\n", m.Path, profile)
+				for _, name := range m.Names {
+					fmt.Fprintf(out, "  %s\n", name)
+				}
+			}
+			fmt.Fprint(out, "Allow? [y/N]: ")
+			input, _ := bufio.NewReader(cmd.InOrStdin()).ReadString('\n')
+			input = strings.TrimSpace(strings.ToLower(input))
+			if input != "y" && input != "yes" {
+				fmt.Fprintln(out, "Cancelled.")
+				return nil
+			}
+		}
+
+		if err := db.Trust(m.Path, m.Hash); err != nil {
+			return err
+		}
+		if len(m.Names) == 0 {
+			fmt.Fprintf(out, "Allowed %s to load every key from %s\n", m.Path, profile)
+		} else {
+			fmt.Fprintf(out, "Allowed %s to load from %s: %s\n", m.Path, profile, strings.Join(m.Names, ", "))
+		}
+		return nil
+	},
+}
+
+var hookDenyCmd = &cobra.Command{
+	Use:   "deny [dir]",
+	Short: "Stop the shell hook loading the .keys file in a directory",
+	Args:  cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		path, err := manifestArg(args)
+		if err != nil {
+			return err
+		}
+		removed, err := db.Untrust(path)
+		if err != nil {
+			return err
+		}
+		if !removed {
+			return fmt.Errorf("%s is not allowed", path)
+		}
+		fmt.Fprintf(cmd.OutOrStdout(), "Denied %s\n", path)
+		return nil
+	},
+}
+
+func init() {
+	for _, shell := range []string{"bash", "zsh", "fish"} {
+		hookCmd.AddCommand(newHookShellCmd(shell))
+	}
+	hookCmd.AddCommand(hookExportCmd)
+	hookAllowCmd.Flags().BoolP("yes", "y", false, "allow without asking for confirmation")
+	hookCmd.AddCommand(hookAllowCmd)
+	hookCmd.AddCommand(hookDenyCmd)
+}
@@ -0,0 +1,179 @@
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stym06/keys/db"
+)
+
+func writeManifest(t *testing.T, dir, content string) *manifest {
+	t.Helper()
+	path := filepath.Join(dir, manifestFile)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	m, err := readManifest(path)
+	if err != nil {
+		t.Fatalf("readManifest: %v", err)
+	}
+	return m
+}
+
+func TestReadManifest(t *testing.T) {
+	m := writeManifest(t, t.TempDir(), "# project keys\nprofile myapp\nOPENAI_KEY\nSTRIPE_*  # stripe\n")
+	if m.Profile != "myapp" || strings.Join(m.Names, ",") != "OPENAI_KEY,STRIPE_*" {
+		t.Errorf("unexpected manifest %+v", m)
+	}
+
+	path := filepath.Join(t.TempDir(), manifestFile)
+	os.WriteFile(path, []byte("OPENAI_KEY extra\n"), 0644)
+	if _, err := readManifest(path); err == nil || !strings.Contains(err.Error(), ":1:") {
+		t.Errorf("expected a line-numbered error, got %v", err)
+	}
+}
+
+func TestHookExportLoadsAllowedManifest(t *testing.T) {
+	setupTestEnv(t)
+	db.AddKey("OPENAI_KEY", "it's secret")
+	db.AddKey("OTHER", "x")
+	proj := t.TempDir()
+	sub := filepath.Join(proj, "sub")
+	os.Mkdir(sub, 0755)
+	m := writeManifest(t, proj, "OPENAI_KEY\n")
+	t.Setenv(envLoadedFrom, "")
+	t.Setenv(envLoadedKeys, "")
+	t.Setenv(envBlocked, "")
+
+	var out, info bytes.Buffer
+	if err := writeHookExport(&out, &info, posixSyntax, sub); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if strings.Contains(out.String(), "OPENAI_KEY") || !strings.Contains(info.String(), "not allowed") {
+		t.Errorf("untrusted manifest should not load, got %q / %q", out.String(), info.String())
+	}
+
+	db.Trust(m.Path, m.Hash)
+	out.Reset()
+	writeHookExport(&out, &info, posixSyntax, sub)
+	want := "export OPENAI_KEY='it'\\''s secret';\n"
+	if !strings.HasPrefix(out.String(), want) || !strings.Contains(out.String(), "export KEYS_LOADED_KEYS='OPENAI_KEY'") {
+		t.Errorf("expected OPENAI_KEY export, got:\n%s", out.String())
+	}
+
+	// Nothing to do while the same manifest stays loaded
+	t.Setenv(envLoadedFrom, m.loadedFrom())
+	t.Setenv(envLoadedKeys, "OPENAI_KEY")
+	out.Reset()
+	writeHookExport(&out, &info, posixSyntax, proj)
+	if out.Len() != 0 {
+		t.Errorf("expected no changes, got:\n%s", out.String())
+	}
+
+	// Leaving the project unsets what was loaded
+	out.Reset()
+	writeHookExport(&out, &info, shells["fish"], t.TempDir())
+	if out.String() != "set -e OPENAI_KEY;\nset -e KEYS_LOADED_FROM;\nset -e KEYS_LOADED_KEYS;\n" {
+		t.Errorf("unexpected unload:\n%s", out.String())
+	}
+}
+
+func TestEditedManifestNeedsAllowAgain(t *testing.T) {
+	setupTestEnv(t)
+	proj := t.TempDir()
+	m := writeManifest(t, proj, "OPENAI_KEY\n")
+	db.Trust(m.Path, m.Hash)
+
+	edited := writeManifest(t, proj, "OPENAI_KEY\nAWS_*\n")
+	if ok, _ := db.IsTrusted(edited.Path, edited.Hash); ok {
+		t.Error("an edited manifest should not stay trusted")
+	}
+	if removed, _ := db.Untrust(m.Path); !removed {
+		t.Error("Untrust should remove the entry")
+	}
+}
+
+func TestHookExportRestoresReplacedValues(t *testing.T) {
+	setupTestEnv(t)
+	db.AddKey("OPENAI_KEY", "from-keys")
+	proj := t.TempDir()
+	m := writeManifest(t, proj, "OPENAI_KEY\n")
+	db.Trust(m.Path, m.Hash)
+	t.Setenv(envLoadedFrom, "")
+	t.Setenv(envLoadedKeys, "")
+	t.Setenv(envBlocked, "")
+	t.Setenv("OPENAI_KEY", "mine")
+
+	var out, info bytes.Buffer
+	if err := writeHookExport(&out, &info, posixSyntax, proj); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := `export KEYS_PREVIOUS='{"OPENAI_KEY":"mine"}';`
+	if !strings.Contains(out.String(), want) {
+		t.Fatalf("expected the replaced value to be recorded, got:\n%s", out.String())
+	}
+
+	// Leaving the project puts the old value back
+	t.Setenv("OPENAI_KEY", "from-keys")
+	t.Setenv(envLoadedFrom, m.loadedFrom())
+	t.Setenv(envLoadedKeys, "OPENAI_KEY")
+	t.Setenv(envPrevious, `{"OPENAI_KEY":"mine"}`)
+	out.Reset()
+	writeHookExport(&out, &info, posixSyntax, t.TempDir())
+	if out.String() != "export OPENAI_KEY='mine';\nunset KEYS_LOADED_FROM;\nunset KEYS_LOADED_KEYS;\nunset KEYS_PREVIOUS;\n" {
+		t.Errorf("unexpected unload:\n%s", out.String())
+	}
+
+	// Switching to an edited manifest remembers the original value, not ours
+	edited := writeManifest(t, proj, "OPENAI_KEY\n# edited\n")
+	db.Trust(edited.Path, edited.Hash)
+	out.Reset()
+	writeHookExport(&out, &info, posixSyntax, proj)
+	if !strings.HasSuffix(out.String(), want+"\n") {
+		t.Errorf("expected the original value to be kept, got:\n%s", out.String())
+	}
+}
+
+func TestHookAllowConfirms(t *testing.T) {
+	setupTestEnv(t)
+	proj := t.TempDir()
+	m := writeManifest(t, proj, "profile myapp\nOPENAI_KEY\n")
+
+	run := func(stdin string, args ...string) string {
+		t.Helper()
+		defer hookAllowCmd.Flags().Set("yes", "false")
+		out := new(bytes.Buffer)
+		rootCmd.SetOut(out)
+		rootCmd.SetIn(strings.NewReader(stdin))
+		defer rootCmd.SetIn(nil)
+		rootCmd.SetArgs(append([]string{"hook", "allow", proj}, args...))
+		if err := rootCmd.Execute(); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return out.String()
+	}
+
+	out := run("n\n")
+	if !strings.Contains(out, `from profile "myapp":`) || !strings.Contains(out, "  OPENAI_KEY\n") || !strings.Contains(out, "Cancelled.") {
+		t.Errorf("expected the manifest to be shown and the allow cancelled, got:\n%s", out)
+	}
+	if ok, _ := db.IsTrusted(m.Path, m.Hash); ok {
+		t.Error("declining should not trust the manifest")
+	}
+
+	run("y\n")
+	if ok, _ := db.IsTrusted(m.Path, m.Hash); !ok {
+		t.Error("confirming should trust the manifest")
+	}
+
+	db.Untrust(m.Path)
+	if out := run("", "--yes"); strings.Contains(out, "Allow?") {
+		t.Errorf("--yes should not prompt, got:\n%s", out)
+	}
+	if ok, _ := db.IsTrusted(m.Path, m.Hash); !ok {
+		t.Error("--yes should trust the manifest")
+	}
+}
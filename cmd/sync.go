@@ -79,6 +79,10 @@ Examples:
 	},
 }
 
+// syncPassphraseEnv names the environment variable 'keys sync pull' reads
+// the passphrase from.
+const syncPassphraseEnv = "KEYS_SYNC_PASSPHRASE"
+
 var syncPullCmd = &cobra.Command{
 	Use:   "pull [host:port]",
 	Short: "Pull keys from another machine",
@@ -86,50 +90,93 @@ var syncPullCmd = &cobra.Command{
 
 If a host:port is provided, connects directly without discovery.
 
+The passphrase is prompted for without echo. To script a pull, pipe it in
+with --passphrase-stdin or set $KEYS_SYNC_PASSPHRASE, so it never shows up
+in the process list. With --output json or yaml nothing is prompted: the
+passphrase must be given one of those ways, and a host:port is needed if
+more than one peer is found.
+
 Examples:
   keys sync pull                    # auto-discover peers via mDNS
-  keys sync pull 192.168.1.10:7331  # connect directly`,
+  keys sync pull 192.168.1.10:7331  # connect directly
+  pass show sync | keys sync pull 192.168.1.10:7331 --passphrase-stdin --output json`,
 	Args: cobra.MaximumNArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
-		reader := bufio.NewReader(os.Stdin)
-
-		if len(args) == 1 {
-			fmt.Print("Enter passphrase: ")
-			passphrase, _ := reader.ReadString('\n')
+		fromStdin, _ := cmd.Flags().GetBool("passphrase-stdin")
+		format, err := outputFormat(cmd)
+		if err != nil {
+			return err
+		}
+		passphrase := os.Getenv(syncPassphraseEnv)
+		if fromStdin {
+			if passphrase, err = readStdinValue(cmd); err != nil {
+				return err
+			}
 			passphrase = strings.TrimSpace(passphrase)
+		}
+		machine := format != outputTable
+		if machine && passphrase == "" {
+			return fmt.Errorf("pass the passphrase with --passphrase-stdin or $%s when using --output %s", syncPassphraseEnv, format)
+		}
 
+		// Progress goes to stderr in machine mode so stdout holds only data
+		info := cmd.OutOrStdout()
+		if machine {
+			info = cmd.ErrOrStderr()
+		}
+		readPassphrase := func() (string, error) {
+			if passphrase != "" {
+				return passphrase, nil
+			}
+			if !stdinIsTerminal() {
+				return "", fmt.Errorf("stdin is not a terminal; pass the passphrase with --passphrase-stdin or $%s", syncPassphraseEnv)
+			}
+			input, err := promptSecret(cmd, "Enter passphrase: ", false)
+			return strings.TrimSpace(input), err
+		}
+
+		if len(args) == 1 {
+			passphrase, err := readPassphrase()
+			if err != nil {
+				return err
+			}
 			result, err := ksync.PullDirect(args[0], passphrase)
 			if err != nil {
 				return err
 			}
-			printSyncResult(result)
-			return nil
+			return printSyncResult(cmd, format, args[0], result)
 		}
 
-		fmt.Println("Scanning for peers...")
+		fmt.Fprintln(info, "Scanning for peers...")
 		peers, err := ksync.DiscoverPeers(5 * time.Second)
 		if err != nil {
 			return err
 		}
 
 		if len(peers) == 0 {
-			fmt.Println("No peers found. Make sure the other machine is running 'keys sync serve'.")
+			if machine {
+				return fmt.Errorf("no peers found")
+			}
+			fmt.Fprintln(info, "No peers found. Make sure the other machine is running 'keys sync serve'.")
 			return nil
 		}
 
-		fmt.Println()
+		fmt.Fprintln(info)
 		for i, p := range peers {
-			fmt.Printf("  %d. %s (%s:%d)\n", i+1, p.Name, p.Addr, p.Port)
+			fmt.Fprintf(info, "  %d. %s (%s:%d)\n", i+1, p.Name, p.Addr, p.Port)
 		}
-		fmt.Println()
+		fmt.Fprintln(info)
 
 		var selected ksync.Peer
-		if len(peers) == 1 {
+		switch {
+		case len(peers) == 1:
 			selected = peers[0]
-			fmt.Printf("Found 1 peer: %s\n", selected.Name)
-		} else {
-			fmt.Print("Select a peer: ")
-			input, _ := reader.ReadString('\n')
+			fmt.Fprintf(info, "Found 1 peer: %s\n", selected.Name)
+		case machine || fromStdin:
+			return fmt.Errorf("found %d peers; pass host:port to choose one", len(peers))
+		default:
+			fmt.Fprint(info, "Select a peer: ")
+			input, _ := bufio.NewReader(cmd.InOrStdin()).ReadString('\n')
 			input = strings.TrimSpace(input)
 			idx, err := strconv.Atoi(input)
 			if err != nil || idx < 1 || idx > len(peers) {
@@ -138,26 +185,48 @@ Examples:
 			selected = peers[idx-1]
 		}
 
-		fmt.Print("Enter passphrase: ")
-		passphrase, _ := reader.ReadString('\n')
-		passphrase = strings.TrimSpace(passphrase)
-
+		passphrase, err = readPassphrase()
+		if err != nil {
+			return err
+		}
 		result, err := ksync.Pull(selected, passphrase)
 		if err != nil {
 			return err
 		}
-		printSyncResult(result)
-		return nil
+		return printSyncResult(cmd, format, fmt.Sprintf("%s:%d", selected.Addr, selected.Port), result)
 	},
 }
 
-func printSyncResult(r *ksync.SyncResult) {
+// syncResultItem is the machine-readable form of 'keys sync pull'.
+type syncResultItem struct {
+	Peer    string `json:"peer" yaml:"peer"`
+	Profile string `json:"profile" yaml:"profile"`
+	Added   int    `json:"added" yaml:"added"`
+	Updated int    `json:"updated" yaml:"updated"`
+	Skipped int    `json:"skipped" yaml:"skipped"`
+	Total   int    `json:"total" yaml:"total"`
+}
+
+func printSyncResult(cmd *cobra.Command, format, peer string, r *ksync.SyncResult) error {
 	total := r.Added + r.Updated + r.Skipped
-	fmt.Printf("Synced. %d keys: %d added, %d updated, %d skipped.\n", total, r.Added, r.Updated, r.Skipped)
+	if format != outputTable {
+		return writeData(cmd, format, syncResultItem{
+			Peer:    peer,
+			Profile: db.GetActiveProfile(),
+			Added:   r.Added,
+			Updated: r.Updated,
+			Skipped: r.Skipped,
+			Total:   total,
+		})
+	}
+	fmt.Fprintf(cmd.OutOrStdout(), "Synced. %d keys: %d added, %d updated, %d skipped.\n", total, r.Added, r.Updated, r.Skipped)
+	return nil
 }
 
 func init() {
 	syncServeCmd.Flags().StringP("profile", "p", "", "profile to serve (default: active profile)")
+	syncPullCmd.Flags().Bool("passphrase-stdin", false, "read the passphrase shown by 'keys sync serve' from stdin")
+	addOutputFlag(syncPullCmd)
 	syncCmd.AddCommand(syncServeCmd)
 	syncCmd.AddCommand(syncPullCmd)
 	rootCmd.AddCommand(syncCmd)
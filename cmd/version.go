@@ -11,11 +11,25 @@ const Version = "0.5.1"
 var versionCmd = &cobra.Command{
 	Use:   "version",
 	Short: "Print the version",
-	Run: func(cmd *cobra.Command, args []string) {
-		fmt.Printf("keys %s\n", Version)
+	RunE: func(cmd *cobra.Command, args []string) error {
+		format, err := outputFormat(cmd)
+		if err != nil {
+			return err
+		}
+		if format != outputTable {
+			return writeData(cmd, format, versionItem{Version: Version})
+		}
+		fmt.Fprintf(cmd.OutOrStdout(), "keys %s\n", Version)
+		return nil
 	},
 }
 
+// versionItem is the machine-readable form of 'keys version'.
+type versionItem struct {
+	Version string `json:"version" yaml:"version"`
+}
+
 func init() {
+	addOutputFlag(versionCmd)
 	rootCmd.AddCommand(versionCmd)
 }
@@ -2,18 +2,33 @@ package db
 
 import (
 	"database/sql"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
 
 	_ "github.com/mattn/go-sqlite3"
 )
 
+// ErrNotFound is wrapped by errors for keys that don't exist.
+var ErrNotFound = errors.New("not found")
+
 type Key struct {
 	Name      string
 	Value     string
 	UpdatedAt int64
+	RotatedAt int64
+}
+
+// LastRotated returns when the key's value last changed. Renames and
+// metadata edits bump UpdatedAt but not RotatedAt.
+func (k Key) LastRotated() int64 {
+	if k.RotatedAt > 0 {
+		return k.RotatedAt
+	}
+	return k.UpdatedAt
 }
 
 func dbPath() (string, error) {
@@ -115,6 +130,29 @@ func open() (*sql.DB, error) {
 		}
 	}
 
+	// Migrate: add rotated_at column
+	if !columnExists(d, "keys", "rotated_at") {
+		if _, err := d.Exec(`ALTER TABLE keys ADD COLUMN rotated_at INTEGER`); err != nil {
+			d.Close()
+			return nil, err
+		}
+		_, _ = d.Exec(`UPDATE keys SET rotated_at = updated_at WHERE rotated_at IS NULL`)
+	}
+
+	// Migrate: create previous_values table
+	_, err = d.Exec(`CREATE TABLE IF NOT EXISTS previous_values (
+		profile TEXT NOT NULL,
+		key_name TEXT NOT NULL,
+		value TEXT NOT NULL,
+		rotated_at INTEGER NOT NULL,
+		expires_at INTEGER NOT NULL,
+		PRIMARY KEY (profile, key_name)
+	)`)
+	if err != nil {
+		d.Close()
+		return nil, err
+	}
+
 	// Migrate: create audit_log table
 	_, err = d.Exec(`CREATE TABLE IF NOT EXISTS audit_log (
 		id INTEGER PRIMARY KEY AUTOINCREMENT,
@@ -129,17 +167,45 @@ func open() (*sql.DB, error) {
 		return nil, err
 	}
 
+	// Migrate: create key_meta table
+	_, err = d.Exec(`CREATE TABLE IF NOT EXISTS key_meta (
+		profile TEXT NOT NULL,
+		key_name TEXT NOT NULL,
+		field TEXT NOT NULL,
+		value TEXT NOT NULL,
+		PRIMARY KEY (profile, key_name, field)
+	)`)
+	if err != nil {
+		d.Close()
+		return nil, err
+	}
+
+	// Migrate: create fingerprints table
+	if err := createFingerprintTables(d); err != nil {
+		d.Close()
+		return nil, err
+	}
+
+	// Migrate: create api_tokens table
+	if err := createAPITokenTable(d); err != nil {
+		d.Close()
+		return nil, err
+	}
+
 	return d, nil
 }
 
 func LogAccess(keyName, action, source string) error {
+	return LogAccessForProfile(GetActiveProfile(), keyName, action, source)
+}
+
+func LogAccessForProfile(profile, keyName, action, source string) error {
 	d, err := open()
 	if err != nil {
 		return err
 	}
 	defer d.Close()
 
-	profile := GetActiveProfile()
 	now := time.Now().Unix()
 	_, err = d.Exec(
 		`INSERT INTO audit_log (profile, key_name, action, source, accessed_at) VALUES (?, ?, ?, ?, ?)`,
@@ -149,6 +215,7 @@ func LogAccess(keyName, action, source string) error {
 }
 
 type AuditEntry struct {
+	Profile    string
 	KeyName    string
 	Action     string
 	Source     string
@@ -156,18 +223,62 @@ type AuditEntry struct {
 	Count      int
 }
 
-func GetAuditLog(limit int) ([]AuditEntry, error) {
+// AuditQuery selects audit log entries. Zero fields match everything.
+type AuditQuery struct {
+	Profile     string // default: the active profile
+	AllProfiles bool
+	Since       int64  // inclusive, unix seconds
+	Until       int64  // exclusive, unix seconds
+	Key         string // glob
+	Action      string
+	Source      string // glob, e.g. "api:*"
+	Limit       int    // 0 for no limit
+}
+
+// QueryAuditLog returns the entries matching q, most recent first.
+func QueryAuditLog(q AuditQuery) ([]AuditEntry, error) {
 	d, err := open()
 	if err != nil {
 		return nil, err
 	}
 	defer d.Close()
 
-	profile := GetActiveProfile()
-	rows, err := d.Query(
-		`SELECT key_name, action, source, accessed_at FROM audit_log WHERE profile = ? ORDER BY accessed_at DESC LIMIT ?`,
-		profile, limit,
-	)
+	var where []string
+	var args []any
+	if !q.AllProfiles {
+		profile := q.Profile
+		if profile == "" {
+			profile = GetActiveProfile()
+		}
+		where, args = append(where, "profile = ?"), append(args, profile)
+	}
+	if q.Since != 0 {
+		where, args = append(where, "accessed_at >= ?"), append(args, q.Since)
+	}
+	if q.Until != 0 {
+		where, args = append(where, "accessed_at < ?"), append(args, q.Until)
+	}
+	if q.Key != "" {
+		where, args = append(where, "key_name GLOB ?"), append(args, q.Key)
+	}
+	if q.Action != "" {
+		where, args = append(where, "action = ?"), append(args, q.Action)
+	}
+	if q.Source != "" {
+		where, args = append(where, "source GLOB ?"), append(args, q.Source)
+	}
+
+	query := `SELECT profile, key_name, action, source, accessed_at FROM audit_log`
+	if len(where) > 0 {
+		query += " WHERE " + strings.Join(where, " AND ")
+	}
+	query += " ORDER BY accessed_at DESC, id DESC"
+	if q.Limit > 0 {
+		query += " LIMIT ?"
+		args = append(args, q.Limit)
+	}
+
+	rows, err := d.Query(query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -176,7 +287,7 @@ func GetAuditLog(limit int) ([]AuditEntry, error) {
 	var entries []AuditEntry
 	for rows.Next() {
 		var e AuditEntry
-		if err := rows.Scan(&e.KeyName, &e.Action, &e.Source, &e.AccessedAt); err != nil {
+		if err := rows.Scan(&e.Profile, &e.KeyName, &e.Action, &e.Source, &e.AccessedAt); err != nil {
 			return nil, err
 		}
 		entries = append(entries, e)
@@ -184,17 +295,20 @@ func GetAuditLog(limit int) ([]AuditEntry, error) {
 	return entries, rows.Err()
 }
 
-func GetAuditSummary() ([]AuditEntry, error) {
+func GetAuditLog(limit int) ([]AuditEntry, error) {
+	return QueryAuditLog(AuditQuery{Limit: limit})
+}
+
+func GetAuditSummaryForProfile(profile string) ([]AuditEntry, error) {
 	d, err := open()
 	if err != nil {
 		return nil, err
 	}
 	defer d.Close()
 
-	profile := GetActiveProfile()
 	rows, err := d.Query(
 		`SELECT key_name, COUNT(*) as cnt, MAX(accessed_at) as last_access
-		 FROM audit_log WHERE profile = ?
+		 FROM audit_log WHERE profile = ? AND key_name != '*'
 		 GROUP BY key_name ORDER BY last_access DESC`,
 		profile,
 	)
@@ -214,6 +328,10 @@ func GetAuditSummary() ([]AuditEntry, error) {
 	return entries, rows.Err()
 }
 
+func GetAuditSummary() ([]AuditEntry, error) {
+	return GetAuditSummaryForProfile(GetActiveProfile())
+}
+
 func ClearAuditLog() error {
 	d, err := open()
 	if err != nil {
@@ -226,21 +344,34 @@ func ClearAuditLog() error {
 	return err
 }
 
-func AddKey(name, value string) error {
+func AddKeyForProfile(profile, name, value string) error {
 	d, err := open()
 	if err != nil {
 		return err
 	}
 	defer d.Close()
 
-	profile := GetActiveProfile()
 	now := time.Now().Unix()
 	_, err = d.Exec(
-		`INSERT INTO keys (profile, name, value, updated_at) VALUES (?, ?, ?, ?)
-		 ON CONFLICT(profile, name) DO UPDATE SET value = excluded.value, updated_at = excluded.updated_at`,
-		profile, name, value, now,
+		`INSERT INTO keys (profile, name, value, updated_at, rotated_at) VALUES (?, ?, ?, ?, ?)
+		 ON CONFLICT(profile, name) DO UPDATE SET
+			rotated_at = CASE WHEN keys.value = excluded.value THEN keys.rotated_at ELSE excluded.rotated_at END,
+			value = excluded.value, updated_at = excluded.updated_at`,
+		profile, name, value, now, now,
 	)
-	return err
+	if err != nil {
+		return err
+	}
+
+	secret, err := fingerprintKey(d)
+	if err != nil {
+		return err
+	}
+	return writeFingerprints(d, secret, profile, name, value)
+}
+
+func AddKey(name, value string) error {
+	return AddKeyForProfile(GetActiveProfile(), name, value)
 }
 
 func GetAllKeysForProfile(profile string) ([]Key, error) {
@@ -250,7 +381,7 @@ func GetAllKeysForProfile(profile string) ([]Key, error) {
 	}
 	defer d.Close()
 
-	rows, err := d.Query(`SELECT name, value, COALESCE(updated_at, 0) FROM keys WHERE profile = ? ORDER BY name`, profile)
+	rows, err := d.Query(`SELECT name, value, COALESCE(updated_at, 0), COALESCE(rotated_at, 0) FROM keys WHERE profile = ? ORDER BY name`, profile)
 	if err != nil {
 		return nil, err
 	}
@@ -259,7 +390,7 @@ func GetAllKeysForProfile(profile string) ([]Key, error) {
 	var keys []Key
 	for rows.Next() {
 		var k Key
-		if err := rows.Scan(&k.Name, &k.Value, &k.UpdatedAt); err != nil {
+		if err := rows.Scan(&k.Name, &k.Value, &k.UpdatedAt, &k.RotatedAt); err != nil {
 			return nil, err
 		}
 		keys = append(keys, k)
@@ -281,7 +412,7 @@ func GetKeysByNamesForProfile(names []string, profile string) ([]Key, error) {
 	}
 	defer d.Close()
 
-	query := `SELECT name, value, COALESCE(updated_at, 0) FROM keys WHERE profile = ? AND name IN (`
+	query := `SELECT name, value, COALESCE(updated_at, 0), COALESCE(rotated_at, 0) FROM keys WHERE profile = ? AND name IN (`
 	args := []interface{}{profile}
 	for i, n := range names {
 		if i > 0 {
@@ -301,7 +432,7 @@ func GetKeysByNamesForProfile(names []string, profile string) ([]Key, error) {
 	var keys []Key
 	for rows.Next() {
 		var k Key
-		if err := rows.Scan(&k.Name, &k.Value, &k.UpdatedAt); err != nil {
+		if err := rows.Scan(&k.Name, &k.Value, &k.UpdatedAt, &k.RotatedAt); err != nil {
 			return nil, err
 		}
 		keys = append(keys, k)
@@ -329,18 +460,17 @@ func KeyExists(name string) (bool, error) {
 	return count > 0, nil
 }
 
-func GetKey(name string) (*Key, error) {
+func GetKeyForProfile(profile, name string) (*Key, error) {
 	d, err := open()
 	if err != nil {
 		return nil, err
 	}
 	defer d.Close()
 
-	profile := GetActiveProfile()
 	var k Key
-	err = d.QueryRow(`SELECT name, value, COALESCE(updated_at, 0) FROM keys WHERE profile = ? AND name = ?`, profile, name).Scan(&k.Name, &k.Value, &k.UpdatedAt)
+	err = d.QueryRow(`SELECT name, value, COALESCE(updated_at, 0), COALESCE(rotated_at, 0) FROM keys WHERE profile = ? AND name = ?`, profile, name).Scan(&k.Name, &k.Value, &k.UpdatedAt, &k.RotatedAt)
 	if err == sql.ErrNoRows {
-		return nil, fmt.Errorf("key %q not found", name)
+		return nil, fmt.Errorf("key %q %w", name, ErrNotFound)
 	}
 	if err != nil {
 		return nil, err
@@ -348,14 +478,17 @@ func GetKey(name string) (*Key, error) {
 	return &k, nil
 }
 
-func DeleteKey(name string) error {
+func GetKey(name string) (*Key, error) {
+	return GetKeyForProfile(GetActiveProfile(), name)
+}
+
+func DeleteKeyForProfile(profile, name string) error {
 	d, err := open()
 	if err != nil {
 		return err
 	}
 	defer d.Close()
 
-	profile := GetActiveProfile()
 	res, err := d.Exec(`DELETE FROM keys WHERE profile = ? AND name = ?`, profile, name)
 	if err != nil {
 		return err
@@ -365,9 +498,21 @@ func DeleteKey(name string) error {
 		return err
 	}
 	if n == 0 {
-		return fmt.Errorf("key %q not found", name)
+		return fmt.Errorf("key %q %w", name, ErrNotFound)
+	}
+	_, err = d.Exec(`DELETE FROM key_meta WHERE profile = ? AND key_name = ?`, profile, name)
+	if err != nil {
+		return err
 	}
-	return nil
+	_, err = d.Exec(`DELETE FROM previous_values WHERE profile = ? AND key_name = ?`, profile, name)
+	if err != nil {
+		return err
+	}
+	return deleteFingerprints(d, profile, name)
+}
+
+func DeleteKey(name string) error {
+	return DeleteKeyForProfile(GetActiveProfile(), name)
 }
 
 func UpdateKey(oldName, newName, newValue string) error {
@@ -380,38 +525,88 @@ func UpdateKey(oldName, newName, newValue string) error {
 	profile := GetActiveProfile()
 	now := time.Now().Unix()
 
-	tx, err := d.Begin()
+	secret, err := fingerprintKey(d)
 	if err != nil {
 		return err
 	}
 
-	// Delete old key
-	res, err := tx.Exec(`DELETE FROM keys WHERE profile = ? AND name = ?`, profile, oldName)
+	tx, err := d.Begin()
 	if err != nil {
-		tx.Rollback()
 		return err
 	}
-	n, err := res.RowsAffected()
+
+	// A rename keeps the rotation time unless the value changes too
+	var oldValue string
+	var rotatedAt int64
+	err = tx.QueryRow(`SELECT value, COALESCE(rotated_at, updated_at, 0) FROM keys WHERE profile = ? AND name = ?`, profile, oldName).Scan(&oldValue, &rotatedAt)
+	if err == sql.ErrNoRows {
+		tx.Rollback()
+		return fmt.Errorf("key %q %w", oldName, ErrNotFound)
+	}
 	if err != nil {
 		tx.Rollback()
 		return err
 	}
-	if n == 0 {
+	if oldValue != newValue {
+		rotatedAt = now
+	}
+
+	// Delete old key
+	if _, err := tx.Exec(`DELETE FROM keys WHERE profile = ? AND name = ?`, profile, oldName); err != nil {
 		tx.Rollback()
-		return fmt.Errorf("key %q not found", oldName)
+		return err
 	}
 
 	// Insert new key
 	_, err = tx.Exec(
-		`INSERT INTO keys (profile, name, value, updated_at) VALUES (?, ?, ?, ?)
-		 ON CONFLICT(profile, name) DO UPDATE SET value = excluded.value, updated_at = excluded.updated_at`,
-		profile, newName, newValue, now,
+		`INSERT INTO keys (profile, name, value, updated_at, rotated_at) VALUES (?, ?, ?, ?, ?)
+		 ON CONFLICT(profile, name) DO UPDATE SET value = excluded.value, updated_at = excluded.updated_at, rotated_at = excluded.rotated_at`,
+		profile, newName, newValue, now, rotatedAt,
 	)
 	if err != nil {
 		tx.Rollback()
 		return err
 	}
 
+	if oldName != newName {
+		// Fingerprints of a previous value follow it to the new name
+		stmts := []struct {
+			query string
+			args  []any
+		}{
+			{`DELETE FROM fingerprints WHERE profile = ? AND key_name = ? AND expires_at IS NOT NULL`, []any{profile, newName}},
+			{`UPDATE fingerprints SET key_name = ? WHERE profile = ? AND key_name = ? AND expires_at IS NOT NULL`, []any{newName, profile, oldName}},
+		}
+		for _, s := range stmts {
+			if _, err := tx.Exec(s.query, s.args...); err != nil {
+				tx.Rollback()
+				return err
+			}
+		}
+		if err := deleteFingerprints(tx, profile, oldName); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	if err := writeFingerprints(tx, secret, profile, newName, newValue); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	// Carry metadata and the previous value over to the new name
+	if oldName != newName {
+		for _, table := range []string{"key_meta", "previous_values"} {
+			if _, err := tx.Exec(`DELETE FROM `+table+` WHERE profile = ? AND key_name = ?`, profile, newName); err != nil {
+				tx.Rollback()
+				return err
+			}
+			if _, err := tx.Exec(`UPDATE `+table+` SET key_name = ? WHERE profile = ? AND key_name = ?`, newName, profile, oldName); err != nil {
+				tx.Rollback()
+				return err
+			}
+		}
+	}
+
 	return tx.Commit()
 }
 
@@ -427,6 +622,15 @@ func NukeKeys() (int64, error) {
 	if err != nil {
 		return 0, err
 	}
+	if _, err := d.Exec(`DELETE FROM key_meta WHERE profile = ?`, profile); err != nil {
+		return 0, err
+	}
+	if _, err := d.Exec(`DELETE FROM previous_values WHERE profile = ?`, profile); err != nil {
+		return 0, err
+	}
+	if _, err := d.Exec(`DELETE FROM fingerprints WHERE profile = ?`, profile); err != nil {
+		return 0, err
+	}
 	return res.RowsAffected()
 }
 
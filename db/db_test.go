@@ -1,8 +1,10 @@
 package db
 
 import (
+	"errors"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 )
@@ -348,3 +350,384 @@ func TestDeleteKeyInProfile(t *testing.T) {
 		t.Error("KEY should still exist in default profile")
 	}
 }
+
+func TestKeyMeta(t *testing.T) {
+	setupTestDB(t)
+
+	AddKey("TOKEN", "val")
+	if err := SetKeyMeta("TOKEN", "url", "https://example.com"); err != nil {
+		t.Fatalf("SetKeyMeta: %v", err)
+	}
+	SetKeyMeta("TOKEN", "notes", "first")
+	SetKeyMeta("TOKEN", "notes", "second")
+
+	meta, err := GetKeyMeta("TOKEN")
+	if err != nil {
+		t.Fatalf("GetKeyMeta: %v", err)
+	}
+	if len(meta) != 2 || meta["url"] != "https://example.com" || meta["notes"] != "second" {
+		t.Errorf("unexpected meta: %v", meta)
+	}
+
+	// Empty value removes the field
+	SetKeyMeta("TOKEN", "notes", "")
+	meta, _ = GetKeyMeta("TOKEN")
+	if _, ok := meta["notes"]; ok {
+		t.Error("notes should be removed")
+	}
+}
+
+func TestKeyMetaFollowsKey(t *testing.T) {
+	setupTestDB(t)
+
+	AddKey("OLD", "val")
+	SetKeyMeta("OLD", "url", "u")
+
+	if err := UpdateKey("OLD", "NEW", "val"); err != nil {
+		t.Fatalf("UpdateKey: %v", err)
+	}
+	meta, _ := GetKeyMeta("NEW")
+	if meta["url"] != "u" {
+		t.Errorf("meta should follow rename, got %v", meta)
+	}
+	meta, _ = GetKeyMeta("OLD")
+	if len(meta) != 0 {
+		t.Errorf("old name should have no meta, got %v", meta)
+	}
+
+	DeleteKey("NEW")
+	AddKey("NEW", "again")
+	meta, _ = GetKeyMeta("NEW")
+	if len(meta) != 0 {
+		t.Errorf("meta should be removed with the key, got %v", meta)
+	}
+}
+
+func TestFingerprintIndexFollowsKey(t *testing.T) {
+	setupTestDB(t)
+
+	AddKey("STRIPE_KEY", "sk_live_abcdef123456")
+	AddKey("SHORT", "abc")
+	line := []byte(`key = "sk_live_abcdef123456"`)
+
+	ix, err := GetFingerprintIndex(nil)
+	if err != nil {
+		t.Fatalf("GetFingerprintIndex: %v", err)
+	}
+	got := ix.MatchLine("x", 1, line)
+	if len(got) != 1 || got[0].Key != "STRIPE_KEY" {
+		t.Fatalf("expected STRIPE_KEY match, got %+v", got)
+	}
+
+	UpdateKey("STRIPE_KEY", "PAY_KEY", "sk_live_abcdef123456")
+	ix, _ = GetFingerprintIndex(nil)
+	if got := ix.MatchLine("x", 1, line); len(got) != 1 || got[0].Key != "PAY_KEY" {
+		t.Errorf("fingerprints should follow rename, got %+v", got)
+	}
+
+	ix, _ = GetFingerprintIndex([]string{"other"})
+	if !ix.Empty() {
+		t.Error("index for another profile should be empty")
+	}
+
+	DeleteKey("PAY_KEY")
+	ix, _ = GetFingerprintIndex(nil)
+	if got := ix.MatchLine("x", 1, line); len(got) != 0 {
+		t.Errorf("fingerprints should be removed with the key, got %+v", got)
+	}
+}
+
+func TestRotateKeyKeepsPrevious(t *testing.T) {
+	setupTestDB(t)
+
+	AddKey("TOKEN", "old-value")
+	if err := RotateKey("TOKEN", "new-value", time.Hour); err != nil {
+		t.Fatalf("RotateKey: %v", err)
+	}
+
+	k, _ := GetKey("TOKEN")
+	if k.Value != "new-value" || k.RotatedAt == 0 {
+		t.Errorf("unexpected key after rotation: %+v", k)
+	}
+	prev, err := GetPreviousValue("TOKEN")
+	if err != nil {
+		t.Fatalf("GetPreviousValue: %v", err)
+	}
+	if prev.Value != "old-value" {
+		t.Errorf("expected previous value old-value, got %q", prev.Value)
+	}
+
+	if err := RotateKey("TOKEN", "new-value", time.Hour); err == nil {
+		t.Error("rotating to the same value should fail")
+	}
+
+	// The previous value follows renames and is removed with the key
+	UpdateKey("TOKEN", "RENAMED", "new-value")
+	if _, err := GetPreviousValue("RENAMED"); err != nil {
+		t.Errorf("previous value should follow rename: %v", err)
+	}
+	DeleteKey("RENAMED")
+	if _, err := GetPreviousValue("RENAMED"); err == nil {
+		t.Error("previous value should be removed with the key")
+	}
+}
+
+func TestRotateKeyWithoutGrace(t *testing.T) {
+	setupTestDB(t)
+
+	AddKey("TOKEN", "old-value")
+	RotateKey("TOKEN", "new-value", 0)
+	if _, err := GetPreviousValue("TOKEN"); err == nil {
+		t.Error("no previous value should be kept without a grace period")
+	}
+}
+
+func TestRotateKeepsOldFingerprintForGrace(t *testing.T) {
+	setupTestDB(t)
+	line := []byte(`token = "old-value-0123456789"`)
+
+	AddKey("TOKEN", "old-value-0123456789")
+	if err := RotateKey("TOKEN", "new-value-0123456789", time.Second); err != nil {
+		t.Fatalf("RotateKey: %v", err)
+	}
+	ix, _ := GetFingerprintIndex(nil)
+	if got := ix.MatchLine("x", 1, line); len(got) != 1 || got[0].Key != "TOKEN" {
+		t.Errorf("the old value should match during the grace period, got %+v", got)
+	}
+	if got := ix.MatchLine("x", 1, []byte(`token = "new-value-0123456789"`)); len(got) != 1 {
+		t.Errorf("the new value should match, got %+v", got)
+	}
+
+	// The old fingerprint follows a rename
+	UpdateKey("TOKEN", "RENAMED", "new-value-0123456789")
+	ix, _ = GetFingerprintIndex(nil)
+	if got := ix.MatchLine("x", 1, line); len(got) != 1 || got[0].Key != "RENAMED" {
+		t.Errorf("the old value should follow the rename, got %+v", got)
+	}
+
+	time.Sleep(1100 * time.Millisecond)
+	ix, _ = GetFingerprintIndex(nil)
+	if got := ix.MatchLine("x", 1, line); len(got) != 0 {
+		t.Errorf("the old value should stop matching after the grace period, got %+v", got)
+	}
+
+	AddKey("OTHER", "first-value-0123456789")
+	RotateKey("OTHER", "second-value-0123456789", 0)
+	ix, _ = GetFingerprintIndex(nil)
+	if got := ix.MatchLine("x", 1, []byte(`"first-value-0123456789"`)); len(got) != 0 {
+		t.Errorf("without a grace period the old value should not match, got %+v", got)
+	}
+}
+
+func TestRotatedAtOnlyChangesWithValue(t *testing.T) {
+	setupTestDB(t)
+
+	d, _ := open()
+	d.Exec(`INSERT INTO keys (profile, name, value, updated_at, rotated_at) VALUES ('default', 'KEY', 'v', 100, 100)`)
+	d.Close()
+
+	AddKey("KEY", "v")
+	UpdateKey("KEY", "RENAMED", "v")
+	k, _ := GetKey("RENAMED")
+	if k.RotatedAt != 100 {
+		t.Errorf("same value should keep rotated_at, got %d", k.RotatedAt)
+	}
+
+	AddKey("RENAMED", "changed")
+	k, _ = GetKey("RENAMED")
+	if k.RotatedAt == 100 || k.LastRotated() != k.RotatedAt {
+		t.Errorf("new value should update rotated_at, got %+v", k)
+	}
+}
+
+func TestCopyKeyKeepsMetaAndHistory(t *testing.T) {
+	setupTestDB(t)
+
+	AddKey("TOKEN", "old-value")
+	RotateKey("TOKEN", "new-value", time.Hour)
+	SetKeyMeta("TOKEN", "url", "https://example.com")
+	LogAccess("TOKEN", "get", "cli")
+	src, _ := GetKey("TOKEN")
+
+	if err := CopyKey("default", "staging", "TOKEN"); err != nil {
+		t.Fatalf("CopyKey: %v", err)
+	}
+	k, err := GetKeyForProfile("staging", "TOKEN")
+	if err != nil {
+		t.Fatalf("GetKeyForProfile: %v", err)
+	}
+	if k.Value != "new-value" || k.UpdatedAt != src.UpdatedAt || k.LastRotated() != src.LastRotated() {
+		t.Errorf("copy should keep value and timestamps, got %+v want %+v", k, src)
+	}
+	if meta, _ := GetKeyMetaForProfile("staging", "TOKEN"); meta["url"] != "https://example.com" {
+		t.Errorf("copy should keep metadata, got %v", meta)
+	}
+	if prev, err := GetPreviousValueForProfile("staging", "TOKEN"); err != nil || prev.Value != "old-value" {
+		t.Errorf("copy should keep previous value, got %+v, %v", prev, err)
+	}
+	if _, err := GetKey("TOKEN"); err != nil {
+		t.Error("copy should leave the source in place")
+	}
+	if summary, _ := GetAuditSummaryForProfile("staging"); len(summary) != 0 {
+		t.Errorf("copy should not move audit entries, got %+v", summary)
+	}
+
+	if err := CopyKey("default", "default", "TOKEN"); err == nil {
+		t.Error("copying within a profile should fail")
+	}
+	if err := CopyKey("default", "staging", "MISSING"); err == nil {
+		t.Error("copying a missing key should fail")
+	}
+}
+
+func TestMoveKey(t *testing.T) {
+	setupTestDB(t)
+
+	AddKey("STRIPE_KEY", "sk_live_abcdef123456")
+	SetKeyMeta("STRIPE_KEY", "url", "https://stripe.com")
+	LogAccess("STRIPE_KEY", "get", "cli")
+	SetActiveProfile("prod")
+	AddKey("STRIPE_KEY", "sk_live_older")
+	SetActiveProfile("default")
+
+	if err := MoveKey("default", "prod", "STRIPE_KEY"); err != nil {
+		t.Fatalf("MoveKey: %v", err)
+	}
+	if _, err := GetKey("STRIPE_KEY"); err == nil {
+		t.Error("move should remove the source key")
+	}
+	if meta, _ := GetKeyMeta("STRIPE_KEY"); len(meta) != 0 {
+		t.Errorf("move should remove source metadata, got %v", meta)
+	}
+	k, _ := GetKeyForProfile("prod", "STRIPE_KEY")
+	if k == nil || k.Value != "sk_live_abcdef123456" {
+		t.Errorf("move should replace the target value, got %+v", k)
+	}
+	if summary, _ := GetAuditSummaryForProfile("default"); len(summary) != 1 || summary[0].KeyName != "STRIPE_KEY" {
+		t.Errorf("audit entries should stay with the source profile, got %+v", summary)
+	}
+	if summary, _ := GetAuditSummaryForProfile("prod"); len(summary) != 0 {
+		t.Errorf("move should not rewrite audit entries, got %+v", summary)
+	}
+
+	line := []byte(`key = "sk_live_abcdef123456"`)
+	ix, _ := GetFingerprintIndex([]string{"default"})
+	if !ix.Empty() {
+		t.Error("fingerprints should be removed from the source profile")
+	}
+	ix, _ = GetFingerprintIndex([]string{"prod"})
+	if got := ix.MatchLine("x", 1, line); len(got) != 1 {
+		t.Errorf("fingerprints should follow the key, got %+v", got)
+	}
+}
+
+func TestAPITokenCreateVerifyRevoke(t *testing.T) {
+	setupTestDB(t)
+
+	token, err := CreateAPIToken("worker", []string{"prod"}, []string{"STRIPE_*"}, 0)
+	if err != nil {
+		t.Fatalf("CreateAPIToken: %v", err)
+	}
+	if _, err := CreateAPIToken("worker", nil, nil, 0); err == nil {
+		t.Error("expected a second active token for the same client to fail")
+	}
+
+	tok, err := VerifyAPIToken(token)
+	if err != nil {
+		t.Fatalf("VerifyAPIToken: %v", err)
+	}
+	if tok.Client != "worker" || tok.LastUsedAt == 0 {
+		t.Errorf("unexpected token record: %+v", tok)
+	}
+	if !tok.AllowsKey("prod", "STRIPE_KEY") || tok.AllowsKey("prod", "OPENAI_KEY") || tok.AllowsKey("dev", "STRIPE_KEY") {
+		t.Errorf("scope not applied: %+v", tok)
+	}
+	if _, err := VerifyAPIToken(token + "x"); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("expected ErrInvalidToken for a wrong secret, got %v", err)
+	}
+
+	if err := RevokeAPIToken("worker"); err != nil {
+		t.Fatalf("RevokeAPIToken: %v", err)
+	}
+	if _, err := VerifyAPIToken(token); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("expected revoked token to be rejected, got %v", err)
+	}
+	if err := RevokeAPIToken("worker"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound revoking twice, got %v", err)
+	}
+	if _, err := CreateAPIToken("worker", nil, nil, 0); err != nil {
+		t.Errorf("expected a new token after revoking: %v", err)
+	}
+}
+
+func TestAPITokenExpires(t *testing.T) {
+	setupTestDB(t)
+
+	token, err := CreateAPIToken("ci", nil, nil, time.Hour)
+	if err != nil {
+		t.Fatalf("CreateAPIToken: %v", err)
+	}
+	if _, err := VerifyAPIToken(token); err != nil {
+		t.Fatalf("VerifyAPIToken: %v", err)
+	}
+
+	d, _ := open()
+	d.Exec(`UPDATE api_tokens SET expires_at = ?`, time.Now().Add(-time.Minute).Unix())
+	d.Close()
+	if _, err := VerifyAPIToken(token); !errors.Is(err, ErrTokenExpired) {
+		t.Errorf("expected ErrTokenExpired, got %v", err)
+	}
+	if _, err := CreateAPIToken("ci", nil, nil, 0); err != nil {
+		t.Errorf("expected an expired token not to block a new one: %v", err)
+	}
+}
+
+func TestQueryAuditLog(t *testing.T) {
+	setupTestDB(t)
+
+	d, err := open()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range []AuditEntry{
+		{Profile: "default", KeyName: "STRIPE_KEY", Action: "get", Source: "cli", AccessedAt: 100},
+		{Profile: "default", KeyName: "STRIPE_HOOK", Action: "inject", Source: "picker", AccessedAt: 200},
+		{Profile: "default", KeyName: "OPENAI_KEY", Action: "get", Source: "api:worker", AccessedAt: 300},
+		{Profile: "prod", KeyName: "STRIPE_KEY", Action: "inject", Source: "picker", AccessedAt: 400},
+	} {
+		d.Exec(`INSERT INTO audit_log (profile, key_name, action, source, accessed_at) VALUES (?, ?, ?, ?, ?)`,
+			e.Profile, e.KeyName, e.Action, e.Source, e.AccessedAt)
+	}
+	d.Close()
+
+	keys := func(q AuditQuery) string {
+		t.Helper()
+		entries, err := QueryAuditLog(q)
+		if err != nil {
+			t.Fatalf("QueryAuditLog(%+v): %v", q, err)
+		}
+		var names []string
+		for _, e := range entries {
+			names = append(names, e.Profile+"/"+e.KeyName)
+		}
+		return strings.Join(names, ",")
+	}
+
+	tests := []struct {
+		q    AuditQuery
+		want string
+	}{
+		{AuditQuery{}, "default/OPENAI_KEY,default/STRIPE_HOOK,default/STRIPE_KEY"},
+		{AuditQuery{Limit: 1}, "default/OPENAI_KEY"},
+		{AuditQuery{Since: 200, Until: 300}, "default/STRIPE_HOOK"},
+		{AuditQuery{Key: "STRIPE_*"}, "default/STRIPE_HOOK,default/STRIPE_KEY"},
+		{AuditQuery{Source: "api:*"}, "default/OPENAI_KEY"},
+		{AuditQuery{Action: "inject", Source: "picker", AllProfiles: true}, "prod/STRIPE_KEY,default/STRIPE_HOOK"},
+		{AuditQuery{Profile: "prod"}, "prod/STRIPE_KEY"},
+	}
+	for _, tt := range tests {
+		if got := keys(tt.q); got != tt.want {
+			t.Errorf("QueryAuditLog(%+v) = %s, want %s", tt.q, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,195 @@
+package db
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"time"
+
+	"github.com/stym06/keys/leak"
+)
+
+// Fingerprints are keyed hashes of stored values (and their encoded forms)
+// kept next to the keys, so that the pre-commit hook can recognise secrets
+// in a diff without reading any values. They are rewritten whenever a key
+// is added, changed or deleted. Fingerprints of a value replaced by 'keys
+// rotate' are kept, with an expiry, for as long as the previous value is.
+
+type execer interface {
+	Exec(query string, args ...any) (sql.Result, error)
+}
+
+func createFingerprintTables(d *sql.DB) error {
+	var exists int
+	err := d.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = 'fingerprints'`).Scan(&exists)
+	if err != nil {
+		return err
+	}
+	if exists > 0 {
+		if !columnExists(d, "fingerprints", "expires_at") {
+			_, err = d.Exec(`ALTER TABLE fingerprints ADD COLUMN expires_at INTEGER`)
+		}
+		return err
+	}
+
+	stmts := []string{
+		`CREATE TABLE IF NOT EXISTS settings (
+			name TEXT PRIMARY KEY,
+			value TEXT NOT NULL
+		)`,
+		`CREATE TABLE fingerprints (
+			profile TEXT NOT NULL,
+			key_name TEXT NOT NULL,
+			encoding TEXT NOT NULL,
+			length INTEGER NOT NULL,
+			hash TEXT NOT NULL,
+			expires_at INTEGER
+		)`,
+		`CREATE INDEX fingerprints_key ON fingerprints (profile, key_name)`,
+	}
+	for _, s := range stmts {
+		if _, err := d.Exec(s); err != nil {
+			return err
+		}
+	}
+
+	// Backfill fingerprints for keys stored before the table existed
+	secret, err := fingerprintKey(d)
+	if err != nil {
+		return err
+	}
+	rows, err := d.Query(`SELECT profile, name, value FROM keys`)
+	if err != nil {
+		return err
+	}
+	var all [][3]string
+	for rows.Next() {
+		var k [3]string
+		if err := rows.Scan(&k[0], &k[1], &k[2]); err != nil {
+			rows.Close()
+			return err
+		}
+		all = append(all, k)
+	}
+	rows.Close()
+	for _, k := range all {
+		if err := writeFingerprints(d, secret, k[0], k[1], k[2]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// fingerprintKey returns the vault's HMAC key, creating it on first use.
+func fingerprintKey(d *sql.DB) ([]byte, error) {
+	var stored string
+	err := d.QueryRow(`SELECT value FROM settings WHERE name = 'fingerprint_key'`).Scan(&stored)
+	if err == nil {
+		return hex.DecodeString(stored)
+	}
+	if err != sql.ErrNoRows {
+		return nil, err
+	}
+
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return nil, err
+	}
+	_, err = d.Exec(`INSERT INTO settings (name, value) VALUES ('fingerprint_key', ?)`, hex.EncodeToString(key))
+	if err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// writeFingerprints replaces the fingerprints of a key's current value.
+func writeFingerprints(x execer, secret []byte, profile, name, value string) error {
+	_, err := x.Exec(`DELETE FROM fingerprints WHERE profile = ? AND key_name = ? AND expires_at IS NULL`, profile, name)
+	if err != nil {
+		return err
+	}
+	return insertFingerprints(x, secret, profile, name, value, nil)
+}
+
+// writePreviousFingerprints replaces the fingerprints of a key's previous
+// value, which stop matching at expiresAt. An empty value only removes them.
+func writePreviousFingerprints(x execer, secret []byte, profile, name, value string, expiresAt int64) error {
+	_, err := x.Exec(`DELETE FROM fingerprints WHERE profile = ? AND key_name = ? AND expires_at IS NOT NULL`, profile, name)
+	if err != nil {
+		return err
+	}
+	if value == "" {
+		return nil
+	}
+	return insertFingerprints(x, secret, profile, name, value, expiresAt)
+}
+
+func insertFingerprints(x execer, secret []byte, profile, name, value string, expiresAt any) error {
+	if len(value) < leak.DefaultMinLength {
+		return nil
+	}
+	for _, fp := range leak.Fingerprints(secret, profile, name, value) {
+		_, err := x.Exec(
+			`INSERT INTO fingerprints (profile, key_name, encoding, length, hash, expires_at) VALUES (?, ?, ?, ?, ?, ?)`,
+			fp.Profile, fp.Name, fp.Encoding, fp.Length, fp.Hash, expiresAt,
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// deleteFingerprints removes every fingerprint of a key, current or previous.
+func deleteFingerprints(x execer, profile, name string) error {
+	_, err := x.Exec(`DELETE FROM fingerprints WHERE profile = ? AND key_name = ?`, profile, name)
+	return err
+}
+
+// GetFingerprintIndex loads the fingerprints of the given profiles, or of
+// every profile if none are given, ready for matching.
+func GetFingerprintIndex(profiles []string) (*leak.Index, error) {
+	d, err := open()
+	if err != nil {
+		return nil, err
+	}
+	defer d.Close()
+
+	secret, err := fingerprintKey(d)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `SELECT profile, key_name, encoding, length, hash FROM fingerprints WHERE (expires_at IS NULL OR expires_at > ?)`
+	args := []any{time.Now().Unix()}
+	for i, p := range profiles {
+		if i == 0 {
+			query += ` AND profile IN (?`
+		} else {
+			query += `,?`
+		}
+		args = append(args, p)
+	}
+	if len(profiles) > 0 {
+		query += `)`
+	}
+
+	rows, err := d.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var fps []leak.Fingerprint
+	for rows.Next() {
+		var fp leak.Fingerprint
+		if err := rows.Scan(&fp.Profile, &fp.Name, &fp.Encoding, &fp.Length, &fp.Hash); err != nil {
+			return nil, err
+		}
+		fps = append(fps, fp)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return leak.NewIndex(secret, fps), nil
+}
@@ -0,0 +1,85 @@
+package db
+
+// Metadata is free-form information attached to a key, such as notes or a
+// URL carried over from an import. Fields are stored per profile alongside
+// the key and follow it through renames and deletes.
+
+func SetKeyMetaForProfile(profile, name, field, value string) error {
+	d, err := open()
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+
+	if value == "" {
+		_, err = d.Exec(`DELETE FROM key_meta WHERE profile = ? AND key_name = ? AND field = ?`, profile, name, field)
+		return err
+	}
+	_, err = d.Exec(
+		`INSERT INTO key_meta (profile, key_name, field, value) VALUES (?, ?, ?, ?)
+		 ON CONFLICT(profile, key_name, field) DO UPDATE SET value = excluded.value`,
+		profile, name, field, value,
+	)
+	return err
+}
+
+func SetKeyMeta(name, field, value string) error {
+	return SetKeyMetaForProfile(GetActiveProfile(), name, field, value)
+}
+
+func GetKeyMetaForProfile(profile, name string) (map[string]string, error) {
+	d, err := open()
+	if err != nil {
+		return nil, err
+	}
+	defer d.Close()
+
+	rows, err := d.Query(`SELECT field, value FROM key_meta WHERE profile = ? AND key_name = ?`, profile, name)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	meta := make(map[string]string)
+	for rows.Next() {
+		var field, value string
+		if err := rows.Scan(&field, &value); err != nil {
+			return nil, err
+		}
+		meta[field] = value
+	}
+	return meta, rows.Err()
+}
+
+func GetKeyMeta(name string) (map[string]string, error) {
+	return GetKeyMetaForProfile(GetActiveProfile(), name)
+}
+
+// GetAllKeyMetaForProfile returns the metadata of every key in profile, by
+// key name.
+func GetAllKeyMetaForProfile(profile string) (map[string]map[string]string, error) {
+	d, err := open()
+	if err != nil {
+		return nil, err
+	}
+	defer d.Close()
+
+	rows, err := d.Query(`SELECT key_name, field, value FROM key_meta WHERE profile = ?`, profile)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	meta := make(map[string]map[string]string)
+	for rows.Next() {
+		var name, field, value string
+		if err := rows.Scan(&name, &field, &value); err != nil {
+			return nil, err
+		}
+		if meta[name] == nil {
+			meta[name] = make(map[string]string)
+		}
+		meta[name][field] = value
+	}
+	return meta, rows.Err()
+}
@@ -0,0 +1,118 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// Rotation replaces a key's value but keeps the previous value readable for
+// a grace period, so that running deployments can move to the new value
+// before the old one stops resolving.
+
+type PreviousValue struct {
+	Value     string
+	RotatedAt int64
+	ExpiresAt int64
+}
+
+func RotateKeyForProfile(profile, name, value string, grace time.Duration) error {
+	d, err := open()
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+
+	secret, err := fingerprintKey(d)
+	if err != nil {
+		return err
+	}
+
+	tx, err := d.Begin()
+	if err != nil {
+		return err
+	}
+
+	var old string
+	err = tx.QueryRow(`SELECT value FROM keys WHERE profile = ? AND name = ?`, profile, name).Scan(&old)
+	if err == sql.ErrNoRows {
+		tx.Rollback()
+		return fmt.Errorf("key %q %w", name, ErrNotFound)
+	}
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	if old == value {
+		tx.Rollback()
+		return fmt.Errorf("new value for %s is the same as the current one", name)
+	}
+
+	now := time.Now()
+	if _, err := tx.Exec(`DELETE FROM previous_values WHERE profile = ? AND (key_name = ? OR expires_at <= ?)`, profile, name, now.Unix()); err != nil {
+		tx.Rollback()
+		return err
+	}
+	// The pre-commit hook keeps blocking the old value while it still works
+	previous := ""
+	if grace > 0 {
+		_, err = tx.Exec(
+			`INSERT INTO previous_values (profile, key_name, value, rotated_at, expires_at) VALUES (?, ?, ?, ?, ?)`,
+			profile, name, old, now.Unix(), now.Add(grace).Unix(),
+		)
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+		previous = old
+	}
+	if err := writePreviousFingerprints(tx, secret, profile, name, previous, now.Add(grace).Unix()); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	_, err = tx.Exec(`UPDATE keys SET value = ?, updated_at = ?, rotated_at = ? WHERE profile = ? AND name = ?`,
+		value, now.Unix(), now.Unix(), profile, name)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err := writeFingerprints(tx, secret, profile, name, value); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+func RotateKey(name, value string, grace time.Duration) error {
+	return RotateKeyForProfile(GetActiveProfile(), name, value, grace)
+}
+
+// GetPreviousValueForProfile returns the value a key had before its last
+// rotation, as long as the grace period has not ended.
+func GetPreviousValueForProfile(profile, name string) (*PreviousValue, error) {
+	d, err := open()
+	if err != nil {
+		return nil, err
+	}
+	defer d.Close()
+
+	var p PreviousValue
+	err = d.QueryRow(`SELECT value, rotated_at, expires_at FROM previous_values WHERE profile = ? AND key_name = ?`, profile, name).
+		Scan(&p.Value, &p.RotatedAt, &p.ExpiresAt)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("no previous value for %q", name)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if time.Now().Unix() >= p.ExpiresAt {
+		_, _ = d.Exec(`DELETE FROM previous_values WHERE profile = ? AND key_name = ?`, profile, name)
+		return nil, fmt.Errorf("previous value for %q expired %s", name, time.Unix(p.ExpiresAt, 0).Format("2006-01-02 15:04"))
+	}
+	return &p, nil
+}
+
+func GetPreviousValue(name string) (*PreviousValue, error) {
+	return GetPreviousValueForProfile(GetActiveProfile(), name)
+}
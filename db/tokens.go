@@ -0,0 +1,225 @@
+package db
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+	"time"
+)
+
+// API tokens let local applications read keys through 'keys serve-api'.
+// Only a hash of each token is stored; the token itself is shown once when
+// it is created. Each token belongs to a named client and can be limited
+// to profiles and key name globs.
+
+// ErrInvalidToken is returned for unknown, malformed or revoked tokens.
+var ErrInvalidToken = errors.New("invalid or revoked API token")
+
+// ErrTokenExpired is returned for tokens past their expiry time.
+var ErrTokenExpired = errors.New("API token expired")
+
+const tokenPrefix = "keys_"
+
+type APIToken struct {
+	ID         string
+	Client     string
+	Profiles   []string // empty allows every profile
+	Patterns   []string // empty allows every key
+	CreatedAt  int64
+	LastUsedAt int64
+	RevokedAt  int64
+	ExpiresAt  int64 // 0 if the token does not expire
+}
+
+func createAPITokenTable(d *sql.DB) error {
+	_, err := d.Exec(`CREATE TABLE IF NOT EXISTS api_tokens (
+		id TEXT PRIMARY KEY,
+		client TEXT NOT NULL,
+		hash TEXT NOT NULL,
+		profiles TEXT NOT NULL DEFAULT '',
+		patterns TEXT NOT NULL DEFAULT '',
+		created_at INTEGER NOT NULL,
+		last_used_at INTEGER NOT NULL DEFAULT 0,
+		revoked_at INTEGER NOT NULL DEFAULT 0,
+		expires_at INTEGER NOT NULL DEFAULT 0
+	)`)
+	return err
+}
+
+// AllowsProfile reports whether the token may read keys from profile.
+func (t *APIToken) AllowsProfile(profile string) bool {
+	if len(t.Profiles) == 0 {
+		return true
+	}
+	for _, p := range t.Profiles {
+		if p == profile {
+			return true
+		}
+	}
+	return false
+}
+
+// AllowsKey reports whether the token may read the named key in profile.
+func (t *APIToken) AllowsKey(profile, name string) bool {
+	if !t.AllowsProfile(profile) {
+		return false
+	}
+	if len(t.Patterns) == 0 {
+		return true
+	}
+	for _, p := range t.Patterns {
+		if ok, _ := path.Match(p, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func hashToken(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+func splitList(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(s, ",")
+}
+
+// CreateAPIToken creates a token for client and returns it. Client names
+// are unique among tokens that have not been revoked or expired. A token with a ttl
+// of zero does not expire.
+func CreateAPIToken(client string, profiles, patterns []string, ttl time.Duration) (string, error) {
+	for _, p := range patterns {
+		if _, err := path.Match(p, ""); err != nil {
+			return "", fmt.Errorf("invalid pattern %q", p)
+		}
+	}
+	d, err := open()
+	if err != nil {
+		return "", err
+	}
+	defer d.Close()
+
+	var n int
+	if err := d.QueryRow(`SELECT COUNT(*) FROM api_tokens WHERE client = ? AND revoked_at = 0 AND (expires_at = 0 OR expires_at > ?)`,
+		client, time.Now().Unix()).Scan(&n); err != nil {
+		return "", err
+	}
+	if n > 0 {
+		return "", fmt.Errorf("client %q already has a token; revoke it first", client)
+	}
+
+	b := make([]byte, 28)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	id, secret := hex.EncodeToString(b[:4]), hex.EncodeToString(b[4:])
+	now := time.Now()
+	var expires int64
+	if ttl > 0 {
+		expires = now.Add(ttl).Unix()
+	}
+	_, err = d.Exec(
+		`INSERT INTO api_tokens (id, client, hash, profiles, patterns, created_at, expires_at) VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		id, client, hashToken(secret), strings.Join(profiles, ","), strings.Join(patterns, ","), now.Unix(), expires,
+	)
+	if err != nil {
+		return "", err
+	}
+	return tokenPrefix + id + "_" + secret, nil
+}
+
+// ListAPITokens returns every token, including revoked ones, oldest first.
+func ListAPITokens() ([]APIToken, error) {
+	d, err := open()
+	if err != nil {
+		return nil, err
+	}
+	defer d.Close()
+
+	rows, err := d.Query(`SELECT id, client, profiles, patterns, created_at, last_used_at, revoked_at, expires_at FROM api_tokens ORDER BY created_at, client`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tokens []APIToken
+	for rows.Next() {
+		var t APIToken
+		var profiles, patterns string
+		if err := rows.Scan(&t.ID, &t.Client, &profiles, &patterns, &t.CreatedAt, &t.LastUsedAt, &t.RevokedAt, &t.ExpiresAt); err != nil {
+			return nil, err
+		}
+		t.Profiles, t.Patterns = splitList(profiles), splitList(patterns)
+		tokens = append(tokens, t)
+	}
+	return tokens, rows.Err()
+}
+
+// RevokeAPIToken revokes the active token of a client, given by client
+// name or token ID. It takes effect on the next request, also for a
+// running server.
+func RevokeAPIToken(clientOrID string) error {
+	d, err := open()
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+
+	res, err := d.Exec(`UPDATE api_tokens SET revoked_at = ? WHERE (client = ? OR id = ?) AND revoked_at = 0`,
+		time.Now().Unix(), clientOrID, clientOrID)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return fmt.Errorf("token %q %w", clientOrID, ErrNotFound)
+	}
+	return nil
+}
+
+// VerifyAPIToken returns the token's record if it is valid, not revoked and
+// not expired, and records that it was used.
+func VerifyAPIToken(token string) (*APIToken, error) {
+	id, secret, ok := strings.Cut(strings.TrimPrefix(token, tokenPrefix), "_")
+	if !ok || !strings.HasPrefix(token, tokenPrefix) {
+		return nil, ErrInvalidToken
+	}
+	d, err := open()
+	if err != nil {
+		return nil, err
+	}
+	defer d.Close()
+
+	var t APIToken
+	var hash, profiles, patterns string
+	err = d.QueryRow(`SELECT id, client, hash, profiles, patterns, created_at, revoked_at, expires_at FROM api_tokens WHERE id = ?`, id).
+		Scan(&t.ID, &t.Client, &hash, &profiles, &patterns, &t.CreatedAt, &t.RevokedAt, &t.ExpiresAt)
+	if err == sql.ErrNoRows {
+		return nil, ErrInvalidToken
+	}
+	if err != nil {
+		return nil, err
+	}
+	if t.RevokedAt != 0 || subtle.ConstantTimeCompare([]byte(hashToken(secret)), []byte(hash)) != 1 {
+		return nil, ErrInvalidToken
+	}
+	if t.ExpiresAt != 0 && time.Now().Unix() >= t.ExpiresAt {
+		return nil, ErrTokenExpired
+	}
+	t.Profiles, t.Patterns = splitList(profiles), splitList(patterns)
+	t.LastUsedAt = time.Now().Unix()
+	_, err = d.Exec(`UPDATE api_tokens SET last_used_at = ? WHERE id = ?`, t.LastUsedAt, t.ID)
+	return &t, err
+}
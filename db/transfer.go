@@ -0,0 +1,104 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// CopyKey copies a key to another profile along with its metadata, its
+// timestamps and any previous value kept by rotation. A key of the same
+// name in toProfile is replaced.
+func CopyKey(fromProfile, toProfile, name string) error {
+	return transferKey(fromProfile, toProfile, name, false)
+}
+
+// MoveKey copies a key to another profile like CopyKey, then removes it
+// from fromProfile. Its audit log entries stay with fromProfile.
+func MoveKey(fromProfile, toProfile, name string) error {
+	return transferKey(fromProfile, toProfile, name, true)
+}
+
+func transferKey(fromProfile, toProfile, name string, move bool) error {
+	if fromProfile == toProfile {
+		return fmt.Errorf("source and target profile are both %q", fromProfile)
+	}
+	d, err := open()
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+
+	secret, err := fingerprintKey(d)
+	if err != nil {
+		return err
+	}
+
+	tx, err := d.Begin()
+	if err != nil {
+		return err
+	}
+
+	var value string
+	var updatedAt, rotatedAt int64
+	err = tx.QueryRow(`SELECT value, COALESCE(updated_at, 0), COALESCE(rotated_at, updated_at, 0) FROM keys WHERE profile = ? AND name = ?`, fromProfile, name).
+		Scan(&value, &updatedAt, &rotatedAt)
+	if err == sql.ErrNoRows {
+		tx.Rollback()
+		return fmt.Errorf("key %q %w in profile %q", name, ErrNotFound, fromProfile)
+	}
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	stmts := []struct {
+		query string
+		args  []any
+	}{
+		// Replace whatever the target profile had under this name
+		{`DELETE FROM keys WHERE profile = ? AND name = ?`, []any{toProfile, name}},
+		{`DELETE FROM key_meta WHERE profile = ? AND key_name = ?`, []any{toProfile, name}},
+		{`DELETE FROM previous_values WHERE profile = ? AND key_name = ?`, []any{toProfile, name}},
+		{`INSERT INTO keys (profile, name, value, updated_at, rotated_at) VALUES (?, ?, ?, ?, ?)`,
+			[]any{toProfile, name, value, updatedAt, rotatedAt}},
+		{`INSERT INTO key_meta (profile, key_name, field, value)
+			SELECT ?, key_name, field, value FROM key_meta WHERE profile = ? AND key_name = ?`,
+			[]any{toProfile, fromProfile, name}},
+		{`INSERT INTO previous_values (profile, key_name, value, rotated_at, expires_at)
+			SELECT ?, key_name, value, rotated_at, expires_at FROM previous_values WHERE profile = ? AND key_name = ?`,
+			[]any{toProfile, fromProfile, name}},
+		{`DELETE FROM fingerprints WHERE profile = ? AND key_name = ? AND expires_at IS NOT NULL`, []any{toProfile, name}},
+		{`INSERT INTO fingerprints (profile, key_name, encoding, length, hash, expires_at)
+			SELECT ?, key_name, encoding, length, hash, expires_at FROM fingerprints
+			WHERE profile = ? AND key_name = ? AND expires_at IS NOT NULL`,
+			[]any{toProfile, fromProfile, name}},
+	}
+	if move {
+		stmts = append(stmts, []struct {
+			query string
+			args  []any
+		}{
+			{`DELETE FROM keys WHERE profile = ? AND name = ?`, []any{fromProfile, name}},
+			{`DELETE FROM key_meta WHERE profile = ? AND key_name = ?`, []any{fromProfile, name}},
+			{`DELETE FROM previous_values WHERE profile = ? AND key_name = ?`, []any{fromProfile, name}},
+		}...)
+	}
+	for _, s := range stmts {
+		if _, err := tx.Exec(s.query, s.args...); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	if err := writeFingerprints(tx, secret, toProfile, name, value); err != nil {
+		tx.Rollback()
+		return err
+	}
+	if move {
+		if err := deleteFingerprints(tx, fromProfile, name); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,97 @@
+package db
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Project manifests read by the shell hook only load once allowed. Each
+// allowed manifest is recorded in ~/.keys/trusted with the hash of its
+// contents, so editing the file revokes the trust.
+
+func trustPath() (string, error) {
+	path, err := configPath()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(filepath.Dir(path), "trusted"), nil
+}
+
+// readTrusted returns the trusted manifests as path → content hash.
+func readTrusted() (map[string]string, error) {
+	path, err := trustPath()
+	if err != nil {
+		return nil, err
+	}
+	trusted := make(map[string]string)
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return trusted, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		hash, file, ok := strings.Cut(scanner.Text(), " ")
+		if ok {
+			trusted[file] = hash
+		}
+	}
+	return trusted, scanner.Err()
+}
+
+func writeTrusted(trusted map[string]string) error {
+	path, err := trustPath()
+	if err != nil {
+		return err
+	}
+	files := make([]string, 0, len(trusted))
+	for file := range trusted {
+		files = append(files, file)
+	}
+	sort.Strings(files)
+	var b strings.Builder
+	for _, file := range files {
+		b.WriteString(trusted[file] + " " + file + "\n")
+	}
+	return os.WriteFile(path, []byte(b.String()), 0600)
+}
+
+// IsTrusted reports whether the manifest at path was allowed with the
+// contents that hash to hash.
+func IsTrusted(path, hash string) (bool, error) {
+	trusted, err := readTrusted()
+	if err != nil {
+		return false, err
+	}
+	return trusted[path] == hash, nil
+}
+
+// Trust allows the manifest at path with its current contents.
+func Trust(path, hash string) error {
+	trusted, err := readTrusted()
+	if err != nil {
+		return err
+	}
+	trusted[path] = hash
+	return writeTrusted(trusted)
+}
+
+// Untrust revokes the manifest at path. It reports whether it was trusted.
+func Untrust(path string) (bool, error) {
+	trusted, err := readTrusted()
+	if err != nil {
+		return false, err
+	}
+	if _, ok := trusted[path]; !ok {
+		return false, nil
+	}
+	delete(trusted, path)
+	return true, writeTrusted(trusted)
+}
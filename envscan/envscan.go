@@ -0,0 +1,167 @@
+// Package envscan finds environment variable reads in source code.
+package envscan
+
+import (
+	"bufio"
+	"bytes"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Ref is a single read of an environment variable.
+type Ref struct {
+	Name string
+	File string
+	Line int
+}
+
+const ident = `([A-Za-z_][A-Za-z0-9_]*)`
+
+var (
+	goPatterns = []*regexp.Regexp{
+		regexp.MustCompile(`os\.(?:Getenv|LookupEnv)\(\s*"` + ident + `"\s*\)`),
+	}
+	jsPatterns = []*regexp.Regexp{
+		regexp.MustCompile(`process\.env\.` + ident),
+		regexp.MustCompile(`process\.env\[\s*['"` + "`" + `]` + ident + `['"` + "`" + `]\s*\]`),
+	}
+	pyPatterns = []*regexp.Regexp{
+		regexp.MustCompile(`os\.environ\[\s*['"]` + ident + `['"]\s*\]`),
+		regexp.MustCompile(`os\.environ\.get\(\s*['"]` + ident + `['"]`),
+		regexp.MustCompile(`os\.getenv\(\s*['"]` + ident + `['"]`),
+	}
+	rubyPatterns = []*regexp.Regexp{
+		regexp.MustCompile(`ENV\[\s*['"]` + ident + `['"]\s*\]`),
+		regexp.MustCompile(`ENV\.fetch\(\s*['"]` + ident + `['"]`),
+	}
+	composePatterns = []*regexp.Regexp{
+		regexp.MustCompile(`\$\{` + ident + `(?:[:?+-][^}]*)?\}`),
+	}
+)
+
+// skipDirs are never descended into.
+var skipDirs = map[string]bool{
+	".git":         true,
+	"node_modules": true,
+	"vendor":       true,
+	".venv":        true,
+	"venv":         true,
+	"__pycache__":  true,
+}
+
+// patternsFor returns the patterns that apply to a file, or nil if the file
+// is not a supported source type.
+func patternsFor(path string) []*regexp.Regexp {
+	base := strings.ToLower(filepath.Base(path))
+	if isComposeFile(base) {
+		return composePatterns
+	}
+	switch filepath.Ext(base) {
+	case ".go":
+		return goPatterns
+	case ".js", ".jsx", ".mjs", ".cjs", ".ts", ".tsx", ".mts", ".cts":
+		return jsPatterns
+	case ".py":
+		return pyPatterns
+	case ".rb", ".rake":
+		return rubyPatterns
+	}
+	return nil
+}
+
+func isComposeFile(base string) bool {
+	ext := filepath.Ext(base)
+	if ext != ".yml" && ext != ".yaml" {
+		return false
+	}
+	name := strings.TrimSuffix(base, ext)
+	return name == "compose" || name == "docker-compose" ||
+		strings.HasPrefix(name, "docker-compose.") || strings.HasPrefix(name, "compose.")
+}
+
+// ScanFile returns the environment variable reads in a single file.
+func ScanFile(path string, data []byte) []Ref {
+	patterns := patternsFor(path)
+	if patterns == nil {
+		return nil
+	}
+	var refs []Ref
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := scanner.Text()
+		for _, re := range patterns {
+			for _, m := range re.FindAllStringSubmatch(line, -1) {
+				refs = append(refs, Ref{Name: m[1], File: path, Line: lineNo})
+			}
+		}
+	}
+	return refs
+}
+
+// Scan walks each path and returns every environment variable read found,
+// ordered by file and line. A trailing "/..." (as in "./...") is accepted
+// and means the same as the directory itself.
+func Scan(paths []string) ([]Ref, error) {
+	var refs []Ref
+	for _, root := range paths {
+		root = strings.TrimSuffix(root, "...")
+		if root != "/" {
+			root = strings.TrimSuffix(root, "/")
+		}
+		if root == "" {
+			root = "."
+		}
+		err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.IsDir() {
+				if path != root && skipDirs[d.Name()] {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if patternsFor(path) == nil {
+				return nil
+			}
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return err
+			}
+			refs = append(refs, ScanFile(path, data)...)
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	sort.SliceStable(refs, func(i, j int) bool {
+		if refs[i].File != refs[j].File {
+			return refs[i].File < refs[j].File
+		}
+		return refs[i].Line < refs[j].Line
+	})
+	return refs, nil
+}
+
+// system holds variables set by the OS, shell or runtime rather than by the
+// application's configuration.
+var system = map[string]bool{
+	"HOME": true, "PATH": true, "USER": true, "SHELL": true, "PWD": true,
+	"TMPDIR": true, "TEMP": true, "TMP": true, "LANG": true, "TERM": true,
+	"HOSTNAME": true, "EDITOR": true, "CI": true, "NODE_ENV": true,
+	"GOPATH": true, "GOOS": true, "GOARCH": true, "XDG_CONFIG_HOME": true,
+}
+
+// IsSystem reports whether name is a well-known variable provided by the
+// environment rather than a secret.
+func IsSystem(name string) bool {
+	return system[name]
+}
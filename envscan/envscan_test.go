@@ -0,0 +1,88 @@
+package envscan
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func names(refs []Ref) []string {
+	var out []string
+	for _, r := range refs {
+		out = append(out, r.Name)
+	}
+	return out
+}
+
+func assertNames(t *testing.T, got []Ref, want ...string) {
+	t.Helper()
+	g := names(got)
+	if len(g) != len(want) {
+		t.Fatalf("got %v, want %v", g, want)
+	}
+	for i := range want {
+		if g[i] != want[i] {
+			t.Fatalf("got %v, want %v", g, want)
+		}
+	}
+}
+
+func TestScanFileGo(t *testing.T) {
+	src := `key := os.Getenv("OPENAI_KEY")
+v, ok := os.LookupEnv( "DB_URL" )
+other := os.Getenv(name)`
+	assertNames(t, ScanFile("main.go", []byte(src)), "OPENAI_KEY", "DB_URL")
+}
+
+func TestScanFileJS(t *testing.T) {
+	src := "const a = process.env.STRIPE_KEY\nconst b = process.env['SENTRY_DSN'] || process.env[`X`]"
+	assertNames(t, ScanFile("app.ts", []byte(src)), "STRIPE_KEY", "SENTRY_DSN", "X")
+}
+
+func TestScanFilePython(t *testing.T) {
+	src := `a = os.environ["A"]
+b = os.environ.get('B', 'x')
+c = os.getenv("C")`
+	assertNames(t, ScanFile("settings.py", []byte(src)), "A", "B", "C")
+}
+
+func TestScanFileRuby(t *testing.T) {
+	src := `key = ENV["RAILS_KEY"]
+other = ENV.fetch('OTHER')`
+	assertNames(t, ScanFile("config.rb", []byte(src)), "RAILS_KEY", "OTHER")
+}
+
+func TestScanFileCompose(t *testing.T) {
+	src := `services:
+  web:
+    environment:
+      - DATABASE_URL=${DATABASE_URL}
+      - PORT=${PORT:-8080}
+      - TOKEN=${TOKEN?required}`
+	assertNames(t, ScanFile("docker-compose.prod.yml", []byte(src)), "DATABASE_URL", "PORT", "TOKEN")
+	if refs := ScanFile("values.yml", []byte(src)); refs != nil {
+		t.Errorf("non-compose YAML should be ignored, got %v", names(refs))
+	}
+}
+
+func TestScanWalksAndSkips(t *testing.T) {
+	root := t.TempDir()
+	write := func(rel, content string) {
+		path := filepath.Join(root, rel)
+		os.MkdirAll(filepath.Dir(path), 0755)
+		os.WriteFile(path, []byte(content), 0644)
+	}
+	write("b/main.go", `os.Getenv("B_KEY")`)
+	write("a/app.js", `process.env.A_KEY`)
+	write("node_modules/lib/index.js", `process.env.IGNORED`)
+	write("README.md", `os.Getenv("NOT_CODE")`)
+
+	refs, err := Scan([]string{root + "/..."})
+	if err != nil {
+		t.Fatalf("Scan: %v", err)
+	}
+	assertNames(t, refs, "A_KEY", "B_KEY")
+	if refs[0].Line != 1 || filepath.Base(refs[0].File) != "app.js" {
+		t.Errorf("unexpected location: %+v", refs[0])
+	}
+}
@@ -0,0 +1,63 @@
+package importer
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+type bitwardenExport struct {
+	Encrypted bool `json:"encrypted"`
+	Items     []struct {
+		Name  string `json:"name"`
+		Notes string `json:"notes"`
+		Login *struct {
+			Username string `json:"username"`
+			Password string `json:"password"`
+			URIs     []struct {
+				URI string `json:"uri"`
+			} `json:"uris"`
+		} `json:"login"`
+		Fields []struct {
+			Name  string `json:"name"`
+			Value string `json:"value"`
+			Type  int    `json:"type"`
+		} `json:"fields"`
+	} `json:"items"`
+}
+
+// Bitwarden custom field types.
+const bitwardenFieldHidden = 1
+
+func parseBitwarden(data []byte) ([]Entry, error) {
+	var export bitwardenExport
+	if err := json.Unmarshal(data, &export); err != nil {
+		return nil, fmt.Errorf("invalid Bitwarden export: %w", err)
+	}
+	if export.Encrypted {
+		return nil, fmt.Errorf("encrypted Bitwarden exports are not supported; export as unencrypted JSON")
+	}
+
+	var entries []Entry
+	for _, item := range export.Items {
+		e := Entry{Name: item.Name, Meta: map[string]string{MetaNotes: item.Notes}}
+		if item.Login != nil {
+			e.Value = item.Login.Password
+			e.Meta[MetaUsername] = item.Login.Username
+			if len(item.Login.URIs) > 0 {
+				e.Meta[MetaURL] = item.Login.URIs[0].URI
+			}
+		}
+		// Items without a login password may still hold the secret in a
+		// hidden custom field, e.g. an "API key" secure note.
+		if e.Value == "" {
+			for _, f := range item.Fields {
+				if f.Type == bitwardenFieldHidden && f.Value != "" {
+					e.Value = f.Value
+					break
+				}
+			}
+		}
+		entries = append(entries, e)
+	}
+	return entries, nil
+}
@@ -0,0 +1,113 @@
+package importer
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"strings"
+)
+
+// Column aliases tried for each entry field, in order. Matching is
+// case-insensitive.
+var (
+	onePasswordColumns = map[string][]string{
+		"name":       {"title", "name"},
+		"value":      {"password", "credential"},
+		MetaURL:      {"url", "website", "urls"},
+		MetaUsername: {"username"},
+		MetaNotes:    {"notes", "notesplain"},
+	}
+
+	genericColumns = map[string][]string{
+		"name":       {"name", "key", "title"},
+		"value":      {"value", "secret", "password"},
+		MetaURL:      {"url"},
+		MetaUsername: {"username", "user"},
+		MetaNotes:    {"notes", "note", "description"},
+	}
+)
+
+func isOnePasswordCSV(data []byte) bool {
+	header, err := csv.NewReader(bytes.NewReader(data)).Read()
+	if err != nil {
+		return false
+	}
+	cols := columnIndex(header)
+	_, title := cols["title"]
+	_, password := cols["password"]
+	return title && password
+}
+
+func columnIndex(header []string) map[string]int {
+	cols := make(map[string]int, len(header))
+	for i, h := range header {
+		h = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(h, "\ufeff")))
+		if _, ok := cols[h]; !ok {
+			cols[h] = i
+		}
+	}
+	return cols
+}
+
+// parseCSV reads a CSV export with a header row. mapping overrides the
+// default column aliases, e.g. {"name": "Service", "value": "Token"}.
+func parseCSV(data []byte, defaults map[string][]string, mapping map[string]string) ([]Entry, error) {
+	r := csv.NewReader(bytes.NewReader(data))
+	r.FieldsPerRecord = -1
+	records, err := r.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, nil
+	}
+
+	cols := columnIndex(records[0])
+	field := make(map[string]int)
+	for f, aliases := range defaults {
+		for _, a := range aliases {
+			if i, ok := cols[a]; ok {
+				field[f] = i
+				break
+			}
+		}
+	}
+	for f, col := range mapping {
+		if _, ok := defaults[f]; !ok {
+			return nil, fmt.Errorf("unknown field %q in column mapping (use name, value, url, username, notes)", f)
+		}
+		i, ok := cols[strings.ToLower(strings.TrimSpace(col))]
+		if !ok {
+			return nil, fmt.Errorf("column %q not found in CSV header", col)
+		}
+		field[f] = i
+	}
+	if _, ok := field["name"]; !ok {
+		return nil, fmt.Errorf("no name column found; use --map name=<column>")
+	}
+	if _, ok := field["value"]; !ok {
+		return nil, fmt.Errorf("no value column found; use --map value=<column>")
+	}
+
+	get := func(rec []string, f string) string {
+		i, ok := field[f]
+		if !ok || i >= len(rec) {
+			return ""
+		}
+		return rec[i]
+	}
+
+	var entries []Entry
+	for _, rec := range records[1:] {
+		entries = append(entries, Entry{
+			Name:  get(rec, "name"),
+			Value: get(rec, "value"),
+			Meta: map[string]string{
+				MetaURL:      get(rec, MetaURL),
+				MetaUsername: get(rec, MetaUsername),
+				MetaNotes:    get(rec, MetaNotes),
+			},
+		})
+	}
+	return entries, nil
+}
@@ -0,0 +1,68 @@
+package importer
+
+import (
+	"bufio"
+	"bytes"
+	"regexp"
+	"strings"
+)
+
+// dotenvBare matches values that need no quoting in a .env file.
+var dotenvBare = regexp.MustCompile(`^[A-Za-z0-9_.,:/@%+=-]*$`)
+
+var (
+	dotenvEscaper   = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\r", `\r`)
+	dotenvUnescaper = strings.NewReplacer(`\\`, `\`, `\"`, `"`, `\n`, "\n", `\r`, "\r")
+)
+
+// QuoteDotenv formats value for a KEY=VALUE line so that it reads back
+// unchanged. Plain values are left bare, others are single-quoted, and
+// values with quotes or line breaks are double-quoted with \n, \r, \" and
+// \\ escapes.
+func QuoteDotenv(value string) string {
+	switch {
+	case dotenvBare.MatchString(value):
+		return value
+	case !strings.ContainsAny(value, "'\r\n"):
+		return "'" + value + "'"
+	}
+	return `"` + dotenvEscaper.Replace(value) + `"`
+}
+
+func parseDotenv(data []byte) ([]Entry, error) {
+	var entries []Entry
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		// Handle export prefix
+		line = strings.TrimPrefix(line, "export ")
+
+		parts := strings.SplitN(line, "=", 2)
+		if len(parts) != 2 {
+			continue
+		}
+
+		name := strings.TrimSpace(parts[0])
+		value := strings.TrimSpace(parts[1])
+
+		// Strip surrounding quotes; double-quoted values may hold escapes
+		if len(value) >= 2 {
+			switch {
+			case value[0] == '"' && value[len(value)-1] == '"':
+				value = dotenvUnescaper.Replace(value[1 : len(value)-1])
+			case value[0] == '\'' && value[len(value)-1] == '\'':
+				value = value[1 : len(value)-1]
+			}
+		}
+
+		if name == "" {
+			continue
+		}
+		entries = append(entries, Entry{Name: name, Value: value})
+	}
+	return entries, scanner.Err()
+}
@@ -0,0 +1,172 @@
+// Package importer converts exports from .env files, password managers and
+// other secret stores into keys.
+package importer
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/stym06/keys/k8s"
+)
+
+// Supported import formats.
+const (
+	FormatDotenv       = "dotenv"
+	Format1PasswordCSV = "1password-csv"
+	Format1PUX         = "1pux"
+	FormatBitwarden    = "bitwarden"
+	FormatKeePass      = "keepass"
+	FormatCSV          = "csv"
+	FormatK8sSecret    = "k8s-secret"
+)
+
+// Formats lists every format accepted by Parse.
+var Formats = []string{
+	FormatDotenv,
+	Format1PasswordCSV,
+	Format1PUX,
+	FormatBitwarden,
+	FormatKeePass,
+	FormatCSV,
+	FormatK8sSecret,
+}
+
+// Metadata fields set on imported entries.
+const (
+	MetaNotes    = "notes"
+	MetaURL      = "url"
+	MetaUsername = "username"
+)
+
+// Entry is a single secret read from an export.
+type Entry struct {
+	Name  string
+	Value string
+	Meta  map[string]string
+}
+
+// Options tune how an export is read.
+type Options struct {
+	// Mapping maps entry fields (name, value, notes, url, username) to
+	// column headers for CSV imports.
+	Mapping map[string]string
+}
+
+// Parse reads data in the given format. Entry names from password managers
+// are converted to valid key names and de-duplicated.
+func Parse(format string, data []byte, opts Options) ([]Entry, error) {
+	var entries []Entry
+	var err error
+
+	switch format {
+	case FormatDotenv:
+		// .env names are already key names; keep them as written.
+		return parseDotenv(data)
+	case FormatK8sSecret:
+		return parseK8sSecret(data)
+	case Format1PasswordCSV:
+		entries, err = parseCSV(data, onePasswordColumns, opts.Mapping)
+	case Format1PUX:
+		entries, err = parse1PUX(data)
+	case FormatBitwarden:
+		entries, err = parseBitwarden(data)
+	case FormatKeePass:
+		entries, err = parseKeePass(data)
+	case FormatCSV:
+		entries, err = parseCSV(data, genericColumns, opts.Mapping)
+	default:
+		return nil, fmt.Errorf("unknown format %q (supported: %s)", format, strings.Join(Formats, ", "))
+	}
+	if err != nil {
+		return nil, err
+	}
+	return normalize(entries), nil
+}
+
+// Detect guesses the format of an export from its file name and contents.
+func Detect(path string, data []byte) string {
+	trimmed := bytes.TrimSpace(data)
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".1pux":
+		return Format1PUX
+	case ".xml":
+		return FormatKeePass
+	case ".json":
+		return FormatBitwarden
+	case ".csv":
+		if isOnePasswordCSV(data) {
+			return Format1PasswordCSV
+		}
+		return FormatCSV
+	case ".yaml", ".yml":
+		return FormatK8sSecret
+	}
+
+	switch {
+	case bytes.HasPrefix(data, []byte("PK\x03\x04")):
+		return Format1PUX
+	case bytes.Contains(trimmed[:min(len(trimmed), 512)], []byte("<KeePassFile")):
+		return FormatKeePass
+	case bytes.HasPrefix(trimmed, []byte("{")):
+		return FormatBitwarden
+	case k8s.IsSecretManifest(data):
+		return FormatK8sSecret
+	}
+	return FormatDotenv
+}
+
+// KeyName converts an item title such as "GitHub Token (work)" into a valid
+// environment variable name such as GITHUB_TOKEN_WORK.
+func KeyName(title string) string {
+	var b strings.Builder
+	underscore := false
+	for _, r := range strings.ToUpper(title) {
+		switch {
+		case r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+			b.WriteRune(r)
+			underscore = false
+		default:
+			if b.Len() > 0 && !underscore {
+				b.WriteByte('_')
+				underscore = true
+			}
+		}
+	}
+	name := strings.TrimRight(b.String(), "_")
+	if name == "" {
+		return ""
+	}
+	if name[0] >= '0' && name[0] <= '9' {
+		name = "_" + name
+	}
+	return name
+}
+
+// normalize converts titles to key names, drops entries without a name or
+// value and suffixes duplicate names with _2, _3, ...
+func normalize(entries []Entry) []Entry {
+	seen := make(map[string]int)
+	var out []Entry
+	for _, e := range entries {
+		name := KeyName(e.Name)
+		if name == "" || e.Value == "" {
+			continue
+		}
+		seen[name]++
+		if n := seen[name]; n > 1 {
+			name += "_" + strconv.Itoa(n)
+		}
+		e.Name = name
+		for k, v := range e.Meta {
+			if strings.TrimSpace(v) == "" {
+				delete(e.Meta, k)
+			}
+		}
+		out = append(out, e)
+	}
+	return out
+}
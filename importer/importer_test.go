@@ -0,0 +1,286 @@
+package importer
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"testing"
+)
+
+func TestKeyName(t *testing.T) {
+	cases := map[string]string{
+		"GitHub Token":       "GITHUB_TOKEN",
+		"  openai / prod  ":  "OPENAI_PROD",
+		"Stripe (live) key!": "STRIPE_LIVE_KEY",
+		"1st key":            "_1ST_KEY",
+		"ALREADY_VALID":      "ALREADY_VALID",
+		"---":                "",
+	}
+	for in, want := range cases {
+		if got := KeyName(in); got != want {
+			t.Errorf("KeyName(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestParseDotenv(t *testing.T) {
+	data := []byte("# comment\nexport A=1\nB=\"two\"\nC='three'\n\nnot a pair\n")
+	entries, err := Parse(FormatDotenv, data, Options{})
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	want := []Entry{{Name: "A", Value: "1"}, {Name: "B", Value: "two"}, {Name: "C", Value: "three"}}
+	if len(entries) != len(want) {
+		t.Fatalf("expected %d entries, got %d", len(want), len(entries))
+	}
+	for i, e := range entries {
+		if e.Name != want[i].Name || e.Value != want[i].Value {
+			t.Errorf("entry %d: got %s=%s, want %s=%s", i, e.Name, e.Value, want[i].Name, want[i].Value)
+		}
+	}
+}
+
+func TestQuoteDotenvRoundTrip(t *testing.T) {
+	values := []string{
+		"plain",
+		"postgres://user:pw@db:5432/app",
+		"",
+		"two words",
+		" padded ",
+		"value # not a comment",
+		`"quoted"`,
+		"'single'",
+		`it's "mixed"`,
+		`C:\path\`,
+		"-----BEGIN KEY-----\nMIIB\n-----END KEY-----\n",
+		"crlf\r\n",
+	}
+	var data []byte
+	for i, v := range values {
+		data = append(data, fmt.Sprintf("K%d=%s\n", i, QuoteDotenv(v))...)
+	}
+	entries, err := Parse(FormatDotenv, data, Options{})
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if len(entries) != len(values) {
+		t.Fatalf("expected %d entries, got %d:\n%s", len(values), len(entries), data)
+	}
+	for i, e := range entries {
+		if e.Value != values[i] {
+			t.Errorf("%q came back as %q (written as %s)", values[i], e.Value, QuoteDotenv(values[i]))
+		}
+	}
+	if got := QuoteDotenv("sk-123"); got != "sk-123" {
+		t.Errorf("plain values should stay bare, got %s", got)
+	}
+}
+
+func TestParse1PasswordCSV(t *testing.T) {
+	data := []byte("Title,Url,Username,Password,OTPAuth,Favorite,Archived,Tags,Notes\n" +
+		"GitHub,https://github.com,octo,ghp_abc,,false,false,,work token\n" +
+		"Empty,,,,,,,,\n")
+	if got := Detect("export.csv", data); got != Format1PasswordCSV {
+		t.Fatalf("Detect = %q, want %q", got, Format1PasswordCSV)
+	}
+	entries, err := Parse(Format1PasswordCSV, data, Options{})
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("expected 1 entry, got %d", len(entries))
+	}
+	e := entries[0]
+	if e.Name != "GITHUB" || e.Value != "ghp_abc" {
+		t.Errorf("unexpected entry %s=%s", e.Name, e.Value)
+	}
+	if e.Meta[MetaURL] != "https://github.com" || e.Meta[MetaUsername] != "octo" || e.Meta[MetaNotes] != "work token" {
+		t.Errorf("unexpected meta: %v", e.Meta)
+	}
+}
+
+func TestParseGenericCSVMapping(t *testing.T) {
+	data := []byte("Service,Token,Comment\nSendgrid API,SG.xyz,mail\n")
+	if _, err := Parse(FormatCSV, data, Options{}); err == nil {
+		t.Fatal("expected error without a name column")
+	}
+
+	opts := Options{Mapping: map[string]string{"name": "service", "value": "Token", "notes": "Comment"}}
+	entries, err := Parse(FormatCSV, data, opts)
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if len(entries) != 1 || entries[0].Name != "SENDGRID_API" || entries[0].Value != "SG.xyz" {
+		t.Fatalf("unexpected entries: %+v", entries)
+	}
+	if entries[0].Meta[MetaNotes] != "mail" {
+		t.Errorf("expected notes 'mail', got %q", entries[0].Meta[MetaNotes])
+	}
+
+	opts.Mapping["value"] = "Missing"
+	if _, err := Parse(FormatCSV, data, opts); err == nil {
+		t.Error("expected error for unknown column")
+	}
+}
+
+func TestParse1PUX(t *testing.T) {
+	exportData := `{"accounts":[{"vaults":[{"items":[
+		{"state":"active","overview":{"title":"AWS","url":"https://aws.amazon.com"},
+		 "details":{"loginFields":[{"value":"admin","designation":"username"},{"value":"pw1","designation":"password"}],"notesPlain":"root"}},
+		{"state":"active","overview":{"title":"OpenAI API"},
+		 "details":{"sections":[{"fields":[{"title":"credential","value":{"concealed":"sk-123"}}]}]}},
+		{"state":"archived","overview":{"title":"Old"},"details":{"password":"gone"}}
+	]}]}]}`
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	w, _ := zw.Create("export.data")
+	w.Write([]byte(exportData))
+	zw.Close()
+
+	data := buf.Bytes()
+	if got := Detect("backup", data); got != Format1PUX {
+		t.Fatalf("Detect = %q, want %q", got, Format1PUX)
+	}
+	entries, err := Parse(Format1PUX, data, Options{})
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("expected 2 entries, got %d", len(entries))
+	}
+	if entries[0].Name != "AWS" || entries[0].Value != "pw1" || entries[0].Meta[MetaUsername] != "admin" {
+		t.Errorf("unexpected login entry: %+v", entries[0])
+	}
+	if entries[1].Name != "OPENAI_API" || entries[1].Value != "sk-123" {
+		t.Errorf("unexpected API credential entry: %+v", entries[1])
+	}
+}
+
+func TestParseBitwarden(t *testing.T) {
+	data := []byte(`{"encrypted":false,"items":[
+		{"name":"Stripe","notes":"live","login":{"username":"ops","password":"sk_live_1","uris":[{"uri":"https://stripe.com"}]}},
+		{"name":"Stripe","login":{"password":"sk_live_2"}},
+		{"name":"Slack bot","fields":[{"name":"visible","value":"x","type":0},{"name":"token","value":"xoxb-1","type":1}]},
+		{"name":"Just a note","notes":"nothing secret"}
+	]}`)
+	if got := Detect("bw.json", data); got != FormatBitwarden {
+		t.Fatalf("Detect = %q, want %q", got, FormatBitwarden)
+	}
+	entries, err := Parse(FormatBitwarden, data, Options{})
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if len(entries) != 3 {
+		t.Fatalf("expected 3 entries, got %d: %+v", len(entries), entries)
+	}
+	if entries[0].Name != "STRIPE" || entries[0].Meta[MetaURL] != "https://stripe.com" {
+		t.Errorf("unexpected first entry: %+v", entries[0])
+	}
+	if entries[1].Name != "STRIPE_2" || entries[1].Value != "sk_live_2" {
+		t.Errorf("duplicate name not suffixed: %+v", entries[1])
+	}
+	if entries[2].Name != "SLACK_BOT" || entries[2].Value != "xoxb-1" {
+		t.Errorf("hidden field not used: %+v", entries[2])
+	}
+
+	if _, err := Parse(FormatBitwarden, []byte(`{"encrypted":true,"items":[]}`), Options{}); err == nil {
+		t.Error("expected error for encrypted export")
+	}
+}
+
+func TestParseKeePass(t *testing.T) {
+	data := []byte(`<?xml version="1.0" encoding="utf-8"?>
+<KeePassFile>
+  <Meta><RecycleBinUUID>bin</RecycleBinUUID></Meta>
+  <Root>
+    <Group>
+      <UUID>root</UUID>
+      <Entry>
+        <String><Key>Title</Key><Value>Database</Value></String>
+        <String><Key>UserName</Key><Value>pg</Value></String>
+        <String><Key>Password</Key><Value ProtectedInMemory="True">hunter2</Value></String>
+        <String><Key>URL</Key><Value>postgres://db</Value></String>
+        <History>
+          <Entry>
+            <String><Key>Title</Key><Value>Database</Value></String>
+            <String><Key>Password</Key><Value>old</Value></String>
+          </Entry>
+        </History>
+      </Entry>
+      <Group>
+        <UUID>sub</UUID>
+        <Entry>
+          <String><Key>Title</Key><Value>Mailgun</Value></String>
+          <String><Key>Password</Key><Value>key-1</Value></String>
+        </Entry>
+      </Group>
+      <Group>
+        <UUID>bin</UUID>
+        <Entry>
+          <String><Key>Title</Key><Value>Deleted</Value></String>
+          <String><Key>Password</Key><Value>x</Value></String>
+        </Entry>
+      </Group>
+    </Group>
+  </Root>
+</KeePassFile>`)
+	if got := Detect("vault", data); got != FormatKeePass {
+		t.Fatalf("Detect = %q, want %q", got, FormatKeePass)
+	}
+	entries, err := Parse(FormatKeePass, data, Options{})
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("expected 2 entries, got %d: %+v", len(entries), entries)
+	}
+	if entries[0].Name != "DATABASE" || entries[0].Value != "hunter2" || entries[0].Meta[MetaUsername] != "pg" {
+		t.Errorf("unexpected entry: %+v", entries[0])
+	}
+	if entries[1].Name != "MAILGUN" {
+		t.Errorf("nested group entry missing: %+v", entries[1])
+	}
+}
+
+func TestDetectDefaultsToDotenv(t *testing.T) {
+	if got := Detect(".env", []byte("A=1\n")); got != FormatDotenv {
+		t.Errorf("Detect = %q, want %q", got, FormatDotenv)
+	}
+}
+
+func TestParseUnknownFormat(t *testing.T) {
+	if _, err := Parse("lastpass", nil, Options{}); err == nil {
+		t.Error("expected error for unknown format")
+	}
+}
+
+func TestParseK8sSecret(t *testing.T) {
+	data := []byte(`apiVersion: v1
+kind: Secret
+metadata:
+  name: app
+data:
+  DATABASE_URL: cG9zdGdyZXM6Ly9kYg==
+  tls.crt: Y2VydA==
+`)
+	if got := Detect("secret.yaml", data); got != FormatK8sSecret {
+		t.Fatalf("Detect = %q, want %q", got, FormatK8sSecret)
+	}
+	if got := Detect("manifest", data); got != FormatK8sSecret {
+		t.Fatalf("Detect by content = %q, want %q", got, FormatK8sSecret)
+	}
+	entries, err := Parse(FormatK8sSecret, data, Options{})
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("expected 2 entries, got %d", len(entries))
+	}
+	if entries[0].Name != "DATABASE_URL" || entries[0].Value != "postgres://db" {
+		t.Errorf("unexpected entry: %+v", entries[0])
+	}
+	if entries[1].Name != "TLS_CRT" || entries[1].Value != "cert" {
+		t.Errorf("invalid env name not converted: %+v", entries[1])
+	}
+}
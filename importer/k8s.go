@@ -0,0 +1,53 @@
+package importer
+
+import (
+	"sort"
+
+	"github.com/stym06/keys/k8s"
+)
+
+// parseK8sSecret reads the data and stringData of every Secret in a
+// manifest. Secret keys that are already valid environment variable names
+// are kept as written.
+func parseK8sSecret(data []byte) ([]Entry, error) {
+	secrets, err := k8s.ParseSecrets(data)
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []Entry
+	for _, s := range secrets {
+		values, err := s.Values()
+		if err != nil {
+			return nil, err
+		}
+		names := make([]string, 0, len(values))
+		for k := range values {
+			names = append(names, k)
+		}
+		sort.Strings(names)
+		for _, k := range names {
+			name := k
+			if !validEnvName(name) {
+				name = KeyName(name)
+			}
+			if name == "" {
+				continue
+			}
+			entries = append(entries, Entry{Name: name, Value: values[k]})
+		}
+	}
+	return entries, nil
+}
+
+func validEnvName(s string) bool {
+	if s == "" || (s[0] >= '0' && s[0] <= '9') {
+		return false
+	}
+	for _, r := range s {
+		if !(r == '_' || r >= 'A' && r <= 'Z' || r >= 'a' && r <= 'z' || r >= '0' && r <= '9') {
+			return false
+		}
+	}
+	return true
+}
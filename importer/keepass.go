@@ -0,0 +1,63 @@
+package importer
+
+import (
+	"encoding/xml"
+	"fmt"
+)
+
+type keePassFile struct {
+	Meta struct {
+		RecycleBinUUID string `xml:"RecycleBinUUID"`
+	} `xml:"Meta"`
+	Root struct {
+		Groups []keePassGroup `xml:"Group"`
+	} `xml:"Root"`
+}
+
+type keePassGroup struct {
+	UUID    string         `xml:"UUID"`
+	Entries []keePassEntry `xml:"Entry"`
+	Groups  []keePassGroup `xml:"Group"`
+}
+
+type keePassEntry struct {
+	Strings []struct {
+		Key   string `xml:"Key"`
+		Value string `xml:"Value"`
+	} `xml:"String"`
+}
+
+func parseKeePass(data []byte) ([]Entry, error) {
+	var file keePassFile
+	if err := xml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("invalid KeePass XML: %w", err)
+	}
+
+	var entries []Entry
+	var walk func(groups []keePassGroup)
+	walk = func(groups []keePassGroup) {
+		for _, g := range groups {
+			if g.UUID != "" && g.UUID == file.Meta.RecycleBinUUID {
+				continue
+			}
+			for _, ke := range g.Entries {
+				fields := make(map[string]string)
+				for _, s := range ke.Strings {
+					fields[s.Key] = s.Value
+				}
+				entries = append(entries, Entry{
+					Name:  fields["Title"],
+					Value: fields["Password"],
+					Meta: map[string]string{
+						MetaURL:      fields["URL"],
+						MetaUsername: fields["UserName"],
+						MetaNotes:    fields["Notes"],
+					},
+				})
+			}
+			walk(g.Groups)
+		}
+	}
+	walk(file.Root.Groups)
+	return entries, nil
+}
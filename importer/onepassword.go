@@ -0,0 +1,125 @@
+package importer
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// 1PUX archives hold a single export.data JSON document. Only the fields
+// needed to recover a secret are decoded.
+type onePUXExport struct {
+	Accounts []struct {
+		Vaults []struct {
+			Items []onePUXItem `json:"items"`
+		} `json:"vaults"`
+	} `json:"accounts"`
+}
+
+type onePUXItem struct {
+	State    string `json:"state"`
+	Overview struct {
+		Title string `json:"title"`
+		URL   string `json:"url"`
+	} `json:"overview"`
+	Details struct {
+		LoginFields []struct {
+			Value       string `json:"value"`
+			Designation string `json:"designation"`
+		} `json:"loginFields"`
+		NotesPlain string `json:"notesPlain"`
+		Password   string `json:"password"`
+		Sections   []struct {
+			Fields []struct {
+				Title string `json:"title"`
+				Value struct {
+					Concealed *string `json:"concealed"`
+					String    *string `json:"string"`
+				} `json:"value"`
+			} `json:"fields"`
+		} `json:"sections"`
+	} `json:"details"`
+}
+
+func parse1PUX(data []byte) ([]Entry, error) {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("not a 1PUX archive: %w", err)
+	}
+
+	var raw []byte
+	for _, f := range zr.File {
+		if f.Name != "export.data" {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return nil, err
+		}
+		raw, err = io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			return nil, err
+		}
+		break
+	}
+	if raw == nil {
+		return nil, fmt.Errorf("1PUX archive has no export.data")
+	}
+
+	var export onePUXExport
+	if err := json.Unmarshal(raw, &export); err != nil {
+		return nil, fmt.Errorf("invalid 1PUX export.data: %w", err)
+	}
+
+	var entries []Entry
+	for _, acct := range export.Accounts {
+		for _, vault := range acct.Vaults {
+			for _, item := range vault.Items {
+				if item.State == "archived" || item.State == "trashed" {
+					continue
+				}
+				entries = append(entries, item.entry())
+			}
+		}
+	}
+	return entries, nil
+}
+
+func (item onePUXItem) entry() Entry {
+	var value, username string
+	for _, f := range item.Details.LoginFields {
+		switch f.Designation {
+		case "password":
+			value = f.Value
+		case "username":
+			username = f.Value
+		}
+	}
+	if value == "" {
+		value = item.Details.Password
+	}
+	// API credentials and similar items keep the secret in a section field.
+	for _, s := range item.Details.Sections {
+		for _, f := range s.Fields {
+			if value == "" && f.Value.Concealed != nil {
+				value = *f.Value.Concealed
+			}
+			if username == "" && f.Title == "username" && f.Value.String != nil {
+				username = *f.Value.String
+			}
+		}
+	}
+
+	return Entry{
+		Name:  item.Overview.Title,
+		Value: value,
+		Meta: map[string]string{
+			MetaURL:      item.Overview.URL,
+			MetaUsername: username,
+			MetaNotes:    item.Details.NotesPlain,
+		},
+	}
+}
@@ -0,0 +1,121 @@
+// Package k8s reads and writes Kubernetes Secret manifests.
+package k8s
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Secret is the subset of a v1 Secret that carries key material.
+type Secret struct {
+	APIVersion string            `yaml:"apiVersion"`
+	Kind       string            `yaml:"kind"`
+	Metadata   Metadata          `yaml:"metadata"`
+	Type       string            `yaml:"type,omitempty"`
+	Data       map[string]string `yaml:"data,omitempty"`
+	StringData map[string]string `yaml:"stringData,omitempty"`
+}
+
+type Metadata struct {
+	Name      string `yaml:"name"`
+	Namespace string `yaml:"namespace,omitempty"`
+}
+
+// Secret names must be RFC 1123 DNS subdomains.
+var nameRe = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]*[a-z0-9])?(\.[a-z0-9]([-a-z0-9]*[a-z0-9])?)*$`)
+
+// NewSecret builds an Opaque Secret holding values. Values are base64
+// encoded under data, or written as-is under stringData.
+func NewSecret(name, namespace string, values map[string]string, stringData bool) (Secret, error) {
+	if len(name) > 253 || !nameRe.MatchString(name) {
+		return Secret{}, fmt.Errorf("invalid secret name %q: use lowercase letters, digits, '-' and '.'", name)
+	}
+	s := Secret{
+		APIVersion: "v1",
+		Kind:       "Secret",
+		Metadata:   Metadata{Name: name, Namespace: namespace},
+		Type:       "Opaque",
+	}
+	if stringData {
+		s.StringData = values
+		return s, nil
+	}
+	s.Data = make(map[string]string, len(values))
+	for k, v := range values {
+		s.Data[k] = base64.StdEncoding.EncodeToString([]byte(v))
+	}
+	return s, nil
+}
+
+// Marshal renders the Secret as a YAML document.
+func (s Secret) Marshal() ([]byte, error) {
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(s); err != nil {
+		return nil, err
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Values returns the decoded contents of data and stringData. As in the
+// API server, stringData wins when a key appears in both.
+func (s Secret) Values() (map[string]string, error) {
+	values := make(map[string]string, len(s.Data)+len(s.StringData))
+	for k, v := range s.Data {
+		decoded, err := base64.StdEncoding.DecodeString(v)
+		if err != nil {
+			return nil, fmt.Errorf("secret %s: data.%s is not valid base64", s.Metadata.Name, k)
+		}
+		values[k] = string(decoded)
+	}
+	for k, v := range s.StringData {
+		values[k] = v
+	}
+	return values, nil
+}
+
+// ParseSecrets reads every Secret from a (possibly multi-document) YAML
+// stream, including Secrets inside a List. Other kinds are skipped.
+func ParseSecrets(data []byte) ([]Secret, error) {
+	var secrets []Secret
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	for {
+		var doc struct {
+			Secret `yaml:",inline"`
+			Items  []Secret `yaml:"items"`
+		}
+		err := dec.Decode(&doc)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid YAML: %w", err)
+		}
+		if doc.Kind == "Secret" {
+			secrets = append(secrets, doc.Secret)
+		}
+		for _, item := range doc.Items {
+			if item.Kind == "Secret" {
+				secrets = append(secrets, item)
+			}
+		}
+	}
+	return secrets, nil
+}
+
+// IsSecretManifest reports whether data looks like YAML containing a Secret.
+func IsSecretManifest(data []byte) bool {
+	return kindRe.Match(data)
+}
+
+var kindRe = regexp.MustCompile(`(?m)^\s*(- )?kind:\s*["']?Secret["']?\s*$`)
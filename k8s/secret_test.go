@@ -0,0 +1,121 @@
+package k8s
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNewSecretData(t *testing.T) {
+	s, err := NewSecret("app-secrets", "prod", map[string]string{"API_KEY": "sk-123"}, false)
+	if err != nil {
+		t.Fatalf("NewSecret: %v", err)
+	}
+	out, err := s.Marshal()
+	if err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+	want := `apiVersion: v1
+kind: Secret
+metadata:
+  name: app-secrets
+  namespace: prod
+type: Opaque
+data:
+  API_KEY: c2stMTIz
+`
+	if string(out) != want {
+		t.Errorf("unexpected manifest:\n%s\nwant:\n%s", out, want)
+	}
+}
+
+func TestNewSecretStringData(t *testing.T) {
+	s, _ := NewSecret("app", "", map[string]string{"A": "plain"}, true)
+	out, _ := s.Marshal()
+	if !strings.Contains(string(out), "stringData:\n  A: plain\n") {
+		t.Errorf("expected stringData, got:\n%s", out)
+	}
+	if strings.Contains(string(out), "namespace") {
+		t.Error("empty namespace should be omitted")
+	}
+}
+
+func TestNewSecretInvalidName(t *testing.T) {
+	if _, err := NewSecret("App_Secrets", "", nil, false); err == nil {
+		t.Error("expected error for invalid name")
+	}
+}
+
+func TestParseSecretsRoundTrip(t *testing.T) {
+	s, _ := NewSecret("one", "", map[string]string{"A": "1", "B": "multi\nline"}, false)
+	out, _ := s.Marshal()
+
+	secrets, err := ParseSecrets(out)
+	if err != nil {
+		t.Fatalf("ParseSecrets: %v", err)
+	}
+	if len(secrets) != 1 {
+		t.Fatalf("expected 1 secret, got %d", len(secrets))
+	}
+	values, err := secrets[0].Values()
+	if err != nil {
+		t.Fatalf("Values: %v", err)
+	}
+	if values["A"] != "1" || values["B"] != "multi\nline" {
+		t.Errorf("unexpected values: %v", values)
+	}
+}
+
+func TestParseSecretsMultiDocument(t *testing.T) {
+	data := []byte(`apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: cfg
+data:
+  NOT_SECRET: x
+---
+apiVersion: v1
+kind: Secret
+metadata:
+  name: a
+data:
+  A: MQ==
+  SHARED: ZnJvbS1kYXRh
+stringData:
+  SHARED: from-stringData
+---
+apiVersion: v1
+kind: List
+items:
+  - apiVersion: v1
+    kind: Secret
+    metadata:
+      name: b
+    stringData:
+      B: two
+`)
+	if !IsSecretManifest(data) {
+		t.Fatal("IsSecretManifest should match")
+	}
+	secrets, err := ParseSecrets(data)
+	if err != nil {
+		t.Fatalf("ParseSecrets: %v", err)
+	}
+	if len(secrets) != 2 {
+		t.Fatalf("expected 2 secrets, got %d", len(secrets))
+	}
+	a, _ := secrets[0].Values()
+	if a["A"] != "1" || a["SHARED"] != "from-stringData" {
+		t.Errorf("unexpected values for a: %v", a)
+	}
+	b, _ := secrets[1].Values()
+	if b["B"] != "two" {
+		t.Errorf("unexpected values for b: %v", b)
+	}
+}
+
+func TestValuesInvalidBase64(t *testing.T) {
+	s := Secret{Metadata: Metadata{Name: "bad"}, Data: map[string]string{"A": "not base64!"}}
+	if _, err := s.Values(); err == nil {
+		t.Error("expected error for invalid base64")
+	}
+}
@@ -0,0 +1,112 @@
+package leak
+
+import (
+	"bufio"
+	"io"
+	"path"
+	"strings"
+)
+
+// AllowlistFile is the name of the allowlist read from the root of a
+// repository.
+const AllowlistFile = ".keys-allowlist"
+
+// Allowlist suppresses known false positives. Each line holds a file glob
+// and, optionally, the keys it applies to:
+//
+//	testdata/**                  # any key under testdata
+//	docs/example.md  DEMO_KEY    # one key in one file
+//	*  PUBLIC_ANON_KEY           # one key anywhere
+type Allowlist struct {
+	rules []allowRule
+}
+
+type allowRule struct {
+	pattern string
+	keys    map[string]bool
+}
+
+func ParseAllowlist(r io.Reader) (*Allowlist, error) {
+	a := &Allowlist{}
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = line[:i]
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		rule := allowRule{pattern: fields[0]}
+		if len(fields) > 1 {
+			rule.keys = make(map[string]bool)
+			for _, k := range fields[1:] {
+				rule.keys[k] = true
+			}
+		}
+		a.rules = append(a.rules, rule)
+	}
+	return a, scanner.Err()
+}
+
+// Allows reports whether f is covered by the allowlist.
+func (a *Allowlist) Allows(f Finding) bool {
+	if a == nil {
+		return false
+	}
+	for _, r := range a.rules {
+		if r.keys != nil && !r.keys[f.Key] {
+			continue
+		}
+		if matchGlob(r.pattern, f.File) {
+			return true
+		}
+	}
+	return false
+}
+
+// Filter returns the findings not covered by the allowlist.
+func (a *Allowlist) Filter(findings []Finding) []Finding {
+	var out []Finding
+	for _, f := range findings {
+		if !a.Allows(f) {
+			out = append(out, f)
+		}
+	}
+	return out
+}
+
+// matchGlob matches a slash-separated path against a pattern where "*"
+// matches within a path segment and "**" matches any number of segments.
+// Patterns without a slash match the file name in any directory, so "*"
+// matches every path.
+func matchGlob(pattern, name string) bool {
+	name = strings.TrimPrefix(name, "./")
+	if !strings.Contains(pattern, "/") {
+		ok, _ := path.Match(pattern, path.Base(name))
+		return ok
+	}
+	return matchSegments(strings.Split(pattern, "/"), strings.Split(name, "/"))
+}
+
+func matchSegments(pat, name []string) bool {
+	for len(pat) > 0 {
+		if pat[0] == "**" {
+			for i := len(name); i >= 0; i-- {
+				if matchSegments(pat[1:], name[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(name) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pat[0], name[0]); !ok {
+			return false
+		}
+		pat, name = pat[1:], name[1:]
+	}
+	return len(name) == 0
+}
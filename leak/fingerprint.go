@@ -0,0 +1,107 @@
+package leak
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"sort"
+)
+
+// Fingerprint is a keyed hash of one encoded form of a stored value. A set
+// of fingerprints can recognise secrets without holding the secrets.
+type Fingerprint struct {
+	Profile  string
+	Name     string
+	Encoding string
+	Length   int
+	Hash     string
+}
+
+// Hash returns the HMAC-SHA256 of value under key, hex encoded.
+func Hash(key []byte, value string) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(value))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Fingerprints returns a fingerprint for every variant of value.
+func Fingerprints(key []byte, profile, name, value string) []Fingerprint {
+	var fps []Fingerprint
+	for _, v := range Variants(value) {
+		fps = append(fps, Fingerprint{
+			Profile:  profile,
+			Name:     name,
+			Encoding: v.Encoding,
+			Length:   len(v.Value),
+			Hash:     Hash(key, v.Value),
+		})
+	}
+	return fps
+}
+
+// Index matches lines against fingerprints. Because only hashes are known,
+// candidates are the substrings of each stored length that start at the
+// beginning of a line or right after a delimiter such as a space, quote,
+// '=', ':' or the '/' and '.' of a URL path.
+type Index struct {
+	key     []byte
+	lengths []int
+	byHash  map[string][]Fingerprint
+}
+
+func NewIndex(key []byte, fps []Fingerprint) *Index {
+	ix := &Index{key: key, byHash: make(map[string][]Fingerprint)}
+	seen := make(map[int]bool)
+	for _, fp := range fps {
+		ix.byHash[fp.Hash] = append(ix.byHash[fp.Hash], fp)
+		if !seen[fp.Length] {
+			seen[fp.Length] = true
+			ix.lengths = append(ix.lengths, fp.Length)
+		}
+	}
+	sort.Ints(ix.lengths)
+	return ix
+}
+
+// Empty reports whether there is nothing to search for.
+func (ix *Index) Empty() bool {
+	return len(ix.byHash) == 0
+}
+
+func isDelimiter(c byte) bool {
+	switch c {
+	case ' ', '\t', '"', '\'', '`', '=', ':', ',', ';', '(', ')', '[', ']', '{', '}', '<', '>', '@', '/', '.':
+		return true
+	}
+	return false
+}
+
+func (ix *Index) MatchLine(file string, lineNo int, line []byte) []Finding {
+	var findings []Finding
+	reported := make(map[string]bool)
+	for start := 0; start < len(line); start++ {
+		if start > 0 && !isDelimiter(line[start-1]) {
+			continue
+		}
+		for _, n := range ix.lengths {
+			if start+n > len(line) {
+				break
+			}
+			for _, fp := range ix.byHash[Hash(ix.key, string(line[start:start+n]))] {
+				id := fp.Profile + "\x00" + fp.Name
+				if reported[id] {
+					continue
+				}
+				reported[id] = true
+				findings = append(findings, Finding{
+					File:     file,
+					Line:     lineNo,
+					Profile:  fp.Profile,
+					Key:      fp.Name,
+					Encoding: fp.Encoding,
+				})
+			}
+		}
+	}
+	return findings
+}
@@ -0,0 +1,90 @@
+package leak
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+)
+
+func testIndex() *Index {
+	key := []byte("fingerprint-key")
+	fps := Fingerprints(key, "prod", "STRIPE_KEY", "sk_live_abcdef123456")
+	fps = append(fps, Fingerprints(key, "dev", "DB_PASS", "hunter2hunter2")...)
+	return NewIndex(key, fps)
+}
+
+func TestFingerprintsDoNotContainValue(t *testing.T) {
+	for _, fp := range Fingerprints([]byte("k"), "p", "N", "sk_live_abcdef123456") {
+		if strings.Contains(fp.Hash, "sk_live") {
+			t.Fatal("fingerprint leaks the value")
+		}
+	}
+	if Hash([]byte("a"), "v") == Hash([]byte("b"), "v") {
+		t.Error("hash should depend on the key")
+	}
+}
+
+func TestIndexMatchLine(t *testing.T) {
+	ix := testIndex()
+	cases := []struct {
+		line string
+		want string
+	}{
+		{`STRIPE=sk_live_abcdef123456`, "STRIPE_KEY"},
+		{`  "key": "sk_live_abcdef123456",`, "STRIPE_KEY"},
+		{`postgres://app:hunter2hunter2@db/app`, "DB_PASS"},
+		{`https://hooks.example.com/sk_live_abcdef123456`, "STRIPE_KEY"},
+		{`GET /v1/charges.sk_live_abcdef123456`, "STRIPE_KEY"},
+		{`auth: ` + base64.StdEncoding.EncodeToString([]byte("hunter2hunter2")), "DB_PASS"},
+		{`nothing to see here`, ""},
+		{`xsk_live_abcdef123456`, ""}, // not at a delimiter
+	}
+	for _, c := range cases {
+		found := ix.MatchLine("f", 1, []byte(c.line))
+		if c.want == "" {
+			if len(found) != 0 {
+				t.Errorf("%q: expected no findings, got %+v", c.line, found)
+			}
+			continue
+		}
+		if len(found) != 1 || found[0].Key != c.want {
+			t.Errorf("%q: expected %s, got %+v", c.line, c.want, found)
+		}
+	}
+}
+
+func TestAllowlist(t *testing.T) {
+	a, err := ParseAllowlist(strings.NewReader(`
+# false positives
+testdata/**
+docs/example.md  DEMO_KEY OTHER
+*.snap
+*  PUBLIC_KEY   # anywhere
+`))
+	if err != nil {
+		t.Fatalf("ParseAllowlist: %v", err)
+	}
+	cases := []struct {
+		file, key string
+		allowed   bool
+	}{
+		{"testdata/a/b.txt", "ANY", true},
+		{"src/testdata/x", "ANY", false},
+		{"docs/example.md", "DEMO_KEY", true},
+		{"docs/example.md", "REAL_KEY", false},
+		{"ui/__snapshots__/app.snap", "ANY", true},
+		{"deploy.sh", "PUBLIC_KEY", true},
+		{"deploy.sh", "SECRET", false},
+	}
+	for _, c := range cases {
+		got := a.Allows(Finding{File: c.file, Key: c.key})
+		if got != c.allowed {
+			t.Errorf("Allows(%s, %s) = %v, want %v", c.file, c.key, got, c.allowed)
+		}
+	}
+
+	var none *Allowlist
+	if none.Allows(Finding{File: "x", Key: "y"}) {
+		t.Error("nil allowlist should allow nothing")
+	}
+}
@@ -0,0 +1,102 @@
+package leak
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// ScanGitHistory scans every line added in the history of the repository
+// at dir, across all refs.
+func ScanGitHistory(dir string, m LineMatcher) ([]Finding, error) {
+	return scanGit(dir, m, "log", "-p", "--all", "--no-color", "--no-ext-diff", "--format=commit %H")
+}
+
+// ScanStaged scans the lines added by the changes staged in the repository
+// at dir.
+func ScanStaged(dir string, m LineMatcher) ([]Finding, error) {
+	return scanGit(dir, m, "diff", "--cached", "-U0", "--no-color", "--no-ext-diff")
+}
+
+func scanGit(dir string, m LineMatcher, args ...string) ([]Finding, error) {
+	cmd := exec.Command("git", append([]string{"-C", dir}, args...)...)
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+
+	findings, scanErr := ScanPatch(stdout, m)
+	if err := cmd.Wait(); err != nil {
+		return findings, fmt.Errorf("git %s: %s", args[0], strings.TrimSpace(stderr.String()))
+	}
+	return findings, scanErr
+}
+
+// ScanPatch scans the added lines of unified diff output, such as that of
+// `git diff` or `git log -p`.
+func ScanPatch(r io.Reader, m LineMatcher) ([]Finding, error) {
+	var findings []Finding
+	var commit, file string
+	lineNo := 0
+	inHeader := false
+
+	br := bufio.NewReader(r)
+	for {
+		line, err := br.ReadBytes('\n')
+		if len(line) > 0 {
+			text := string(bytes.TrimRight(line, "\n"))
+			switch {
+			case strings.HasPrefix(text, "commit "):
+				commit = strings.TrimPrefix(text, "commit ")
+				if len(commit) > 12 {
+					commit = commit[:12]
+				}
+			case strings.HasPrefix(text, "diff --git "):
+				inHeader = true
+			case inHeader && strings.HasPrefix(text, "+++ "):
+				file = strings.TrimPrefix(strings.TrimPrefix(text, "+++ "), "b/")
+			case strings.HasPrefix(text, "@@ "):
+				inHeader = false
+				lineNo = hunkStart(text)
+			case inHeader:
+				// index, mode and "---" lines of the file header
+			case strings.HasPrefix(text, "+"):
+				for _, f := range m.MatchLine(file, lineNo, line[1:]) {
+					f.Commit = commit
+					findings = append(findings, f)
+				}
+				lineNo++
+			case strings.HasPrefix(text, " "):
+				lineNo++
+			}
+		}
+		if errors.Is(err, io.EOF) {
+			return findings, nil
+		}
+		if err != nil {
+			return findings, err
+		}
+	}
+}
+
+// hunkStart returns the first new-file line number of a hunk header such
+// as "@@ -10,4 +12,6 @@".
+func hunkStart(header string) int {
+	fields := strings.Fields(header)
+	if len(fields) < 3 || !strings.HasPrefix(fields[2], "+") {
+		return 0
+	}
+	start, _, _ := strings.Cut(fields[2][1:], ",")
+	n, _ := strconv.Atoi(start)
+	return n
+}
@@ -0,0 +1,201 @@
+// Package leak searches files and git history for copies of stored
+// secrets, including common encodings of them. Findings identify the key
+// that leaked, never the value.
+package leak
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+)
+
+// Secret is a stored value to look for.
+type Secret struct {
+	Profile string
+	Name    string
+	Value   string
+}
+
+// Encodings a secret may appear in.
+const (
+	EncodingRaw       = "raw"
+	EncodingBase64    = "base64"
+	EncodingBase64URL = "base64url"
+	EncodingURL       = "urlencoded"
+)
+
+// Variant is one encoded form of a secret value.
+type Variant struct {
+	Encoding string
+	Value    string
+}
+
+// Variants returns the raw value and every distinct encoded form of it.
+func Variants(value string) []Variant {
+	candidates := []Variant{
+		{EncodingRaw, value},
+		{EncodingBase64, base64.StdEncoding.EncodeToString([]byte(value))},
+		{EncodingBase64, base64.RawStdEncoding.EncodeToString([]byte(value))},
+		{EncodingBase64URL, base64.URLEncoding.EncodeToString([]byte(value))},
+		{EncodingBase64URL, base64.RawURLEncoding.EncodeToString([]byte(value))},
+		{EncodingURL, url.QueryEscape(value)},
+		{EncodingURL, url.PathEscape(value)},
+	}
+	seen := make(map[string]bool)
+	var variants []Variant
+	for _, v := range candidates {
+		if seen[v.Value] {
+			continue
+		}
+		seen[v.Value] = true
+		variants = append(variants, v)
+	}
+	return variants
+}
+
+// DefaultMinLength is the shortest value searched for by default, since
+// short values such as "true" or "8080" match too much.
+const DefaultMinLength = 8
+
+// Finding is a line containing a stored secret. Commit is set for findings
+// in git history.
+type Finding struct {
+	File     string
+	Commit   string
+	Line     int
+	Profile  string
+	Key      string
+	Encoding string
+}
+
+// Location formats the finding as [commit:]file:line.
+func (f Finding) Location() string {
+	loc := fmt.Sprintf("%s:%d", f.File, f.Line)
+	if f.Commit != "" {
+		loc = f.Commit + ":" + loc
+	}
+	return loc
+}
+
+// LineMatcher finds secrets in a single line of text.
+type LineMatcher interface {
+	MatchLine(file string, lineNo int, line []byte) []Finding
+}
+
+type needle struct {
+	secret   Secret
+	encoding string
+	value    []byte
+}
+
+// Scanner matches lines against a fixed set of secrets.
+type Scanner struct {
+	needles []needle
+}
+
+// NewScanner prepares a scanner for secrets. Values shorter than minLength
+// are ignored.
+func NewScanner(secrets []Secret, minLength int) *Scanner {
+	s := &Scanner{}
+	for _, sec := range secrets {
+		if len(sec.Value) < minLength {
+			continue
+		}
+		for _, v := range Variants(sec.Value) {
+			s.needles = append(s.needles, needle{sec, v.Encoding, []byte(v.Value)})
+		}
+	}
+	return s
+}
+
+// Empty reports whether there is nothing to search for.
+func (s *Scanner) Empty() bool {
+	return len(s.needles) == 0
+}
+
+// MatchLine returns a finding for each secret contained in line.
+func (s *Scanner) MatchLine(file string, lineNo int, line []byte) []Finding {
+	var findings []Finding
+	reported := make(map[Secret]bool)
+	for _, n := range s.needles {
+		if reported[n.secret] || !bytes.Contains(line, n.value) {
+			continue
+		}
+		reported[n.secret] = true
+		findings = append(findings, Finding{
+			File:     file,
+			Line:     lineNo,
+			Profile:  n.secret.Profile,
+			Key:      n.secret.Name,
+			Encoding: n.encoding,
+		})
+	}
+	return findings
+}
+
+// ScanReader scans r line by line, reporting findings under the name file.
+func (s *Scanner) ScanReader(file string, r io.Reader) ([]Finding, error) {
+	var findings []Finding
+	br := bufio.NewReader(r)
+	lineNo := 0
+	for {
+		line, err := br.ReadBytes('\n')
+		if len(line) > 0 {
+			lineNo++
+			findings = append(findings, s.MatchLine(file, lineNo, line)...)
+		}
+		if errors.Is(err, io.EOF) {
+			return findings, nil
+		}
+		if err != nil {
+			return findings, err
+		}
+	}
+}
+
+// ScanPaths walks files and directories, skipping .git directories and
+// binary files.
+func (s *Scanner) ScanPaths(paths []string) ([]Finding, error) {
+	var findings []Finding
+	for _, root := range paths {
+		err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.IsDir() {
+				if d.Name() == ".git" && path != root {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if !d.Type().IsRegular() {
+				return nil
+			}
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return err
+			}
+			if isBinary(data) {
+				return nil
+			}
+			found, err := s.ScanReader(path, bytes.NewReader(data))
+			findings = append(findings, found...)
+			return err
+		})
+		if err != nil {
+			return findings, err
+		}
+	}
+	return findings, nil
+}
+
+func isBinary(data []byte) bool {
+	return bytes.IndexByte(data[:min(len(data), 8000)], 0) >= 0
+}
@@ -0,0 +1,117 @@
+package leak
+
+import (
+	"encoding/base64"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestVariants(t *testing.T) {
+	v := Variants("sk_live/abc+def???>>>")
+	encodings := make(map[string]bool)
+	values := make(map[string]bool)
+	for _, x := range v {
+		encodings[x.Encoding] = true
+		values[x.Value] = true
+	}
+	for _, e := range []string{EncodingRaw, EncodingBase64, EncodingBase64URL, EncodingURL} {
+		if !encodings[e] {
+			t.Errorf("missing %s variant", e)
+		}
+	}
+	if !values[base64.StdEncoding.EncodeToString([]byte("sk_live/abc+def???>>>"))] {
+		t.Error("missing standard base64 form")
+	}
+	if !values[url.QueryEscape("sk_live/abc+def???>>>")] {
+		t.Error("missing URL-encoded form")
+	}
+	if len(values) != len(v) {
+		t.Error("variants should be distinct")
+	}
+}
+
+func testScanner() *Scanner {
+	return NewScanner([]Secret{
+		{Profile: "default", Name: "STRIPE_KEY", Value: "sk_live_abcdef123456"},
+		{Profile: "default", Name: "SHORT", Value: "true"},
+	}, 8)
+}
+
+func TestScanReader(t *testing.T) {
+	s := testScanner()
+	encoded := base64.StdEncoding.EncodeToString([]byte("sk_live_abcdef123456"))
+	input := "debug: true\nkey: sk_live_abcdef123456\nb64: " + encoded + "\n"
+
+	findings, err := s.ScanReader("config.yml", strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("ScanReader: %v", err)
+	}
+	if len(findings) != 2 {
+		t.Fatalf("expected 2 findings, got %+v", findings)
+	}
+	if findings[0].Line != 2 || findings[0].Key != "STRIPE_KEY" || findings[0].Encoding != EncodingRaw {
+		t.Errorf("unexpected raw finding: %+v", findings[0])
+	}
+	if findings[1].Line != 3 || findings[1].Encoding != EncodingBase64 {
+		t.Errorf("unexpected base64 finding: %+v", findings[1])
+	}
+}
+
+func TestScanPathsSkipsBinaryAndGit(t *testing.T) {
+	root := t.TempDir()
+	os.WriteFile(filepath.Join(root, "leak.txt"), []byte("sk_live_abcdef123456\n"), 0644)
+	os.WriteFile(filepath.Join(root, "blob.bin"), []byte("\x00sk_live_abcdef123456"), 0644)
+	os.MkdirAll(filepath.Join(root, ".git"), 0755)
+	os.WriteFile(filepath.Join(root, ".git", "packed"), []byte("sk_live_abcdef123456\n"), 0644)
+
+	findings, err := testScanner().ScanPaths([]string{root})
+	if err != nil {
+		t.Fatalf("ScanPaths: %v", err)
+	}
+	if len(findings) != 1 || filepath.Base(findings[0].File) != "leak.txt" {
+		t.Errorf("unexpected findings: %+v", findings)
+	}
+}
+
+func TestScanPatch(t *testing.T) {
+	patch := `commit 0123456789abcdef0123456789abcdef01234567
+
+diff --git a/deploy.sh b/deploy.sh
+new file mode 100644
+index 0000000..1111111
+--- /dev/null
++++ b/deploy.sh
+@@ -0,0 +1,3 @@
++#!/bin/sh
++export STRIPE=sk_live_abcdef123456
++echo done
+commit fedcba9876543210fedcba9876543210fedcba98
+
+diff --git a/app.env b/app.env
+index 1111111..2222222 100644
+--- a/app.env
++++ b/app.env
+@@ -10,2 +10,3 @@ header
+ A=1
+-OLD=sk_live_abcdef123456
++++KEY=sk_live_abcdef123456
+`
+	findings, err := ScanPatch(strings.NewReader(patch), testScanner())
+	if err != nil {
+		t.Fatalf("ScanPatch: %v", err)
+	}
+	if len(findings) != 2 {
+		t.Fatalf("expected 2 findings, got %+v", findings)
+	}
+	if findings[0].Location() != "0123456789ab:deploy.sh:2" {
+		t.Errorf("unexpected first finding: %+v", findings[0])
+	}
+	// An added line starting with "++" must not be mistaken for a header,
+	// and removed lines are not counted in the new file.
+	if findings[1].Location() != "fedcba987654:app.env:11" {
+		t.Errorf("unexpected second finding: %+v", findings[1])
+	}
+}
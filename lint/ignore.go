@@ -0,0 +1,93 @@
+package lint
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+)
+
+// Ignore suppresses findings. Each line holds a rule name, or "*" for every
+// rule, and optionally the keys it applies to as NAME or profile/NAME
+// globs:
+//
+//	never-accessed                # turn a rule off
+//	stale  prod/LEGACY_*          # one rule for some keys in one profile
+//	*  dev/TEST_*                 # every rule for some keys
+type Ignore struct {
+	rules []ignoreRule
+}
+
+type ignoreRule struct {
+	rule     string
+	patterns []string
+}
+
+func ParseIgnore(r io.Reader) (*Ignore, error) {
+	ig := &Ignore{}
+	known := make(map[string]bool, len(Rules))
+	for _, r := range Rules {
+		known[r] = true
+	}
+
+	scanner := bufio.NewScanner(r)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := scanner.Text()
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = line[:i]
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		if fields[0] != "*" && !known[fields[0]] {
+			return nil, fmt.Errorf("line %d: unknown rule %q", lineNo, fields[0])
+		}
+		for _, p := range fields[1:] {
+			if _, err := path.Match(p, ""); err != nil {
+				return nil, fmt.Errorf("line %d: invalid pattern %q", lineNo, p)
+			}
+		}
+		ig.rules = append(ig.rules, ignoreRule{rule: fields[0], patterns: fields[1:]})
+	}
+	return ig, scanner.Err()
+}
+
+// Ignores reports whether f is suppressed.
+func (ig *Ignore) Ignores(f Finding) bool {
+	if ig == nil {
+		return false
+	}
+	for _, r := range ig.rules {
+		if r.rule != "*" && r.rule != f.Rule {
+			continue
+		}
+		if len(r.patterns) == 0 {
+			return true
+		}
+		for _, p := range r.patterns {
+			target := f.Key
+			if strings.Contains(p, "/") {
+				target = f.Profile + "/" + f.Key
+			}
+			if ok, _ := path.Match(p, target); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Filter returns the findings that are not suppressed.
+func (ig *Ignore) Filter(findings []Finding) []Finding {
+	var out []Finding
+	for _, f := range findings {
+		if !ig.Ignores(f) {
+			out = append(out, f)
+		}
+	}
+	return out
+}
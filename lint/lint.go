@@ -0,0 +1,128 @@
+// Package lint reports hygiene problems across the keys in a vault.
+package lint
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/stym06/keys/db"
+	"github.com/stym06/keys/provider"
+)
+
+// Rule names. They are also used in the ignore file.
+const (
+	DuplicateValue = "duplicate-value"
+	InvalidName    = "invalid-name"
+	Whitespace     = "whitespace"
+	EmptyValue     = "empty-value"
+	NeverAccessed  = "never-accessed"
+	Stale          = "stale"
+	LiveInDev      = "live-in-dev"
+)
+
+// Rules lists every rule in the order findings are reported.
+var Rules = []string{DuplicateValue, InvalidName, Whitespace, EmptyValue, NeverAccessed, Stale, LiveInDev}
+
+// DevProfile is the profile expected to hold only test credentials.
+const DevProfile = "dev"
+
+// Keys younger than this are not reported as never accessed.
+const neverAccessedGrace = 7 * 24 * time.Hour
+
+// Key is a stored key with the context the rules need.
+type Key struct {
+	db.Key
+	Profile string
+	// LastAccess is the time of the latest audit log entry, or 0 if the
+	// key was never accessed.
+	LastAccess int64
+}
+
+type Finding struct {
+	Rule    string `json:"rule" yaml:"rule"`
+	Profile string `json:"profile" yaml:"profile"`
+	Key     string `json:"key" yaml:"key"`
+	Message string `json:"message" yaml:"message"`
+}
+
+type Options struct {
+	// MaxAge is how long a value may go without rotation; 0 disables the
+	// stale rule.
+	MaxAge time.Duration
+	Now    time.Time
+}
+
+var validName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// Run checks keys from every profile and returns findings sorted by rule,
+// profile and key.
+func Run(keys []Key, opts Options) []Finding {
+	var findings []Finding
+	add := func(rule string, k Key, format string, args ...any) {
+		findings = append(findings, Finding{rule, k.Profile, k.Name, fmt.Sprintf(format, args...)})
+	}
+
+	byValue := make(map[string][]Key)
+	for _, k := range keys {
+		if k.Value != "" {
+			byValue[k.Value] = append(byValue[k.Value], k)
+		}
+	}
+
+	for _, k := range keys {
+		if dups := byValue[k.Value]; len(dups) > 1 {
+			var others []string
+			for _, o := range dups {
+				if o.Profile != k.Profile || o.Name != k.Name {
+					others = append(others, o.Profile+"/"+o.Name)
+				}
+			}
+			add(DuplicateValue, k, "same value as %s", strings.Join(others, ", "))
+		}
+		if !validName.MatchString(k.Name) {
+			add(InvalidName, k, "not a valid environment variable name")
+		}
+		switch {
+		case k.Value == "":
+			add(EmptyValue, k, "value is empty")
+		case strings.TrimSpace(k.Value) != k.Value:
+			add(Whitespace, k, "value has leading or trailing whitespace")
+		}
+
+		age := opts.Now.Sub(time.Unix(k.LastRotated(), 0))
+		if k.LastAccess == 0 && k.LastRotated() > 0 && age > neverAccessedGrace {
+			add(NeverAccessed, k, "never accessed in %d days", days(age))
+		}
+		if opts.MaxAge > 0 && k.LastRotated() > 0 && age > opts.MaxAge {
+			add(Stale, k, "%d days old, max %d", days(age), days(opts.MaxAge))
+		}
+		if k.Profile == DevProfile {
+			if p := provider.Detect(k.Value); p != nil && p.IsLive(k.Value) {
+				add(LiveInDev, k, "looks like a live %s credential in the %s profile", p.Name, DevProfile)
+			}
+		}
+	}
+
+	order := make(map[string]int, len(Rules))
+	for i, r := range Rules {
+		order[r] = i
+	}
+	sort.SliceStable(findings, func(i, j int) bool {
+		a, b := findings[i], findings[j]
+		if a.Rule != b.Rule {
+			return order[a.Rule] < order[b.Rule]
+		}
+		if a.Profile != b.Profile {
+			return a.Profile < b.Profile
+		}
+		return a.Key < b.Key
+	})
+	return findings
+}
+
+func days(d time.Duration) int {
+	return int(d.Hours() / 24)
+}
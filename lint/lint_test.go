@@ -0,0 +1,104 @@
+package lint
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stym06/keys/db"
+)
+
+func key(profile, name, value string, rotated time.Time, lastAccess int64) Key {
+	return Key{
+		Key:        db.Key{Name: name, Value: value, UpdatedAt: rotated.Unix(), RotatedAt: rotated.Unix()},
+		Profile:    profile,
+		LastAccess: lastAccess,
+	}
+}
+
+func rulesFor(findings []Finding, profile, name string) []string {
+	var rules []string
+	for _, f := range findings {
+		if f.Profile == profile && f.Key == name {
+			rules = append(rules, f.Rule)
+		}
+	}
+	return rules
+}
+
+func TestRun(t *testing.T) {
+	now := time.Now()
+	recent := now.Add(-time.Hour)
+	old := now.Add(-200 * 24 * time.Hour)
+
+	keys := []Key{
+		key("default", "API_KEY", "shared-secret", recent, 1),
+		key("prod", "API_KEY", "shared-secret", recent, 1),
+		key("default", "my-key", "x", recent, 1),
+		key("default", "PADDED", " value ", recent, 1),
+		key("default", "EMPTY", "", recent, 1),
+		key("default", "UNUSED", "y", old, 0),
+		key("dev", "STRIPE_KEY", "sk_live_abc", recent, 1),
+		key("dev", "STRIPE_TEST", "sk_test_abc", recent, 1),
+		key("dev", "CHANNEL", "news-live-channel", recent, 1),
+		key("default", "FINE", "z", recent, 0),
+	}
+	findings := Run(keys, Options{MaxAge: 90 * 24 * time.Hour, Now: now})
+
+	tests := []struct {
+		profile, name string
+		want          string
+	}{
+		{"default", "API_KEY", DuplicateValue},
+		{"prod", "API_KEY", DuplicateValue},
+		{"default", "my-key", InvalidName},
+		{"default", "PADDED", Whitespace},
+		{"default", "EMPTY", EmptyValue},
+		{"default", "UNUSED", NeverAccessed + "," + Stale},
+		{"dev", "STRIPE_KEY", LiveInDev},
+		{"dev", "STRIPE_TEST", ""},
+		{"dev", "CHANNEL", ""},
+		{"default", "FINE", ""},
+	}
+	for _, tt := range tests {
+		got := strings.Join(rulesFor(findings, tt.profile, tt.name), ",")
+		if got != tt.want {
+			t.Errorf("%s/%s: got rules %q, want %q", tt.profile, tt.name, got, tt.want)
+		}
+	}
+
+	if findings[0].Rule != DuplicateValue || !strings.Contains(findings[0].Message, "prod/API_KEY") {
+		t.Errorf("unexpected first finding: %+v", findings[0])
+	}
+}
+
+func TestIgnore(t *testing.T) {
+	ig, err := ParseIgnore(strings.NewReader(`
+never-accessed              # off everywhere
+stale  prod/LEGACY_*
+*  TEST_*
+`))
+	if err != nil {
+		t.Fatalf("ParseIgnore: %v", err)
+	}
+
+	tests := []struct {
+		f    Finding
+		want bool
+	}{
+		{Finding{Rule: NeverAccessed, Profile: "default", Key: "A"}, true},
+		{Finding{Rule: Stale, Profile: "prod", Key: "LEGACY_TOKEN"}, true},
+		{Finding{Rule: Stale, Profile: "default", Key: "LEGACY_TOKEN"}, false},
+		{Finding{Rule: EmptyValue, Profile: "dev", Key: "TEST_KEY"}, true},
+		{Finding{Rule: EmptyValue, Profile: "dev", Key: "KEY"}, false},
+	}
+	for _, tt := range tests {
+		if got := ig.Ignores(tt.f); got != tt.want {
+			t.Errorf("Ignores(%+v) = %v, want %v", tt.f, got, tt.want)
+		}
+	}
+
+	if _, err := ParseIgnore(strings.NewReader("no-such-rule\n")); err == nil {
+		t.Error("expected error for unknown rule")
+	}
+}
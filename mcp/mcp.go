@@ -0,0 +1,184 @@
+// Package mcp implements the server side of the Model Context Protocol
+// over stdio: newline-delimited JSON-RPC 2.0 messages, one per line, on
+// stdin and stdout. Only tools are supported.
+package mcp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// ProtocolVersion is the MCP revision the server speaks.
+const ProtocolVersion = "2025-06-18"
+
+// JSON-RPC error codes.
+const (
+	codeParseError     = -32700
+	codeInvalidRequest = -32600
+	codeMethodNotFound = -32601
+	codeInvalidParams  = -32602
+)
+
+// Handler runs a tool call with its JSON arguments and returns text for the
+// agent. An error is reported to the agent as a failed tool call rather
+// than a protocol error.
+type Handler func(ctx context.Context, args json.RawMessage) (string, error)
+
+// Tool is a tool the server offers.
+type Tool struct {
+	Name        string         `json:"name"`
+	Description string         `json:"description"`
+	InputSchema map[string]any `json:"inputSchema"`
+	Handler     Handler        `json:"-"`
+}
+
+type Server struct {
+	name    string
+	version string
+	tools   []Tool
+}
+
+func NewServer(name, version string, tools ...Tool) *Server {
+	return &Server{name: name, version: version, tools: tools}
+}
+
+type request struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+type response struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  any             `json:"result,omitempty"`
+	Error   *rpcError       `json:"error,omitempty"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+type content struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+type callResult struct {
+	Content []content `json:"content"`
+	IsError bool      `json:"isError,omitempty"`
+}
+
+// Serve reads requests from r and writes responses to w until r ends or
+// ctx is cancelled. Requests are handled one at a time, in order.
+func (s *Server) Serve(ctx context.Context, r io.Reader, w io.Writer) error {
+	lines := make(chan []byte)
+	errCh := make(chan error, 1)
+	go func() {
+		scanner := bufio.NewScanner(r)
+		scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+		for scanner.Scan() {
+			line := append([]byte(nil), scanner.Bytes()...)
+			select {
+			case lines <- line:
+			case <-ctx.Done():
+				return
+			}
+		}
+		errCh <- scanner.Err()
+	}()
+
+	enc := json.NewEncoder(w)
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case err := <-errCh:
+			return err
+		case line := <-lines:
+			if len(line) == 0 {
+				continue
+			}
+			if resp := s.handle(ctx, line); resp != nil {
+				if err := enc.Encode(resp); err != nil {
+					return err
+				}
+			}
+		}
+	}
+}
+
+// handle returns the response to one message, or nil for a notification.
+func (s *Server) handle(ctx context.Context, line []byte) *response {
+	var req request
+	if err := json.Unmarshal(line, &req); err != nil {
+		return errorResponse(json.RawMessage("null"), codeParseError, "parse error")
+	}
+	if req.ID == nil {
+		return nil
+	}
+	if req.JSONRPC != "2.0" || req.Method == "" {
+		return errorResponse(req.ID, codeInvalidRequest, "invalid request")
+	}
+
+	switch req.Method {
+	case "initialize":
+		var params struct {
+			ProtocolVersion string `json:"protocolVersion"`
+		}
+		_ = json.Unmarshal(req.Params, &params)
+		return result(req.ID, map[string]any{
+			"protocolVersion": ProtocolVersion,
+			"capabilities":    map[string]any{"tools": map[string]any{}},
+			"serverInfo":      map[string]string{"name": s.name, "version": s.version},
+		})
+	case "ping":
+		return result(req.ID, map[string]any{})
+	case "tools/list":
+		return result(req.ID, map[string]any{"tools": s.tools})
+	case "tools/call":
+		var params struct {
+			Name      string          `json:"name"`
+			Arguments json.RawMessage `json:"arguments"`
+		}
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			return errorResponse(req.ID, codeInvalidParams, "invalid params")
+		}
+		tool := s.tool(params.Name)
+		if tool == nil {
+			return errorResponse(req.ID, codeInvalidParams, fmt.Sprintf("unknown tool %q", params.Name))
+		}
+		if params.Arguments == nil {
+			params.Arguments = json.RawMessage("{}")
+		}
+		text, err := tool.Handler(ctx, params.Arguments)
+		if err != nil {
+			return result(req.ID, callResult{Content: []content{{"text", err.Error()}}, IsError: true})
+		}
+		return result(req.ID, callResult{Content: []content{{"text", text}}})
+	default:
+		return errorResponse(req.ID, codeMethodNotFound, fmt.Sprintf("method %q not found", req.Method))
+	}
+}
+
+func (s *Server) tool(name string) *Tool {
+	for i := range s.tools {
+		if s.tools[i].Name == name {
+			return &s.tools[i]
+		}
+	}
+	return nil
+}
+
+func result(id json.RawMessage, v any) *response {
+	return &response{JSONRPC: "2.0", ID: id, Result: v}
+}
+
+func errorResponse(id json.RawMessage, code int, msg string) *response {
+	return &response{JSONRPC: "2.0", ID: id, Error: &rpcError{Code: code, Message: msg}}
+}
@@ -0,0 +1,95 @@
+package mcp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func serve(t *testing.T, lines ...string) []map[string]any {
+	t.Helper()
+	echo := Tool{
+		Name:        "echo",
+		InputSchema: map[string]any{"type": "object"},
+		Handler: func(ctx context.Context, args json.RawMessage) (string, error) {
+			var a struct{ Text string }
+			json.Unmarshal(args, &a)
+			if a.Text == "" {
+				return "", errors.New("nothing to echo")
+			}
+			return a.Text, nil
+		},
+	}
+	var out strings.Builder
+	if err := NewServer("test", "1.0", echo).Serve(context.Background(), strings.NewReader(strings.Join(lines, "\n")+"\n"), &out); err != nil {
+		t.Fatalf("Serve: %v", err)
+	}
+
+	var responses []map[string]any
+	scanner := bufio.NewScanner(strings.NewReader(out.String()))
+	for scanner.Scan() {
+		var resp map[string]any
+		if err := json.Unmarshal(scanner.Bytes(), &resp); err != nil {
+			t.Fatalf("invalid response %q: %v", scanner.Text(), err)
+		}
+		responses = append(responses, resp)
+	}
+	return responses
+}
+
+func TestServeLifecycle(t *testing.T) {
+	responses := serve(t,
+		`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2025-06-18","capabilities":{},"clientInfo":{"name":"c","version":"1"}}}`,
+		`{"jsonrpc":"2.0","method":"notifications/initialized"}`,
+		`{"jsonrpc":"2.0","id":2,"method":"tools/list"}`,
+		`{"jsonrpc":"2.0","id":"three","method":"tools/call","params":{"name":"echo","arguments":{"text":"hi"}}}`,
+	)
+	if len(responses) != 3 {
+		t.Fatalf("expected 3 responses (none for the notification), got %d: %v", len(responses), responses)
+	}
+
+	init := responses[0]["result"].(map[string]any)
+	if init["protocolVersion"] != ProtocolVersion || init["serverInfo"].(map[string]any)["name"] != "test" {
+		t.Errorf("unexpected initialize result: %v", init)
+	}
+
+	tools := responses[1]["result"].(map[string]any)["tools"].([]any)
+	if len(tools) != 1 || tools[0].(map[string]any)["name"] != "echo" {
+		t.Errorf("unexpected tools: %v", tools)
+	}
+
+	if responses[2]["id"] != "three" {
+		t.Errorf("expected the request id to be echoed, got %v", responses[2]["id"])
+	}
+	content := responses[2]["result"].(map[string]any)["content"].([]any)[0].(map[string]any)
+	if content["type"] != "text" || content["text"] != "hi" {
+		t.Errorf("unexpected tool result: %v", content)
+	}
+}
+
+func TestServeErrors(t *testing.T) {
+	responses := serve(t,
+		`not json`,
+		`{"jsonrpc":"2.0","id":1,"method":"resources/list"}`,
+		`{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"missing"}}`,
+		`{"jsonrpc":"2.0","id":3,"method":"tools/call","params":{"name":"echo","arguments":{}}}`,
+	)
+	if len(responses) != 4 {
+		t.Fatalf("expected 4 responses, got %d", len(responses))
+	}
+	for i, code := range []float64{codeParseError, codeMethodNotFound, codeInvalidParams} {
+		rpcErr, ok := responses[i]["error"].(map[string]any)
+		if !ok || rpcErr["code"] != code {
+			t.Errorf("response %d: expected error code %v, got %v", i, code, responses[i])
+		}
+	}
+
+	// A failing tool is a result with isError, not a protocol error
+	result := responses[3]["result"].(map[string]any)
+	if result["isError"] != true || !strings.Contains(result["content"].([]any)[0].(map[string]any)["text"].(string), "nothing to echo") {
+		t.Errorf("expected a tool error result, got %v", responses[3])
+	}
+}
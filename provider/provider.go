@@ -0,0 +1,191 @@
+// Package provider recognises API keys from well-known services by their
+// format, without contacting the service.
+package provider
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Provider describes the key format of one service.
+type Provider struct {
+	ID   string // short tag, e.g. "github"
+	Name string // display name, e.g. "GitHub"
+
+	// Prefixes identify the provider's values. Pattern is the full format a
+	// well-formed value matches, and MinLength the shortest such value.
+	Prefixes  []string
+	Pattern   *regexp.Regexp
+	MinLength int
+
+	// LivePrefixes mark production values, for providers that issue
+	// separate test credentials.
+	LivePrefixes []string
+
+	// Hints are key name segments that suggest the provider, e.g. "GH" in
+	// GH_TOKEN.
+	Hints []string
+}
+
+// Registry lists the known providers.
+var Registry = []Provider{
+	{
+		ID: "anthropic", Name: "Anthropic",
+		Prefixes:  []string{"sk-ant-"},
+		Pattern:   regexp.MustCompile(`^sk-ant-[A-Za-z0-9_-]{32,}$`),
+		MinLength: 39,
+		Hints:     []string{"ANTHROPIC", "CLAUDE"},
+	},
+	{
+		ID: "openai", Name: "OpenAI",
+		Prefixes:  []string{"sk-proj-", "sk-"},
+		Pattern:   regexp.MustCompile(`^sk-(proj-)?[A-Za-z0-9_-]{20,}$`),
+		MinLength: 23,
+		Hints:     []string{"OPENAI"},
+	},
+	{
+		ID: "github", Name: "GitHub",
+		Prefixes:  []string{"ghp_", "gho_", "ghu_", "ghs_", "ghr_", "github_pat_"},
+		Pattern:   regexp.MustCompile(`^(gh[pousr]_[A-Za-z0-9]{36}|github_pat_[A-Za-z0-9_]{82})$`),
+		MinLength: 40,
+		Hints:     []string{"GITHUB", "GH"},
+	},
+	{
+		ID: "gitlab", Name: "GitLab",
+		Prefixes:  []string{"glpat-"},
+		Pattern:   regexp.MustCompile(`^glpat-[A-Za-z0-9_-]{20,}$`),
+		MinLength: 26,
+		Hints:     []string{"GITLAB", "GL"},
+	},
+	{
+		ID: "aws", Name: "AWS",
+		Prefixes:  []string{"AKIA", "ASIA"},
+		Pattern:   regexp.MustCompile(`^(AKIA|ASIA)[A-Z0-9]{16}$`),
+		MinLength: 20,
+		Hints:     []string{"AWS"},
+	},
+	{
+		ID: "slack", Name: "Slack",
+		Prefixes:  []string{"xoxb-", "xoxp-", "xoxa-", "xoxr-", "xapp-"},
+		Pattern:   regexp.MustCompile(`^(xox[bpar]|xapp)-[0-9A-Za-z-]{20,}$`),
+		MinLength: 25,
+		Hints:     []string{"SLACK"},
+	},
+	{
+		ID: "stripe", Name: "Stripe",
+		Prefixes:     []string{"sk_live_", "sk_test_", "pk_live_", "pk_test_", "rk_live_", "rk_test_", "whsec_"},
+		Pattern:      regexp.MustCompile(`^((sk|pk|rk)_(live|test)_[0-9A-Za-z]{24,}|whsec_[0-9A-Za-z+/=]{32,})$`),
+		MinLength:    32,
+		LivePrefixes: []string{"sk_live_", "pk_live_", "rk_live_"},
+		Hints:        []string{"STRIPE"},
+	},
+	{
+		ID: "google", Name: "Google",
+		Prefixes:  []string{"AIza"},
+		Pattern:   regexp.MustCompile(`^AIza[0-9A-Za-z_-]{35}$`),
+		MinLength: 39,
+		Hints:     []string{"GOOGLE", "GCP", "GEMINI", "FIREBASE"},
+	},
+	{
+		ID: "sendgrid", Name: "SendGrid",
+		Prefixes:  []string{"SG."},
+		Pattern:   regexp.MustCompile(`^SG\.[0-9A-Za-z_-]{22}\.[0-9A-Za-z_-]{43}$`),
+		MinLength: 69,
+		Hints:     []string{"SENDGRID"},
+	},
+	{
+		ID: "huggingface", Name: "Hugging Face",
+		Prefixes:  []string{"hf_"},
+		Pattern:   regexp.MustCompile(`^hf_[A-Za-z0-9]{34,}$`),
+		MinLength: 37,
+		Hints:     []string{"HUGGINGFACE", "HF"},
+	},
+	{
+		ID: "npm", Name: "npm",
+		Prefixes:  []string{"npm_"},
+		Pattern:   regexp.MustCompile(`^npm_[A-Za-z0-9]{36}$`),
+		MinLength: 40,
+		Hints:     []string{"NPM"},
+	},
+}
+
+// Detect returns the provider whose prefix value starts with, preferring
+// the longest prefix, or nil if none matches.
+func Detect(value string) *Provider {
+	p, _ := match(value)
+	return p
+}
+
+// Prefix returns the provider prefix value starts with, such as "ghp_", or
+// "" if the value is not recognised. Prefixes are fixed markers, not secret
+// material, so they are safe to display.
+func Prefix(value string) string {
+	_, prefix := match(value)
+	return prefix
+}
+
+func match(value string) (*Provider, string) {
+	var found *Provider
+	var longest string
+	for i := range Registry {
+		for _, prefix := range Registry[i].Prefixes {
+			if len(prefix) > len(longest) && strings.HasPrefix(value, prefix) {
+				found, longest = &Registry[i], prefix
+			}
+		}
+	}
+	return found, longest
+}
+
+// IsLive reports whether value is one of the provider's production
+// credentials rather than a test one.
+func (p *Provider) IsLive(value string) bool {
+	for _, prefix := range p.LivePrefixes {
+		if strings.HasPrefix(value, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// FromName returns the provider suggested by a key name, or nil. A provider
+// is suggested when one of its hints is a whole segment of the name, so
+// GH_TOKEN suggests GitHub but GHOST_URL does not.
+func FromName(name string) *Provider {
+	segments := strings.FieldsFunc(strings.ToUpper(name), func(r rune) bool {
+		return r == '_' || r == '-' || r == '.'
+	})
+	for i := range Registry {
+		for _, hint := range Registry[i].Hints {
+			for _, s := range segments {
+				if s == hint {
+					return &Registry[i]
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// Check returns warnings about a value that looks truncated or malformed
+// for its provider, or whose name suggests a different provider.
+func Check(name, value string) []string {
+	p := Detect(value)
+	if p == nil {
+		return nil
+	}
+
+	var warnings []string
+	if !p.Pattern.MatchString(value) {
+		if len(value) < p.MinLength {
+			warnings = append(warnings, fmt.Sprintf("value looks truncated for a %s key (%d characters, expected at least %d)", p.Name, len(value), p.MinLength))
+		} else {
+			warnings = append(warnings, fmt.Sprintf("value looks malformed for a %s key", p.Name))
+		}
+	}
+	if hinted := FromName(name); hinted != nil && hinted.ID != p.ID {
+		warnings = append(warnings, fmt.Sprintf("value looks like a %s key, but the name suggests %s", p.Name, hinted.Name))
+	}
+	return warnings
+}
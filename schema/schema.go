@@ -0,0 +1,207 @@
+// Package schema parses requirement manifests such as .keys.required and
+// validates stored keys against them.
+//
+// Each non-comment line names a key, optionally followed by attributes and
+// a trailing "# description":
+//
+//	OPENAI_KEY  pattern=^sk-  min=40  max-age=90d  # OpenAI API key
+//	SENTRY_DSN  optional                           # error reporting
+//
+// A plain list of names is a valid manifest in which every key is required.
+package schema
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/stym06/keys/db"
+)
+
+// Rule describes the expectations for a single key.
+type Rule struct {
+	Name        string
+	Optional    bool
+	Pattern     *regexp.Regexp
+	MinLength   int
+	MaxAge      time.Duration
+	Description string
+}
+
+// Parse reads a manifest. Errors include the offending line number.
+func Parse(r io.Reader) ([]Rule, error) {
+	var rules []Rule
+	seen := make(map[string]bool)
+	scanner := bufio.NewScanner(r)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		var rule Rule
+		if i := commentIndex(line); i >= 0 {
+			rule.Description = strings.TrimSpace(line[i+1:])
+			line = strings.TrimSpace(line[:i])
+		}
+
+		fields := strings.Fields(line)
+		rule.Name = fields[0]
+		for _, attr := range fields[1:] {
+			if err := rule.set(attr); err != nil {
+				return nil, fmt.Errorf("line %d: %w", lineNo, err)
+			}
+		}
+		if seen[rule.Name] {
+			return nil, fmt.Errorf("line %d: %s is listed more than once", lineNo, rule.Name)
+		}
+		seen[rule.Name] = true
+		rules = append(rules, rule)
+	}
+	return rules, scanner.Err()
+}
+
+// commentIndex returns the position of a "#" that starts a trailing
+// comment, i.e. one preceded by whitespace, or -1.
+func commentIndex(line string) int {
+	for i := 1; i < len(line); i++ {
+		if line[i] == '#' && (line[i-1] == ' ' || line[i-1] == '\t') {
+			return i
+		}
+	}
+	return -1
+}
+
+func (r *Rule) set(attr string) error {
+	key, val, hasVal := strings.Cut(attr, "=")
+	switch key {
+	case "optional":
+		r.Optional = true
+	case "required":
+		r.Optional = false
+	case "pattern":
+		re, err := regexp.Compile(val)
+		if err != nil {
+			return fmt.Errorf("invalid pattern for %s: %w", r.Name, err)
+		}
+		r.Pattern = re
+	case "min":
+		n, err := strconv.Atoi(val)
+		if err != nil || n < 0 {
+			return fmt.Errorf("invalid min for %s: %q", r.Name, val)
+		}
+		r.MinLength = n
+	case "max-age":
+		d, err := ParseDuration(val)
+		if err != nil {
+			return fmt.Errorf("invalid max-age for %s: %w", r.Name, err)
+		}
+		r.MaxAge = d
+	default:
+		if hasVal {
+			return fmt.Errorf("unknown attribute %q for %s", key, r.Name)
+		}
+		return fmt.Errorf("unexpected %q after %s", attr, r.Name)
+	}
+	return nil
+}
+
+// ParseDuration extends time.ParseDuration with day (d) and week (w) units,
+// e.g. "90d" or "2w".
+func ParseDuration(s string) (time.Duration, error) {
+	if n, ok := strings.CutSuffix(s, "d"); ok {
+		days, err := strconv.Atoi(n)
+		if err != nil || days < 0 {
+			return 0, fmt.Errorf("invalid duration %q", s)
+		}
+		return time.Duration(days) * 24 * time.Hour, nil
+	}
+	if n, ok := strings.CutSuffix(s, "w"); ok {
+		weeks, err := strconv.Atoi(n)
+		if err != nil || weeks < 0 {
+			return 0, fmt.Errorf("invalid duration %q", s)
+		}
+		return time.Duration(weeks) * 7 * 24 * time.Hour, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid duration %q", s)
+	}
+	return d, nil
+}
+
+// Violation kinds.
+const (
+	Missing  = "missing"
+	Mismatch = "pattern"
+	TooShort = "min_length"
+	TooOld   = "max_age"
+)
+
+type Violation struct {
+	Kind    string `json:"kind" yaml:"kind"`
+	Message string `json:"message" yaml:"message"`
+}
+
+// Result is the outcome of checking one rule.
+type Result struct {
+	Rule       Rule
+	Present    bool
+	Violations []Violation
+}
+
+// OK reports whether the key satisfies its rule. Missing optional keys are
+// OK.
+func (r Result) OK() bool {
+	return len(r.Violations) == 0
+}
+
+// Validate checks keys against rules, returning one result per rule in
+// manifest order.
+func Validate(rules []Rule, keys []db.Key, now time.Time) []Result {
+	byName := make(map[string]db.Key, len(keys))
+	for _, k := range keys {
+		byName[k.Name] = k
+	}
+
+	results := make([]Result, 0, len(rules))
+	for _, rule := range rules {
+		res := Result{Rule: rule}
+		k, ok := byName[rule.Name]
+		res.Present = ok
+		if !ok {
+			if !rule.Optional {
+				res.Violations = append(res.Violations, Violation{Missing, "missing"})
+			}
+			results = append(results, res)
+			continue
+		}
+		res.Violations = Check(rule, k, now)
+		results = append(results, res)
+	}
+	return results
+}
+
+// Check validates the value and age of a stored key against rule.
+func Check(rule Rule, k db.Key, now time.Time) []Violation {
+	var violations []Violation
+	if rule.Pattern != nil && !rule.Pattern.MatchString(k.Value) {
+		violations = append(violations, Violation{Mismatch, fmt.Sprintf("does not match %s", rule.Pattern)})
+	}
+	if rule.MinLength > 0 && len(k.Value) < rule.MinLength {
+		violations = append(violations, Violation{TooShort, fmt.Sprintf("shorter than %d characters", rule.MinLength)})
+	}
+	if rule.MaxAge > 0 && k.LastRotated() > 0 {
+		age := now.Sub(time.Unix(k.LastRotated(), 0))
+		if age > rule.MaxAge {
+			violations = append(violations, Violation{TooOld, fmt.Sprintf("%d days old, max %d", int(age.Hours()/24), int(rule.MaxAge.Hours()/24))})
+		}
+	}
+	return violations
+}
@@ -0,0 +1,111 @@
+package schema
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stym06/keys/db"
+)
+
+func TestParsePlainList(t *testing.T) {
+	rules, err := Parse(strings.NewReader("# deps\nOPENAI_KEY\n\nDATABASE_URL\n"))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if len(rules) != 2 {
+		t.Fatalf("expected 2 rules, got %d", len(rules))
+	}
+	if rules[0].Name != "OPENAI_KEY" || rules[0].Optional {
+		t.Errorf("unexpected rule: %+v", rules[0])
+	}
+}
+
+func TestParseAttributes(t *testing.T) {
+	src := "OPENAI_KEY  pattern=^sk-  min=10  max-age=90d  # OpenAI #1 key\nSENTRY_DSN optional\n"
+	rules, err := Parse(strings.NewReader(src))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	r := rules[0]
+	if r.Pattern == nil || r.Pattern.String() != "^sk-" {
+		t.Errorf("unexpected pattern: %v", r.Pattern)
+	}
+	if r.MinLength != 10 || r.MaxAge != 90*24*time.Hour {
+		t.Errorf("unexpected min/max-age: %d %v", r.MinLength, r.MaxAge)
+	}
+	if r.Description != "OpenAI #1 key" {
+		t.Errorf("unexpected description: %q", r.Description)
+	}
+	if !rules[1].Optional {
+		t.Error("SENTRY_DSN should be optional")
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	cases := []string{
+		"KEY pattern=(",
+		"KEY min=abc",
+		"KEY max-age=soon",
+		"KEY color=blue",
+		"KEY extra",
+		"KEY\nKEY",
+	}
+	for _, src := range cases {
+		if _, err := Parse(strings.NewReader(src)); err == nil {
+			t.Errorf("expected error for %q", src)
+		}
+	}
+}
+
+func TestParseDuration(t *testing.T) {
+	cases := map[string]time.Duration{
+		"90d": 90 * 24 * time.Hour,
+		"2w":  14 * 24 * time.Hour,
+		"12h": 12 * time.Hour,
+	}
+	for in, want := range cases {
+		got, err := ParseDuration(in)
+		if err != nil || got != want {
+			t.Errorf("ParseDuration(%q) = %v, %v; want %v", in, got, err, want)
+		}
+	}
+	if _, err := ParseDuration("-1d"); err == nil {
+		t.Error("expected error for negative days")
+	}
+}
+
+func TestValidate(t *testing.T) {
+	now := time.Now()
+	rules, _ := Parse(strings.NewReader(
+		"OPENAI_KEY pattern=^sk- min=8\nOLD_KEY max-age=30d\nMISSING\nOPTIONAL optional\n"))
+	keys := []db.Key{
+		{Name: "OPENAI_KEY", Value: "pk-123", UpdatedAt: now.Unix()},
+		{Name: "OLD_KEY", Value: "x", UpdatedAt: now.Add(-40 * 24 * time.Hour).Unix()},
+	}
+
+	results := Validate(rules, keys, now)
+	if len(results) != 4 {
+		t.Fatalf("expected 4 results, got %d", len(results))
+	}
+
+	kinds := func(r Result) []string {
+		var k []string
+		for _, v := range r.Violations {
+			k = append(k, v.Kind)
+		}
+		return k
+	}
+	if got := kinds(results[0]); len(got) != 2 || got[0] != Mismatch || got[1] != TooShort {
+		t.Errorf("OPENAI_KEY: unexpected violations %v", got)
+	}
+	if got := kinds(results[1]); len(got) != 1 || got[0] != TooOld {
+		t.Errorf("OLD_KEY: unexpected violations %v", got)
+	}
+	if got := kinds(results[2]); len(got) != 1 || got[0] != Missing {
+		t.Errorf("MISSING: unexpected violations %v", got)
+	}
+	if !results[3].OK() || results[3].Present {
+		t.Errorf("OPTIONAL should be OK and absent: %+v", results[3])
+	}
+}
@@ -2,11 +2,12 @@ package tui
 
 import (
 	"fmt"
-	"os/exec"
 	"strings"
 	"time"
 
+	"github.com/stym06/keys/clipboard"
 	"github.com/stym06/keys/db"
+	"github.com/stym06/keys/provider"
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
@@ -46,6 +47,8 @@ var (
 	ageGreenStyle  = lipgloss.NewStyle().Foreground(lipgloss.Color("10"))
 	ageYellowStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("11"))
 	ageRedStyle    = lipgloss.NewStyle().Foreground(lipgloss.Color("9"))
+
+	badgeStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("13"))
 )
 
 type SeeModel struct {
@@ -61,6 +64,8 @@ type SeeModel struct {
 	copied    string // flash message
 	copiedFmt string // "export" or "env"
 	copiedN   int    // number of keys copied
+	copyErr   string // why the last copy failed
+	copyFn    func(string) error
 
 	// Masked/peek mode
 	masked   bool
@@ -69,6 +74,10 @@ type SeeModel struct {
 	// Env export via ctrl+e
 	envExport     bool
 	envExportKeys []db.Key
+
+	// Send to another profile via ctrl+t
+	sendProfile bool
+	sendKeys    []db.Key
 }
 
 func NewSee(keys []db.Key) SeeModel {
@@ -83,15 +92,39 @@ func (m SeeModel) Done() bool          { return m.done }
 func (m SeeModel) Message() string     { return m.message }
 func (m SeeModel) EnvExport() bool     { return m.envExport }
 func (m SeeModel) EnvExportKeys() []db.Key { return m.envExportKeys }
+func (m SeeModel) SendProfile() bool    { return m.sendProfile }
+func (m SeeModel) SendKeys() []db.Key   { return m.sendKeys }
 
 func (m SeeModel) Init() tea.Cmd {
 	return nil
 }
 
-func copyToClipboard(text string) error {
-	cmd := exec.Command("pbcopy")
-	cmd.Stdin = strings.NewReader(text)
-	return cmd.Run()
+// WithCopy replaces how copied text reaches the clipboard, e.g. to clear
+// it again after a timeout.
+func (m SeeModel) WithCopy(copy func(text string) error) SeeModel {
+	m.copyFn = copy
+	return m
+}
+
+func (m SeeModel) copyToClipboard(text string) error {
+	if m.copyFn != nil {
+		return m.copyFn(text)
+	}
+	_, err := clipboard.Copy(text)
+	return err
+}
+
+// flashCopy records the outcome of copying n keys in the given format.
+func (m *SeeModel) flashCopy(err error, format string, n int) {
+	if err != nil {
+		m.copied = ""
+		m.copyErr = err.Error()
+		return
+	}
+	m.copied = "done"
+	m.copiedFmt = format
+	m.copiedN = n
+	m.copyErr = ""
 }
 
 // selectedFromMatches returns checked keys that are in the current filtered view.
@@ -119,6 +152,15 @@ func ageIndicator(updatedAt int64) string {
 	return ageRedStyle.Render("● ")
 }
 
+// providerBadge names the service a value belongs to, if recognised.
+func providerBadge(value string) string {
+	p := provider.Detect(value)
+	if p == nil {
+		return ""
+	}
+	return " " + badgeStyle.Render(p.ID)
+}
+
 func (m SeeModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
 	case tea.KeyMsg:
@@ -129,14 +171,14 @@ func (m SeeModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		case "up", "ctrl+p":
 			if m.state == stateSearch && m.cursor > 0 {
 				m.cursor--
-				m.copied = ""
+				m.copied, m.copyErr = "", ""
 			}
 		case "down", "ctrl+n":
 			if m.state == stateSearch {
 				matches := m.filteredKeys()
 				if m.cursor < len(matches)-1 {
 					m.cursor++
-					m.copied = ""
+					m.copied, m.copyErr = "", ""
 				}
 			}
 		case " ":
@@ -148,7 +190,7 @@ func (m SeeModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 					if !m.selected[name] {
 						delete(m.selected, name)
 					}
-					m.copied = ""
+					m.copied, m.copyErr = "", ""
 				}
 				return m, nil
 			}
@@ -168,7 +210,7 @@ func (m SeeModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 					case stateSearch:
 						m.input += msg.String()
 						m.cursor = 0
-						m.copied = ""
+						m.copied, m.copyErr = "", ""
 					case stateAddName:
 						m.newName += msg.String()
 					case stateAddValue:
@@ -192,6 +234,21 @@ func (m SeeModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				}
 				return m, nil
 			}
+		case "ctrl+t":
+			if m.state == stateSearch {
+				matches := m.filteredKeys()
+				keys := m.selectedFromMatches(matches)
+				if len(keys) == 0 && len(matches) > 0 && m.cursor < len(matches) {
+					keys = []db.Key{matches[m.cursor]}
+				}
+				if len(keys) > 0 {
+					m.sendProfile = true
+					m.sendKeys = keys
+					m.done = true
+					return m, tea.Quit
+				}
+				return m, nil
+			}
 		case "shift+tab", "ctrl+y":
 			if m.state == stateSearch {
 				matches := m.filteredKeys()
@@ -205,11 +262,7 @@ func (m SeeModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 					for _, k := range keys {
 						lines = append(lines, fmt.Sprintf("export %s=%s", k.Name, k.Value))
 					}
-					if err := copyToClipboard(strings.Join(lines, "\n")); err == nil {
-						m.copied = "done"
-						m.copiedFmt = "export"
-						m.copiedN = len(keys)
-					}
+					m.flashCopy(m.copyToClipboard(strings.Join(lines, "\n")), "export", len(keys))
 				}
 				return m, nil
 			}
@@ -225,11 +278,7 @@ func (m SeeModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 					for _, k := range keys {
 						lines = append(lines, fmt.Sprintf("%s=%s", k.Name, k.Value))
 					}
-					if err := copyToClipboard(strings.Join(lines, "\n")); err == nil {
-						m.copied = "done"
-						m.copiedFmt = "env"
-						m.copiedN = len(keys)
-					}
+					m.flashCopy(m.copyToClipboard(strings.Join(lines, "\n")), "env", len(keys))
 				}
 				return m, nil
 			}
@@ -239,7 +288,7 @@ func (m SeeModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				if len(m.input) > 0 {
 					m.input = m.input[:len(m.input)-1]
 					m.cursor = 0
-					m.copied = ""
+					m.copied, m.copyErr = "", ""
 				}
 			case stateAddName:
 				if len(m.newName) > 0 {
@@ -279,7 +328,7 @@ func (m SeeModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				case stateSearch:
 					m.input += msg.String()
 					m.cursor = 0
-					m.copied = ""
+					m.copied, m.copyErr = "", ""
 				case stateAddName:
 					m.newName += msg.String()
 				case stateAddValue:
@@ -343,6 +392,8 @@ func (m SeeModel) View() string {
 					b.WriteString(copiedStyle.Render(fmt.Sprintf("  Copied %d keys as export", m.copiedN)))
 				}
 			}
+		} else if m.copyErr != "" {
+			b.WriteString(copiedStyle.Render("  Copy failed: " + m.copyErr))
 		}
 		b.WriteString("\n")
 
@@ -382,7 +433,7 @@ func (m SeeModel) View() string {
 					check = checkStyle.Render("[x] ")
 				}
 
-				age := ageIndicator(k.UpdatedAt)
+				age := ageIndicator(k.LastRotated())
 
 				name := k.Name
 				val := k.Value
@@ -400,7 +451,7 @@ func (m SeeModel) View() string {
 					val = dimStyle.Render(val)
 				}
 
-				b.WriteString(fmt.Sprintf("%s%s%s%s = %s\n", pointer, check, age, name, val))
+				b.WriteString(fmt.Sprintf("%s%s%s%s = %s%s\n", pointer, check, age, name, val, providerBadge(k.Value)))
 			}
 		}
 
@@ -420,9 +471,9 @@ func (m SeeModel) View() string {
 
 	b.WriteString("\n")
 	if m.masked {
-		b.WriteString(dimStyle.Render("  space select  r reveal  S-tab/ctrl+y copy export  tab copy KEY=VAL  ctrl+e export .env  esc quit"))
+		b.WriteString(dimStyle.Render("  space select  r reveal  S-tab/ctrl+y copy export  tab copy KEY=VAL  ctrl+e export .env  ctrl+t send to profile  esc quit"))
 	} else {
-		b.WriteString(dimStyle.Render("  space select  S-tab/ctrl+y copy export  tab copy KEY=VAL  ctrl+e export .env  enter add key  esc quit"))
+		b.WriteString(dimStyle.Render("  space select  S-tab/ctrl+y copy export  tab copy KEY=VAL  ctrl+e export .env  ctrl+t send to profile  enter add key  esc quit"))
 	}
 	return b.String()
 }